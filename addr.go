@@ -0,0 +1,191 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DefaultDialTimeout bounds each connection attempt of TCPWriters.
+const DefaultDialTimeout = 5 * time.Second
+
+// WriterFactory creates the member for an address, for AddAddr and SetAddrs.
+type WriterFactory interface {
+	NewWriter(addr string) (lineProtocol.WriteCloser, error)
+}
+
+// WriterFactoryFunc adapts a function to a WriterFactory.
+type WriterFactoryFunc func(addr string) (lineProtocol.WriteCloser, error)
+
+// NewWriter calls f(addr).
+func (f WriterFactoryFunc) NewWriter(addr string) (lineProtocol.WriteCloser, error) {
+	return f(addr)
+}
+
+// TCPWriters is the default WriterFactory.  It returns a Reconnecting writer
+// named addr that streams line protocol over TCP.
+var TCPWriters WriterFactory = WriterFactoryFunc(func(addr string) (lineProtocol.WriteCloser, error) {
+	return NewReconnecting(addr, func() (lineProtocol.WriteCloser, error) {
+		conn, err := net.DialTimeout("tcp", addr, DefaultDialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return &connWriter{Conn: conn, name: addr}, nil
+	}), nil
+})
+
+type connWriter struct {
+	net.Conn
+	name string
+}
+
+func (w *connWriter) Name() string { return w.name }
+
+// WithWriterFactory sets how AddAddr and SetAddrs create members.  The
+// default is TCPWriters.
+func WithWriterFactory(f WriterFactory) Option {
+	return func(c *Consistent) { c.factory = f }
+}
+
+// AddAddr adds the member for addr, creating it with the WriterFactory the
+// first time.  The hash owns that member: it is cached by address, so adding
+// addr again returns the same writer, and RemoveAddr and SetAddrs close it.
+func (c *Consistent) AddAddr(addr string) (lineProtocol.WriteCloser, error) {
+	w, err := c.writerFor(addr)
+	if err != nil {
+		return nil, err
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return nil, c.opError("add", addr, w, c.refusal())
+	}
+	c.add(w)
+	return w, nil
+}
+
+// RemoveAddr removes the member AddAddr created for addr and closes it, once
+// the writes in flight through the hash that may still reach it have
+// returned.  It does nothing for unknown addresses.  Like Remove, it refuses
+// to go below WithMinMembers, returning ErrMinMembers and leaving the member
+// open.
+func (c *Consistent) RemoveAddr(addr string) error {
+	c.lock()
+	w, ok := c.owned[addr]
+	if !ok {
+		c.unlock()
+		return nil
+	}
+	if !c.allowMutation() {
+		c.unlock()
+		return c.opError("remove", addr, w, c.refusal())
+	}
+	if c.members[w] && !c.allowShrink(len(c.members)-1) {
+		c.unlock()
+		return c.opError("remove", addr, w, ErrMinMembers)
+	}
+	c.remove(w)
+	delete(c.owned, addr)
+	ready := c.retire(w)
+	c.unlock()
+	return closeWriters(ready)
+}
+
+// SetAddrs makes the members AddAddr created exactly those for addrs.
+// Members for new addresses are created, those for dropped addresses are
+// removed and closed as by RemoveAddr.  Members added with Add are left alone.
+func (c *Consistent) SetAddrs(addrs []string) error {
+	want := make(map[string]bool, len(addrs))
+	var errs []error
+	for _, a := range addrs {
+		if want[a] {
+			continue
+		}
+		want[a] = true
+		if _, err := c.writerFor(a); err != nil {
+			errs = append(errs, err)
+			delete(want, a)
+		}
+	}
+
+	c.lock()
+	if !c.allowMutation() {
+		c.unlock()
+		return c.opError("setaddrs", "", nil, c.refusal())
+	}
+	drop := 0
+	for a := range c.owned {
+		if !want[a] {
+			drop++
+		}
+	}
+	if !c.allowShrink(len(c.members) - drop) {
+		c.unlock()
+		return c.opError("setaddrs", "", nil, ErrMinMembers)
+	}
+	var ready []lineProtocol.WriteCloser
+	for a, w := range c.owned {
+		if want[a] {
+			c.add(w)
+			continue
+		}
+		c.remove(w)
+		delete(c.owned, a)
+		ready = append(ready, c.retire(w)...)
+	}
+	c.unlock()
+	if err := closeWriters(ready); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Addrs returns the sorted addresses of the members AddAddr created.
+func (c *Consistent) Addrs() []string {
+	c.rlock()
+	defer c.runlock()
+	a := make([]string, 0, len(c.owned))
+	for k := range c.owned {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// writerFor returns the cached writer for addr, creating it if needed.  The
+// factory runs without the lock since it may dial.
+func (c *Consistent) writerFor(addr string) (lineProtocol.WriteCloser, error) {
+	c.rlock()
+	w, ok := c.owned[addr]
+	f := c.factory
+	c.runlock()
+	if ok {
+		return w, nil
+	}
+	if f == nil {
+		f = TCPWriters
+	}
+	w, err := f.NewWriter(addr)
+	if err != nil {
+		return nil, &Error{Op: "dial", Key: addr, Err: err}
+	}
+	c.lock()
+	if prev, ok := c.owned[addr]; ok {
+		c.unlock()
+		w.Close()
+		return prev, nil
+	}
+	if c.owned == nil {
+		c.owned = make(map[string]lineProtocol.WriteCloser)
+	}
+	c.owned[addr] = w
+	c.unlock()
+	return w, nil
+}
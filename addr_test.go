@@ -0,0 +1,63 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestAddAddr(t *testing.T) {
+	made := make(map[string]*member)
+	f := WriterFactoryFunc(func(addr string) (lineProtocol.WriteCloser, error) {
+		m := newMember(addr)
+		made[addr] = m
+		return m, nil
+	})
+	x := New(WithWriterFactory(f))
+	a, err := x.AddAddr("10.0.0.1:8086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := x.AddAddr("10.0.0.1:8086")
+	if a != again || len(made) != 1 {
+		t.Error("expected the writer for an address to be cached")
+	}
+	if err := x.SetAddrs([]string{"10.0.0.2:8086", "10.0.0.3:8086"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := x.Addrs(); !reflect.DeepEqual(got, []string{"10.0.0.2:8086", "10.0.0.3:8086"}) {
+		t.Errorf("got %v", got)
+	}
+	checkNum(len(x.Members()), 2, t)
+	if !made["10.0.0.1:8086"].closed {
+		t.Error("expected a dropped address to be closed")
+	}
+	if err := x.RemoveAddr("10.0.0.2:8086"); err != nil {
+		t.Fatal(err)
+	}
+	if !made["10.0.0.2:8086"].closed || len(x.Members()) != 1 {
+		t.Error("expected RemoveAddr to remove and close the member")
+	}
+}
+
+func TestSetAddrsMinMembers(t *testing.T) {
+	f := WriterFactoryFunc(func(addr string) (lineProtocol.WriteCloser, error) {
+		return newMember(addr), nil
+	})
+	x := New(WithWriterFactory(f), WithMinMembers(2))
+	if err := x.SetAddrs([]string{"10.0.0.1:8086", "10.0.0.2:8086"}); err != nil {
+		t.Fatal(err)
+	}
+	err := x.SetAddrs([]string{"10.0.0.1:8086"})
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "setaddrs" || !errors.Is(err, ErrMinMembers) {
+		t.Fatalf("got %v, expected a setaddrs ErrMinMembers", err)
+	}
+	checkNum(len(x.Members()), 2, t)
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultWatchTimeout is how long a watch request waits for a change when it
+// gives no timeout.
+const DefaultWatchTimeout = 30 * time.Second
+
+// MaxWatchTimeout caps the timeout a watch request may ask for.
+const MaxWatchTimeout = 5 * time.Minute
+
+// Admin serves read-only HTTP endpoints describing a hash:
+//
+//	GET /stats   Stats as JSON
+//	GET /table   the routing table, see ExportRoutingTable
+//	GET /watch   long-polls for the next routing table
+//
+// /watch takes the epoch the client last saw as ?epoch= and waits until the
+// hash moves past it, then answers like /table.  It waits up to ?timeout=, a
+// Go duration defaulting to DefaultWatchTimeout, and answers 304 Not
+// Modified if nothing changed.  Every response carries the current epoch in
+// the X-Ring-Epoch header.
+//
+// Clients of /table and /watch state the newest table version they support
+// as ?version=, defaulting to 1, and get the table downgraded to what both
+// sides support, named in the X-Table-Version header.
+type Admin struct {
+	c   *Consistent
+	mux *http.ServeMux
+}
+
+// NewAdmin creates an Admin for c.  Mount it under a prefix with
+// http.StripPrefix.
+func NewAdmin(c *Consistent) *Admin {
+	a := &Admin{c: c, mux: http.NewServeMux()}
+	a.mux.HandleFunc("GET /stats", a.stats)
+	a.mux.HandleFunc("GET /table", a.table)
+	a.mux.HandleFunc("GET /watch", a.watch)
+	return a
+}
+
+// ServeHTTP implements http.Handler.
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mux.ServeHTTP(w, r)
+}
+
+func (a *Admin) stats(w http.ResponseWriter, r *http.Request) {
+	a.reply(w, a.c.Epoch(), a.c.Stats())
+}
+
+func (a *Admin) table(w http.ResponseWriter, r *http.Request) {
+	want := 1
+	if s := r.URL.Query().Get("version"); s != "" {
+		var err error
+		if want, err = strconv.Atoi(s); err != nil {
+			http.Error(w, "version: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	v, err := NegotiateVersion(want)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t, _ := a.c.ExportRoutingTable().Downgrade(v)
+	w.Header().Set("X-Table-Version", strconv.Itoa(v))
+	a.reply(w, t.Epoch, t)
+}
+
+func (a *Admin) watch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	seen, err := strconv.ParseUint(q.Get("epoch"), 10, 64)
+	if err != nil {
+		http.Error(w, "epoch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	timeout := DefaultWatchTimeout
+	if s := q.Get("timeout"); s != "" {
+		if timeout, err = time.ParseDuration(s); err != nil || timeout <= 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+	}
+	if timeout > MaxWatchTimeout {
+		timeout = MaxWatchTimeout
+	}
+	expired := make(chan struct{})
+	timer := a.c.clock.AfterFunc(timeout, func() { close(expired) })
+	defer timer.Stop()
+	for {
+		a.c.lock()
+		epoch, changed := a.c.epoch, a.c.changes()
+		a.c.unlock()
+		if epoch != seen {
+			a.table(w, r)
+			return
+		}
+		select {
+		case <-changed:
+		case <-expired:
+			w.Header().Set("X-Ring-Epoch", strconv.FormatUint(epoch, 10))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (a *Admin) reply(w http.ResponseWriter, epoch uint64, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Ring-Epoch", strconv.FormatUint(epoch, 10))
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAdminWatch(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	srv := httptest.NewServer(NewAdmin(x))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var table RoutingTable
+	json.NewDecoder(res.Body).Decode(&table)
+	res.Body.Close()
+	checkNum(len(table.Members), 1, t)
+	checkNum(table.Version, 1, t)
+	epoch := res.Header.Get("X-Ring-Epoch")
+
+	res, err = http.Get(srv.URL + "/table?version=99")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if v := res.Header.Get("X-Table-Version"); v != strconv.Itoa(RoutingTableVersion) {
+		t.Errorf("got version %s, expected %d", v, RoutingTableVersion)
+	}
+
+	res, err = http.Get(srv.URL + "/watch?epoch=" + epoch + "&timeout=10ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	checkNum(res.StatusCode, http.StatusNotModified, t)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		x.Add(newMember("hijklmn"))
+	}()
+	res, err = http.Get(srv.URL + "/watch?epoch=" + epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(res.Body).Decode(&table)
+	res.Body.Close()
+	checkNum(res.StatusCode, http.StatusOK, t)
+	checkNum(len(table.Members), 2, t)
+	if table.Epoch == 0 || strconv.FormatUint(table.Epoch, 10) == epoch {
+		t.Errorf("got epoch %d, expected it to have moved past %s", table.Epoch, epoch)
+	}
+}
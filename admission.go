@@ -0,0 +1,146 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrRebalancing is the error returned by Get, under RejectMoving, for a key
+// whose owner changes in the pending rebalance.
+var ErrRebalancing = errors.New("key is rebalancing")
+
+// AdmissionPolicy is what the hash does with keys that move in a pending
+// rebalance, see BeginRebalance.
+type AdmissionPolicy int
+
+const (
+	// AdmitAll routes every key to its current owner.
+	AdmitAll AdmissionPolicy = iota
+	// RejectMoving fails Get for moving keys with ErrRebalancing, so callers
+	// can buffer them until the rebalance completes.
+	RejectMoving
+	// WriteBoth routes moving keys to their current owner, and makes Write
+	// copy them to their next owner as well.
+	WriteBoth
+)
+
+// WithAdmission sets how keys moving in a pending rebalance are routed.  The
+// default is AdmitAll.
+func WithAdmission(p AdmissionPolicy) Option {
+	return func(c *Consistent) { c.admission = p }
+}
+
+// rebalance is a topology change announced by BeginRebalance.
+type rebalance struct {
+	r        *ringState
+	elements []lineProtocol.WriteCloser
+}
+
+// owner returns the member owning key on the pending circle.
+func (b *rebalance) owner(key uint32) lineProtocol.WriteCloser {
+	s := b.r.sorted
+	if len(s) == 0 {
+		return nil
+	}
+	i := sort.Search(len(s), func(x int) bool { return s[x] > key })
+	if i >= len(s) {
+		i = 0
+	}
+	return b.r.circle[s[i]]
+}
+
+// BeginRebalance announces that the hash is about to become elements, as
+// with SetCtx.  Until CompleteRebalance or AbortRebalance, keys whose owner
+// differs between the current circle and the new one are routed by the
+// policy set WithAdmission; nothing moves yet.  A rebalance already pending
+// is replaced.
+func (c *Consistent) BeginRebalance(ctx context.Context, elements []lineProtocol.WriteCloser) error {
+	c.rlock()
+	r, err := c.build(ctx, elements)
+	c.runlock()
+	if err != nil {
+		return err
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("beginrebalance", "", nil, c.refusal())
+	}
+	c.pending = &rebalance{r: r, elements: append([]lineProtocol.WriteCloser(nil), elements...)}
+	return nil
+}
+
+// CompleteRebalance applies the pending rebalance with SetCtx and ends it.
+// It does nothing if no rebalance is pending.  If SetCtx fails, the
+// rebalance stays pending.
+func (c *Consistent) CompleteRebalance(ctx context.Context) error {
+	c.rlock()
+	b := c.pending
+	c.runlock()
+	if b == nil {
+		return nil
+	}
+	if err := c.SetCtx(ctx, b.elements); err != nil {
+		return err
+	}
+	c.lock()
+	if c.pending == b {
+		c.pending = nil
+	}
+	c.unlock()
+	return nil
+}
+
+// AbortRebalance drops the pending rebalance, leaving the hash as it is.
+func (c *Consistent) AbortRebalance() {
+	c.lock()
+	defer c.unlock()
+	c.pending = nil
+}
+
+// Rebalancing reports whether a rebalance is pending.
+func (c *Consistent) Rebalancing() bool {
+	c.rlock()
+	defer c.runlock()
+	return c.pending != nil
+}
+
+// Moving returns the current and next owner of key if it moves in the
+// pending rebalance.  ok is false if no rebalance is pending or key stays
+// where it is.
+func (c *Consistent) Moving(key string) (from, to lineProtocol.WriteCloser, ok bool) {
+	c.rlock()
+	defer c.runlock()
+	if c.pending == nil || len(c.circle) == 0 {
+		return nil, nil, false
+	}
+	h := c.keyHash(key)
+	from, _, err := c.get(h)
+	if err != nil {
+		return nil, nil, false
+	}
+	to = c.pending.owner(h)
+	if to == nil || to == from {
+		return nil, nil, false
+	}
+	return from, to, true
+}
+
+// movingTo returns the next owner of the key hashing to h if it is not e.
+// need c.rlock() before calling
+func (c *Consistent) movingTo(h uint32, e lineProtocol.WriteCloser) lineProtocol.WriteCloser {
+	if c.pending == nil {
+		return nil
+	}
+	if to := c.pending.owner(h); to != e {
+		return to
+	}
+	return nil
+}
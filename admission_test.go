@@ -0,0 +1,88 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestRejectMoving(t *testing.T) {
+	a, b, c, d := newMember("a"), newMember("b"), newMember("c"), newMember("d")
+	x := New(WithAdmission(RejectMoving))
+	x.Set([]lineProtocol.WriteCloser{a, b, c})
+	next := []lineProtocol.WriteCloser{a, b, c, d}
+	if err := x.BeginRebalance(context.Background(), next); err != nil {
+		t.Fatal(err)
+	}
+	if !x.Rebalancing() {
+		t.Fatal("expected a pending rebalance")
+	}
+	want := make(map[string]lineProtocol.WriteCloser)
+	moving := 0
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("key%d", i)
+		from, to, ok := x.Moving(k)
+		e, err := x.Get(k)
+		if ok {
+			moving++
+			if !errors.Is(err, ErrRebalancing) || to != d || from == d {
+				t.Fatalf("%s moving %v -> %v: Get = %v, %v", k, from, to, e, err)
+			}
+			want[k] = to
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s stays but Get failed: %v", k, err)
+		}
+		want[k] = e
+	}
+	if moving == 0 || moving == 1000 {
+		t.Fatalf("%d of 1000 keys moving", moving)
+	}
+
+	if err := x.CompleteRebalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if x.Rebalancing() {
+		t.Error("rebalance still pending after CompleteRebalance")
+	}
+	for k, w := range want {
+		if e, err := x.Get(k); err != nil || e != w {
+			t.Fatalf("%s: got %v, %v, want %s", k, e, err, w)
+		}
+	}
+}
+
+func TestWriteBoth(t *testing.T) {
+	a, b := newMember("a"), newMember("b")
+	x := New(WithAdmission(WriteBoth))
+	x.Add(a)
+	x.BeginRebalance(context.Background(), []lineProtocol.WriteCloser{a, b})
+	var k string
+	for i := 0; k == ""; i++ {
+		if _, _, ok := x.Moving(fmt.Sprint(i)); ok {
+			k = fmt.Sprint(i)
+		}
+	}
+	if e, err := x.Get(k); err != nil || e != a {
+		t.Fatalf("Get(%s) = %v, %v; want the current owner", k, e, err)
+	}
+	x.Write(k, []byte("x"))
+	if a.buf.String() != "x" || b.buf.String() != "x" {
+		t.Errorf("a got %q, b got %q", a.buf.String(), b.buf.String())
+	}
+
+	x.AbortRebalance()
+	x.Write(k, []byte("y"))
+	if b.buf.String() != "x" {
+		t.Error("wrote to the next owner after AbortRebalance")
+	}
+	checkNum(len(x.Members()), 1, t)
+}
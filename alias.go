@@ -0,0 +1,77 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// AddAlias makes alias another name for element in GetMember, RemoveByName
+// and Restore, for example its new hostname while a rename is rolled out.
+// Aliases do not change placement and are dropped with their member.
+func (c *Consistent) AddAlias(alias string, element lineProtocol.WriteCloser) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("alias", alias, element, c.refusal())
+	}
+	if !c.members[element] {
+		return c.opError("alias", alias, element, ErrUnknownMember)
+	}
+	if e, ok := c.byName(alias); ok && e != element {
+		return c.opError("alias", alias, element, ErrMemberExists)
+	}
+	if c.aliases == nil {
+		c.aliases = make(map[string]lineProtocol.WriteCloser)
+	}
+	c.aliases[alias] = element
+	return nil
+}
+
+// RemoveAlias forgets alias.  Like AddAlias, it is refused without the
+// WithLeader lease or once the hash is closed.
+func (c *Consistent) RemoveAlias(alias string) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("removealias", alias, nil, c.refusal())
+	}
+	delete(c.aliases, alias)
+	return nil
+}
+
+// GetMember returns the member named or aliased name.
+func (c *Consistent) GetMember(name string) (lineProtocol.WriteCloser, bool) {
+	c.rlock()
+	defer c.runlock()
+	return c.byName(name)
+}
+
+// RemoveByName removes the member named or aliased name, like Remove.
+func (c *Consistent) RemoveByName(name string) {
+	if e, ok := c.GetMember(name); ok {
+		c.Remove(e)
+	}
+}
+
+// need c.rlock() before calling
+func (c *Consistent) byName(name string) (lineProtocol.WriteCloser, bool) {
+	if e, ok := c.aliases[name]; ok {
+		return e, true
+	}
+	for k := range c.members {
+		if k.Name() == name || MemberID(k) == name {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// need c.lock() before calling
+func (c *Consistent) removeAliases(element lineProtocol.WriteCloser) {
+	for a, e := range c.aliases {
+		if e == element {
+			delete(c.aliases, a)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestAliases(t *testing.T) {
+	a, b := newMember("old-host"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	if err := x.AddAlias("new-host", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.AddAlias("hijklmn", a); !errors.Is(err, ErrMemberExists) {
+		t.Errorf("got %v, expected ErrMemberExists", err)
+	}
+	if e, ok := x.GetMember("new-host"); !ok || e != a {
+		t.Errorf("got %v, %v, expected %v", e, ok, a)
+	}
+	if e, ok := x.GetMember("hijklmn"); !ok || e != b {
+		t.Errorf("got %v, %v, expected %v", e, ok, b)
+	}
+
+	// the new process only knows the new name
+	renamed := newMember("old-host")
+	lookup := func(name string) (lineProtocol.WriteCloser, error) {
+		switch name {
+		case "new-host":
+			return renamed, nil
+		case "hijklmn":
+			return b, nil
+		}
+		return nil, errors.New("unknown " + name)
+	}
+	y := New()
+	if err := y.Restore(x.Snapshot(), lookup); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := y.GetMember("new-host"); !ok || e != renamed {
+		t.Errorf("got %v, %v, expected the restored member", e, ok)
+	}
+
+	x.RemoveByName("new-host")
+	checkNum(len(x.Members()), 1, t)
+	if _, ok := x.GetMember("new-host"); ok {
+		t.Error("expected the alias to go with its member")
+	}
+}
+
+func TestRemoveAliasClosed(t *testing.T) {
+	a := newMember("old-host")
+	x := New()
+	x.Add(a)
+	x.AddAlias("new-host", a)
+	x.Close()
+	if err := x.RemoveAlias("new-host"); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, expected ErrClosed", err)
+	}
+	if _, ok := x.GetMember("new-host"); !ok {
+		t.Error("expected the alias to survive a refused RemoveAlias")
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"hash/crc32"
+	"strconv"
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrFull is the error returned when adding to a fixed-capacity hash that has
+// no free buckets left.
+var ErrFull = errors.New("no free buckets")
+
+// Anchor is a consistent hash using the AnchorHash algorithm (Mendelson et al.,
+// "AnchorHash: A Scalable Consistent Hash", 2020).  Lookups take expected
+// constant time and memory is a few ints per bucket, independent of the number
+// of replicas.  The number of members is bounded by the capacity given to
+// NewAnchor.
+//
+// Adding a member reuses the most recently freed bucket, so removing and
+// re-adding a member restores its keys exactly.
+type Anchor struct {
+	a       []uint32 // 0 for working buckets, else the working size when removed
+	k       []uint32 // successor of a removed bucket
+	w       []uint32 // working buckets in positions [0, n)
+	l       []uint32 // position of each bucket in w
+	r       []uint32 // stack of removed buckets
+	n       uint32
+	buckets []lineProtocol.WriteCloser
+	members map[lineProtocol.WriteCloser]uint32
+	sync.RWMutex
+}
+
+// NewAnchor creates an empty Anchor that can hold up to capacity members.
+func NewAnchor(capacity int) *Anchor {
+	x := &Anchor{
+		a:       make([]uint32, capacity),
+		k:       make([]uint32, capacity),
+		w:       make([]uint32, capacity),
+		l:       make([]uint32, capacity),
+		r:       make([]uint32, 0, capacity),
+		buckets: make([]lineProtocol.WriteCloser, capacity),
+		members: make(map[lineProtocol.WriteCloser]uint32),
+	}
+	for b := 0; b < capacity; b++ {
+		x.k[b], x.w[b], x.l[b] = uint32(b), uint32(b), uint32(b)
+	}
+	for b := capacity - 1; b >= 0; b-- {
+		x.r = append(x.r, uint32(b))
+		x.a[b] = uint32(b)
+	}
+	return x
+}
+
+// Add inserts element in the hash.
+func (x *Anchor) Add(element lineProtocol.WriteCloser) error {
+	x.Lock()
+	defer x.Unlock()
+	if _, ok := x.members[element]; ok {
+		return ErrMemberExists
+	}
+	if len(x.r) == 0 {
+		return ErrFull
+	}
+	b := x.r[len(x.r)-1]
+	x.r = x.r[:len(x.r)-1]
+	x.a[b] = 0
+	x.l[x.w[x.n]] = x.n
+	x.w[x.l[b]] = b
+	x.k[b] = b
+	x.n++
+	x.buckets[b] = element
+	x.members[element] = b
+	return nil
+}
+
+// Remove removes an element from the hash.
+func (x *Anchor) Remove(element lineProtocol.WriteCloser) {
+	x.Lock()
+	defer x.Unlock()
+	b, ok := x.members[element]
+	if !ok {
+		return
+	}
+	x.r = append(x.r, b)
+	x.n--
+	x.a[b] = x.n
+	x.w[x.l[b]] = x.w[x.n]
+	x.l[x.w[x.n]] = x.l[b]
+	x.k[b] = x.w[x.n]
+	x.buckets[b] = nil
+	delete(x.members, element)
+}
+
+// Members returns the elements in the hash.
+func (x *Anchor) Members() []lineProtocol.WriteCloser {
+	x.RLock()
+	defer x.RUnlock()
+	var m []lineProtocol.WriteCloser
+	for k := range x.members {
+		m = append(m, k)
+	}
+	return m
+}
+
+// Get returns the element name hashes to.
+func (x *Anchor) Get(name string) (lineProtocol.WriteCloser, error) {
+	x.RLock()
+	defer x.RUnlock()
+	if x.n == 0 {
+		return nil, ErrEmptyCircle
+	}
+	return x.buckets[x.bucket(crc32.ChecksumIEEE([]byte(name)))], nil
+}
+
+// GetN returns n distinct elements for name.  The first is the one Get
+// returns; the others are found by rehashing name with a replica suffix.
+func (x *Anchor) GetN(name string, n int) ([]lineProtocol.WriteCloser, error) {
+	x.RLock()
+	defer x.RUnlock()
+	if x.n == 0 {
+		return nil, ErrEmptyCircle
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	if n > int(x.n) {
+		n = int(x.n)
+	}
+	res := make([]lineProtocol.WriteCloser, 0, n)
+	for i := 0; len(res) < n && i < 4*len(x.a); i++ {
+		key := name
+		if i > 0 {
+			key = name + "#" + strconv.Itoa(i)
+		}
+		e := x.buckets[x.bucket(crc32.ChecksumIEEE([]byte(key)))]
+		if !sliceContainsMember(res, e) {
+			res = append(res, e)
+		}
+	}
+	for i := uint32(0); len(res) < n && i < x.n; i++ {
+		if e := x.buckets[x.w[i]]; !sliceContainsMember(res, e) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+// bucket is GETBUCKET from the AnchorHash paper.
+// need x.RLock() before calling
+func (x *Anchor) bucket(key uint32) uint32 {
+	b := key % uint32(len(x.a))
+	for x.a[b] > 0 {
+		h := mix32(key^b) % x.a[b]
+		for x.a[h] >= x.a[b] {
+			h = x.k[h]
+		}
+		b = h
+	}
+	return b
+}
+
+// mix32 is the murmur3 finalizer, used to derive independent per-bucket hashes
+// from one key hash.
+func mix32(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
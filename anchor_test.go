@@ -0,0 +1,88 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestAnchorMinimalDisruption(t *testing.T) {
+	x := NewAnchor(16)
+	var ms []*member
+	for i := 0; i < 8; i++ {
+		m := newMember("node" + strconv.Itoa(i))
+		ms = append(ms, m)
+		if err := x.Add(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := make(map[string]lineProtocol.WriteCloser)
+	counts := make(map[lineProtocol.WriteCloser]int)
+	for i := 0; i < 10000; i++ {
+		k := strconv.Itoa(i)
+		before[k], _ = x.Get(k)
+		counts[before[k]]++
+	}
+	checkNum(len(counts), 8, t)
+
+	x.Remove(ms[3])
+	for k, was := range before {
+		got, _ := x.Get(k)
+		if was != ms[3] && got != was {
+			t.Fatalf("%s moved from %v to %v", k, was, got)
+		}
+		if got == ms[3] {
+			t.Fatalf("%s still on removed member", k)
+		}
+	}
+
+	x.Add(ms[3])
+	for k, was := range before {
+		if got, _ := x.Get(k); got != was {
+			t.Fatalf("%s: got %v after re-add, expected %v", k, got, was)
+		}
+	}
+}
+
+func TestAnchorFullAndEmpty(t *testing.T) {
+	x := NewAnchor(1)
+	if _, err := x.Get("key"); !errors.Is(err, ErrEmptyCircle) {
+		t.Errorf("expected empty circle error, got %v", err)
+	}
+	x.Add(newMember("a"))
+	if err := x.Add(newMember("b")); !errors.Is(err, ErrFull) {
+		t.Errorf("expected full error, got %v", err)
+	}
+}
+
+func TestAnchorGetN(t *testing.T) {
+	x := NewAnchor(8)
+	for i := 0; i < 4; i++ {
+		x.Add(newMember("node" + strconv.Itoa(i)))
+	}
+	res, err := x.GetN("key", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(res), 4, t)
+	if first, _ := x.Get("key"); res[0] != first {
+		t.Errorf("got %v first, expected %v", res[0], first)
+	}
+}
+
+func BenchmarkAnchorGet(b *testing.B) {
+	x := NewAnchor(10000)
+	for i := 0; i < 10000; i++ {
+		x.Add(newMember("node" + strconv.Itoa(i)))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Get("nothing")
+	}
+}
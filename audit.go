@@ -0,0 +1,106 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// AuditPolicy says which writes WithAudit copies to its sink.
+type AuditPolicy struct {
+	Rate         float64            // share of keys audited, 0 to 1
+	Measurements map[string]float64 // rate by measurement, overriding Rate
+}
+
+type audit struct {
+	sink   lineProtocol.WriteCloser
+	policy AuditPolicy
+
+	mu       sync.Mutex // serializes records on sink
+	audited  atomic.Int64
+	failures atomic.Int64
+}
+
+// WithAudit makes Write copy a sample of the writes it routes to sink, each
+// preceded by a line protocol comment carrying the routing decision:
+//
+//	# audit key=<key> member=<name> epoch=<epoch> result=<ok or error>
+//
+// Sampling is by key, so all the writes of an audited series are copied.
+// The measurement of a key is its prefix up to the first unescaped comma or
+// space.  A failing sink never fails a write; its errors are counted in
+// Stats.  Unlike WithShadow, auditing changes nothing about routing.
+func WithAudit(sink lineProtocol.WriteCloser, p AuditPolicy) Option {
+	return func(c *Consistent) {
+		c.audit = &audit{sink: sink, policy: p}
+	}
+}
+
+// sampled reports whether writes of key are audited.
+func (a *audit) sampled(key string) bool {
+	rate := a.policy.Rate
+	if len(a.policy.Measurements) > 0 {
+		m := key
+		if i := indexUnescaped(m, ','); i >= 0 {
+			m = m[:i]
+		}
+		if i := indexUnescaped(m, ' '); i >= 0 {
+			m = m[:i]
+		}
+		if r, ok := a.policy.Measurements[m]; ok {
+			rate = r
+		}
+	}
+	switch {
+	case rate <= 0:
+		return false
+	case rate >= 1:
+		return true
+	}
+	return float64(hash64(key)>>11)/(1<<53) < rate
+}
+
+// auditWrite copies the write of p for key to e, which returned werr, to the
+// sink if key is sampled.
+func (c *Consistent) auditWrite(key string, e lineProtocol.WriteCloser, p []byte, werr error) {
+	a := c.audit
+	if !a.sampled(key) {
+		return
+	}
+	c.rlock()
+	epoch := c.epoch
+	c.runlock()
+	result := "ok"
+	if werr != nil {
+		result = strconv.Quote(werr.Error())
+	}
+	var b strings.Builder
+	b.WriteString("# audit key=")
+	b.WriteString(strconv.Quote(key))
+	b.WriteString(" member=")
+	b.WriteString(strconv.Quote(e.Name()))
+	b.WriteString(" epoch=")
+	b.WriteString(strconv.FormatUint(epoch, 10))
+	b.WriteString(" result=")
+	b.WriteString(result)
+	b.WriteByte('\n')
+	b.Write(p)
+	if len(p) > 0 && p[len(p)-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	a.mu.Lock()
+	_, err := a.sink.Write([]byte(b.String()))
+	a.mu.Unlock()
+	if err != nil {
+		a.failures.Add(1)
+		return
+	}
+	a.audited.Add(1)
+}
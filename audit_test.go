@@ -0,0 +1,58 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	a, sink := newMember("a"), newMember("audit")
+	x := New(WithAudit(sink, AuditPolicy{
+		Rate:         0,
+		Measurements: map[string]float64{"cpu": 1, `m\,x`: 0.5},
+	}))
+	x.Add(a)
+	x.Write("cpu,host=a", []byte("cpu,host=a v=1 1\n"))
+	x.Write("mem,host=a", []byte("mem,host=a v=1 1\n"))
+
+	want := fmt.Sprintf("# audit key=\"cpu,host=a\" member=\"a\" epoch=%d result=ok\ncpu,host=a v=1 1\n", x.Epoch())
+	if got := sink.String(); got != want {
+		t.Fatalf("sink got %q, want %q", got, want)
+	}
+	if s := x.Stats(); s.Audited != 1 || s.AuditFailures != 0 {
+		t.Errorf("Audited = %d, AuditFailures = %d", s.Audited, s.AuditFailures)
+	}
+
+	n := 0
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf(`m\,x,host=%d`, i)
+		if x.audit.sampled(k) {
+			n++
+		}
+		if x.audit.sampled(k) != x.audit.sampled(k) {
+			t.Fatal("sampling is not stable by key")
+		}
+	}
+	if n < 400 || n > 600 {
+		t.Errorf("sampled %d of 1000 keys at rate 0.5", n)
+	}
+
+	a.err = errors.New("down")
+	x.Write("cpu,host=b", []byte("cpu,host=b v=1 1"))
+	if !strings.Contains(sink.String(), `result="down"`+"\ncpu,host=b v=1 1\n") {
+		t.Errorf("failed write not audited: %q", sink.String())
+	}
+	sink.err = errors.New("full")
+	if _, err := x.Write("cpu,host=c", []byte("x")); err == nil {
+		t.Fatal("expected the member error")
+	}
+	if s := x.Stats(); s.Audited != 2 || s.AuditFailures != 1 {
+		t.Errorf("Audited = %d, AuditFailures = %d", s.Audited, s.AuditFailures)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DefaultDialAttempts is how many times NewFromAddrs tries each address
+// unless WithDialRetries says otherwise.
+const DefaultDialAttempts = 3
+
+// DefaultDialBackoff is the wait before NewFromAddrs's second attempt; it
+// doubles for every further one.
+const DefaultDialBackoff = 200 * time.Millisecond
+
+// WithDialRetries sets how many times NewFromAddrs tries each address and
+// how long it waits before the second attempt.
+func WithDialRetries(attempts int, backoff time.Duration) Option {
+	return func(c *Consistent) {
+		if attempts < 1 {
+			attempts = 1
+		}
+		c.dialAttempts, c.dialBackoff = attempts, backoff
+	}
+}
+
+// AddrStatus is the outcome of bootstrapping one address.
+type AddrStatus struct {
+	Addr     string
+	Member   lineProtocol.WriteCloser // nil if Err is not nil
+	Attempts int
+	Err      error
+}
+
+// connector is implemented by members that can connect ahead of the first
+// write, such as Reconnecting.
+type connector interface {
+	Connect() error
+}
+
+// NewFromAddrs creates a hash with opts and adds a member for every address
+// in addrs, created by factory, or TCPWriters if factory is nil, as with
+// AddAddr.  Addresses are dialed in parallel; members that can connect ahead
+// of time (Reconnecting, and so TCPWriters) are connected before they are
+// added, and an address failing is retried as set by WithDialRetries.
+// Addresses that still fail are left out and their writers closed.
+//
+// The statuses are in the order of addrs.  The error is ErrEmptyCircle if no
+// address could be added.
+func NewFromAddrs(addrs []string, factory WriterFactory, opts ...Option) (*Consistent, []AddrStatus, error) {
+	c := New(opts...)
+	if factory != nil {
+		c.factory = factory
+	}
+	f := c.factory
+	if f == nil {
+		f = TCPWriters
+	}
+	attempts, backoff := c.dialAttempts, c.dialBackoff
+	if attempts == 0 {
+		attempts, backoff = DefaultDialAttempts, DefaultDialBackoff
+	}
+
+	status := make([]AddrStatus, len(addrs))
+	var wg sync.WaitGroup
+	for i, a := range addrs {
+		status[i].Addr = a
+		wg.Add(1)
+		go func(s *AddrStatus) {
+			defer wg.Done()
+			wait := backoff
+			for s.Attempts < attempts {
+				if s.Attempts > 0 {
+					time.Sleep(wait)
+					wait *= 2
+				}
+				s.Attempts++
+				if s.Member, s.Err = dialAddr(f, s.Addr); s.Err == nil {
+					return
+				}
+			}
+			s.Err = &Error{Op: "dial", Key: s.Addr, Err: s.Err}
+		}(&status[i])
+	}
+	wg.Wait()
+
+	c.lock()
+	defer c.unlock()
+	if c.owned == nil {
+		c.owned = make(map[string]lineProtocol.WriteCloser)
+	}
+	for i := range status {
+		s := &status[i]
+		if s.Err != nil {
+			continue
+		}
+		if prev, ok := c.owned[s.Addr]; ok {
+			// the same address listed twice
+			s.Member.Close()
+			s.Member = prev
+			continue
+		}
+		c.owned[s.Addr] = s.Member
+		c.add(s.Member)
+	}
+	if len(c.members) == 0 {
+		return c, status, ErrEmptyCircle
+	}
+	return c, status, nil
+}
+
+// dialAddr creates the writer for addr and connects it if it can.
+func dialAddr(f WriterFactory, addr string) (lineProtocol.WriteCloser, error) {
+	w, err := f.NewWriter(addr)
+	if err != nil {
+		return nil, err
+	}
+	if cn, ok := w.(connector); ok {
+		if err := cn.Connect(); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
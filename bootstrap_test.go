@@ -0,0 +1,86 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestNewFromAddrs(t *testing.T) {
+	var mu sync.Mutex
+	tries := make(map[string]int)
+	f := WriterFactoryFunc(func(addr string) (lineProtocol.WriteCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		tries[addr]++
+		switch {
+		case addr == "down":
+			return nil, errors.New("connection refused")
+		case addr == "flaky" && tries[addr] < 2:
+			return nil, errors.New("timeout")
+		}
+		return newMember(addr), nil
+	})
+	x, status, err := NewFromAddrs([]string{"a", "flaky", "down", "a"}, f, WithDialRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.Members()), 2, t)
+	if s := status[0]; s.Addr != "a" || s.Err != nil || s.Attempts != 1 {
+		t.Errorf("a: %+v", s)
+	}
+	if s := status[1]; s.Err != nil || s.Attempts != 2 {
+		t.Errorf("flaky: %+v", s)
+	}
+	if s := status[2]; s.Err == nil || s.Member != nil || s.Attempts != 3 {
+		t.Errorf("down: %+v", s)
+	}
+	if status[3].Member != status[0].Member {
+		t.Error("expected a repeated address to share its member")
+	}
+	if got := x.Addrs(); len(got) != 2 || got[0] != "a" || got[1] != "flaky" {
+		t.Errorf("Addrs() = %v", got)
+	}
+
+	if _, _, err := NewFromAddrs([]string{"down"}, f, WithDialRetries(1, 0)); err != ErrEmptyCircle {
+		t.Errorf("no reachable address: %v", err)
+	}
+}
+
+func TestNewFromAddrsTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	dead := closed.Addr().String()
+	closed.Close()
+
+	x, status, err := NewFromAddrs([]string{ln.Addr().String(), dead}, nil, WithDialRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+	checkNum(len(x.Members()), 1, t)
+	if status[0].Err != nil || status[1].Err == nil {
+		t.Errorf("status = %+v", status)
+	}
+}
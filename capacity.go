@@ -0,0 +1,96 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrAtCapacity is the error returned by Get when the owner of a key has
+// reached its capacity and every member after it on the circle is either at
+// capacity or down.
+var ErrAtCapacity = errors.New("all members at capacity")
+
+type capacity struct {
+	limit int64
+	load  atomic.Int64
+	rate  loadRate // see WithCapacityWarning
+}
+
+// SetCapacity caps the load element may carry.  Load is measured in whatever
+// units the caller reports through AddLoad, for example keys or in-flight
+// requests.  Once element's load reaches limit, Get sends its keys to the next
+// member on the circle that still has room.  A limit <= 0 removes the cap.
+func (c *Consistent) SetCapacity(element lineProtocol.WriteCloser, limit int64) error {
+	c.lock()
+	defer c.unlock()
+	if _, ok := c.members[element]; !ok {
+		return c.opError("setcapacity", "", element, ErrUnknownMember)
+	}
+	if limit <= 0 {
+		delete(c.capacities, element)
+		return nil
+	}
+	if c.capacities == nil {
+		c.capacities = make(map[lineProtocol.WriteCloser]*capacity)
+	}
+	if cp, ok := c.capacities[element]; ok {
+		cp.limit = limit
+		return nil
+	}
+	c.capacities[element] = &capacity{limit: limit}
+	return nil
+}
+
+// AddLoad adds delta to the load reported for element.  It has no effect on
+// members without a capacity.
+func (c *Consistent) AddLoad(element lineProtocol.WriteCloser, delta int64) {
+	c.rlock()
+	defer c.runlock()
+	cp, ok := c.capacities[element]
+	if !ok {
+		return
+	}
+	load := cp.load.Add(delta)
+	if w := c.capacityWarn; w != nil && w.observe(cp, load, c.clock.Now()) {
+		c.capacityWarnings.Add(1)
+		go w.warn(c.capacityStatus(element, cp))
+	}
+}
+
+// Load returns the load reported for element.
+func (c *Consistent) Load(element lineProtocol.WriteCloser) int64 {
+	c.rlock()
+	defer c.runlock()
+	if cp, ok := c.capacities[element]; ok {
+		return cp.load.Load()
+	}
+	return 0
+}
+
+// need c.rlock() before calling
+func (c *Consistent) full(element lineProtocol.WriteCloser) bool {
+	cp, ok := c.capacities[element]
+	return ok && cp.load.Load() >= cp.limit
+}
+
+// overflow returns the first member after point i on the circle that is up
+// and has room to spare.  owner is the member at point i.
+// need c.rlock() before calling
+func (c *Consistent) overflow(i int, owner lineProtocol.WriteCloser) (lineProtocol.WriteCloser, error) {
+	for n := 1; n < len(c.sortedHashes); n++ {
+		e := c.circle[c.sortedHashes[(i+n)%len(c.sortedHashes)]]
+		if !c.full(e) && !c.isDown(e) && !c.isExcluded(e) {
+			return e, nil
+		}
+	}
+	if c.full(owner) {
+		return nil, ErrAtCapacity
+	}
+	return nil, ErrMemberDown
+}
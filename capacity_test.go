@@ -0,0 +1,48 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCapacityOverflow(t *testing.T) {
+	x := New()
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x.Add(a)
+	x.Add(b)
+	owner, _ := x.Get("ggg")
+	other := a
+	if owner == a {
+		other = b
+	}
+	if err := x.SetCapacity(owner, 2); err != nil {
+		t.Fatal(err)
+	}
+	x.AddLoad(owner, 1)
+	if got, _ := x.Get("ggg"); got != owner {
+		t.Errorf("got %v below capacity, expected %v", got, owner)
+	}
+	x.AddLoad(owner, 1)
+	if got, _ := x.Get("ggg"); got != other {
+		t.Errorf("got %v at capacity, expected overflow to %v", got, other)
+	}
+	if s := x.Stats(); s.Overflows != 1 {
+		t.Errorf("got %d overflows, expected 1", s.Overflows)
+	}
+	x.SetCapacity(other, 1)
+	x.AddLoad(other, 1)
+	if _, err := x.Get("ggg"); !errors.Is(err, ErrAtCapacity) {
+		t.Errorf("expected at capacity error, got %v", err)
+	}
+	x.AddLoad(owner, -1)
+	if got, _ := x.Get("ggg"); got != owner {
+		t.Errorf("got %v after load dropped, expected %v", got, owner)
+	}
+	if err := x.SetCapacity(newMember("nope"), 1); !errors.Is(err, ErrUnknownMember) {
+		t.Errorf("expected unknown member error, got %v", err)
+	}
+}
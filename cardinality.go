@@ -0,0 +1,142 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"sync/atomic"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DefaultCardinalityPrecision is the precision WithCardinality uses when
+// given one outside [4, 16].  It takes 16KiB per member for an error of
+// about 0.8%.
+const DefaultCardinalityPrecision = 12
+
+// hyperLogLog estimates the number of distinct keys added to it.  Registers
+// are updated with atomic compare-and-swap so concurrent Gets can feed it
+// under the read lock.
+type hyperLogLog struct {
+	p    uint8
+	regs []atomic.Uint32
+}
+
+func newHyperLogLog(p uint8) *hyperLogLog {
+	return &hyperLogLog{p: p, regs: make([]atomic.Uint32, 1<<p)}
+}
+
+func (h *hyperLogLog) add(key string) {
+	x := hash64(key)
+	r := &h.regs[x>>(64-h.p)]
+	rho := uint32(bits.LeadingZeros64(x<<h.p|1<<(h.p-1))) + 1
+	for {
+		old := r.Load()
+		if rho <= old || r.CompareAndSwap(old, rho) {
+			return
+		}
+	}
+}
+
+func (h *hyperLogLog) estimate() float64 {
+	m := float64(len(h.regs))
+	var sum float64
+	zeros := 0
+	for i := range h.regs {
+		v := h.regs[i].Load()
+		sum += math.Ldexp(1, -int(v))
+		if v == 0 {
+			zeros++
+		}
+	}
+	var alpha float64
+	switch len(h.regs) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return e
+}
+
+func (h *hyperLogLog) reset() {
+	for i := range h.regs {
+		h.regs[i].Store(0)
+	}
+}
+
+// hash64 is FNV-1a followed by the splitmix64 finalizer, which spreads the
+// high bits HyperLogLog indexes its registers by.
+func hash64(s string) uint64 {
+	x := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		x ^= uint64(s[i])
+		x *= 1099511628211
+	}
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// WithCardinality makes Get estimate the number of distinct keys it routes to
+// each member with a HyperLogLog of 2^precision registers, reported by
+// Cardinalities.  Distinct keys, unlike Get calls, are what a member has to
+// store.
+func WithCardinality(precision int) Option {
+	return func(c *Consistent) {
+		if precision < 4 || precision > 16 {
+			precision = DefaultCardinalityPrecision
+		}
+		c.cardinality = uint8(precision)
+	}
+}
+
+// Cardinality is the estimated number of distinct keys routed to a member.
+type Cardinality struct {
+	Member lineProtocol.WriteCloser
+	Keys   float64
+}
+
+// Cardinalities returns the estimated distinct keys routed to every member
+// since it joined or the last ResetCardinalities, sorted by name, or nil if
+// the hash was not created WithCardinality.
+func (c *Consistent) Cardinalities() []Cardinality {
+	if c.cardinality == 0 {
+		return nil
+	}
+	c.rlock()
+	defer c.runlock()
+	res := make([]Cardinality, 0, len(c.members))
+	for k := range c.members {
+		res = append(res, Cardinality{Member: k, Keys: c.state[k].keys.estimate()})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Member.Name() < res[j].Member.Name() })
+	return res
+}
+
+// ResetCardinalities clears the estimates of every member.
+func (c *Consistent) ResetCardinalities() {
+	if c.cardinality == 0 {
+		return
+	}
+	c.rlock()
+	defer c.runlock()
+	for _, st := range c.state {
+		st.keys.reset()
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCardinalities(t *testing.T) {
+	if New().Cardinalities() != nil {
+		t.Error("expected no estimates without WithCardinality")
+	}
+	x := New(WithCardinality(12))
+	x.Add(newMember("abcdefg"))
+	x.Add(newMember("hijklmn"))
+	want := make(map[string]int)
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 20000; i++ {
+			k := fmt.Sprintf("cpu,host=server%d", i)
+			e, _ := x.Get(k)
+			if round == 0 {
+				want[e.Name()]++
+			}
+		}
+	}
+	for _, c := range x.Cardinalities() {
+		w := float64(want[c.Member.Name()])
+		if math.Abs(c.Keys-w)/w > 0.05 {
+			t.Errorf("%s: estimated %.0f distinct keys, expected about %.0f", c.Member.Name(), c.Keys, w)
+		}
+	}
+	x.ResetCardinalities()
+	for _, c := range x.Cardinalities() {
+		if c.Keys != 0 {
+			t.Errorf("%s: got %.0f after reset", c.Member.Name(), c.Keys)
+		}
+	}
+}
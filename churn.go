@@ -0,0 +1,123 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "time"
+
+// DefaultChurnWindow is the window over which Stats reports recent churn.
+const DefaultChurnWindow = time.Minute
+
+// Churn counts membership changes.
+type Churn struct {
+	Adds      int64 // members added
+	Removes   int64 // members removed
+	Evictions int64 // members marked down
+}
+
+// Total returns the number of changes of any kind.
+func (ch Churn) Total() int64 {
+	return ch.Adds + ch.Removes + ch.Evictions
+}
+
+type churnKind int
+
+const (
+	churnAdd churnKind = iota
+	churnRemove
+	churnEvict
+)
+
+type churnEvent struct {
+	at   time.Time
+	kind churnKind
+}
+
+type churnTracker struct {
+	window    time.Duration
+	threshold int64
+	alert     func(Churn)
+	alerted   bool
+	events    []churnEvent
+	total     Churn
+}
+
+// WithChurnAlert sets the window over which Stats reports recent churn and
+// calls alert, on its own goroutine, whenever more than threshold changes
+// happen within it, as when discovery flaps.  alert is called again only after
+// churn has dropped back to the threshold.  A threshold of 0 or less only sets
+// the window.
+func WithChurnAlert(window time.Duration, threshold int64, alert func(Churn)) Option {
+	return func(c *Consistent) {
+		c.churn.window = window
+		c.churn.threshold = threshold
+		c.churn.alert = alert
+	}
+}
+
+// need c.lock() before calling
+func (c *Consistent) recordChurn(kind churnKind, n int64) {
+	if n <= 0 {
+		return
+	}
+	t := &c.churn
+	now := c.clock.Now()
+	for i := int64(0); i < n; i++ {
+		t.events = append(t.events, churnEvent{now, kind})
+	}
+	switch kind {
+	case churnAdd:
+		t.total.Adds += n
+	case churnRemove:
+		t.total.Removes += n
+	case churnEvict:
+		t.total.Evictions += n
+	}
+	// drop events that left the window
+	cut := 0
+	for cut < len(t.events) && now.Sub(t.events[cut].at) > t.window {
+		cut++
+	}
+	t.events = append(t.events[:0], t.events[cut:]...)
+
+	if t.alert == nil || t.threshold <= 0 {
+		return
+	}
+	if int64(len(t.events)) <= t.threshold {
+		t.alerted = false
+		return
+	}
+	if !t.alerted {
+		t.alerted = true
+		go t.alert(t.recent(now))
+	}
+}
+
+// recent counts the events within the window ending at now.
+func (t *churnTracker) recent(now time.Time) Churn {
+	var ch Churn
+	for _, e := range t.events {
+		if now.Sub(e.at) > t.window {
+			continue
+		}
+		switch e.kind {
+		case churnAdd:
+			ch.Adds++
+		case churnRemove:
+			ch.Removes++
+		case churnEvict:
+			ch.Evictions++
+		}
+	}
+	return ch
+}
+
+// Sub returns the changes counted since prev.
+func (ch Churn) Sub(prev Churn) Churn {
+	return Churn{
+		Adds:      ch.Adds - prev.Adds,
+		Removes:   ch.Removes - prev.Removes,
+		Evictions: ch.Evictions - prev.Evictions,
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChurnStats(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	x.Remove(b)
+	x.MarkDown(a)
+	want := Churn{Adds: 2, Removes: 1, Evictions: 1}
+	if s := x.Stats(); s.Churn != want || s.RecentChurn != want {
+		t.Errorf("got %+v and %+v, expected %+v", s.Churn, s.RecentChurn, want)
+	}
+}
+
+func TestChurnAlert(t *testing.T) {
+	alerts := make(chan Churn, 10)
+	x := New(WithChurnAlert(time.Hour, 2, func(ch Churn) { alerts <- ch }))
+	x.Add(newMember("abcdefg"))
+	x.Add(newMember("hijklmn"))
+	select {
+	case ch := <-alerts:
+		t.Fatalf("got alert %+v below the threshold", ch)
+	case <-time.After(10 * time.Millisecond):
+	}
+	x.Add(newMember("opqrstu"))
+	x.Add(newMember("vwxyz"))
+	select {
+	case ch := <-alerts:
+		checkNum(int(ch.Total()), 3, t)
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert")
+	}
+	select {
+	case ch := <-alerts:
+		t.Errorf("got a second alert %+v", ch)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Claims hands out exclusive, short-lived claims on the keys a member owns,
+// for singleton background work such as compacting one series.  A claim is
+// only valid while the hash still routes its key to that member: once the
+// epoch moves and the key lands elsewhere, Valid reports false and the work
+// should stop, since the new owner may claim the key on its own instance.
+type Claims struct {
+	c    *Consistent
+	self lineProtocol.WriteCloser
+	ttl  time.Duration
+	mu   sync.Mutex
+	held map[string]*claim
+}
+
+type claim struct {
+	epoch   uint64
+	expires time.Time
+}
+
+// NewClaims creates Claims on c for the keys it routes to self.  A claim not
+// released within ttl lapses, so a stuck job cannot hold a key forever; ttl
+// <= 0 means claims last until released.
+func NewClaims(c *Consistent, self lineProtocol.WriteCloser, ttl time.Duration) *Claims {
+	return &Claims{c: c, self: self, ttl: ttl, held: make(map[string]*claim)}
+}
+
+// TryClaim claims key if the hash routes it to the Claims' member and no
+// other claim on it is held.  release gives the claim up; it does nothing if
+// the claim already lapsed.
+func (cl *Claims) TryClaim(key string) (release func(), ok bool) {
+	epoch, owned := cl.owns(key)
+	if !owned {
+		return nil, false
+	}
+	now := cl.c.clock.Now()
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if h, ok := cl.held[key]; ok && !cl.lapsed(h, now) {
+		return nil, false
+	}
+	h := &claim{epoch: epoch}
+	if cl.ttl > 0 {
+		h.expires = now.Add(cl.ttl)
+	}
+	cl.held[key] = h
+	return func() {
+		cl.mu.Lock()
+		defer cl.mu.Unlock()
+		if cl.held[key] == h {
+			delete(cl.held, key)
+		}
+	}, true
+}
+
+// Valid reports whether a claim on key is held and the hash still routes key
+// to the Claims' member.  A claim found invalid is dropped.
+func (cl *Claims) Valid(key string) bool {
+	cl.mu.Lock()
+	h, ok := cl.held[key]
+	var epoch uint64
+	if ok {
+		epoch = h.epoch
+	}
+	cl.mu.Unlock()
+	if !ok {
+		return false
+	}
+	owned := true
+	if cl.c.Epoch() != epoch {
+		epoch, owned = cl.owns(key)
+	}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.held[key] != h {
+		return false
+	}
+	if !owned || cl.lapsed(h, cl.c.clock.Now()) {
+		delete(cl.held, key)
+		return false
+	}
+	h.epoch = epoch
+	return true
+}
+
+// owns routes key and reports whether it lands on self, and at which epoch.
+// A change racing with the lookup counts as not owned.
+func (cl *Claims) owns(key string) (uint64, bool) {
+	epoch := cl.c.Epoch()
+	e, err := cl.c.Get(key)
+	if err != nil || e != cl.self || cl.c.Epoch() != epoch {
+		return 0, false
+	}
+	return epoch, true
+}
+
+// need cl.mu held before calling
+func (cl *Claims) lapsed(h *claim, now time.Time) bool {
+	return !h.expires.IsZero() && now.After(h.expires)
+}
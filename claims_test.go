@@ -0,0 +1,66 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaims(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	var key string
+	for _, k := range []string{"foo", "bar", "baz", "qux", "quux"} {
+		if e, _ := x.Get(k); e == a {
+			key = k
+			break
+		}
+	}
+	if key == "" {
+		t.Fatal("no key routed to a")
+	}
+	cl := NewClaims(x, a, 0)
+	if _, ok := NewClaims(x, b, 0).TryClaim(key); ok {
+		t.Error("expected a claim on a key owned elsewhere to fail")
+	}
+	release, ok := cl.TryClaim(key)
+	if !ok {
+		t.Fatal("expected the owner to claim its key")
+	}
+	if _, ok := cl.TryClaim(key); ok {
+		t.Error("expected a second claim to fail while the first is held")
+	}
+	x.Add(newMember("opqrstu"))
+	if e, _ := x.Get(key); e == a && !cl.Valid(key) {
+		t.Error("expected the claim to survive a change that keeps the key")
+	}
+	release()
+	if cl.Valid(key) {
+		t.Error("expected a released claim to be invalid")
+	}
+
+	cl.TryClaim(key)
+	x.Remove(a)
+	if cl.Valid(key) {
+		t.Error("expected the claim to lapse once the key moved")
+	}
+
+	x = New()
+	x.Add(a)
+	cl = NewClaims(x, a, time.Millisecond)
+	if _, ok := cl.TryClaim(key); !ok {
+		t.Fatal("expected the claim to succeed")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if cl.Valid(key) {
+		t.Error("expected the claim to expire")
+	}
+	if _, ok := cl.TryClaim(key); !ok {
+		t.Error("expected an expired claim to be claimable again")
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "time"
+
+// Clock is the source of time for everything in the package that waits or
+// expires: ramps, claim TTLs, health checks and the other Start loops,
+// reconnect backoff, idle release and the windows of churn, dedup, hot keys,
+// routing counts, history and debouncing, and the admin watch timeout.  Tests
+// and embedders with a fake clock of their own set one WithClock.  Latencies
+// and rebuild times are measured, and network deadlines, dial retries and
+// injected delays kept, on the real clock whatever the Clock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a pending call scheduled with Clock.AfterFunc.
+type Timer interface {
+	// Stop cancels the call, reporting whether it was still pending.
+	Stop() bool
+}
+
+// Ticker delivers ticks on Chan until stopped.
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// RealClock is the Clock of the time package, and the default.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) Chan() <-chan time.Time { return t.C }
+
+// WithClock makes the hash, and the helpers created over it such as
+// StatsHistory, Claims or Debouncer, tell time with clk.
+func WithClock(clk Clock) Option {
+	return func(c *Consistent) {
+		if clk != nil {
+			c.clock = clk
+		}
+	}
+}
+
+// Clock returns the Clock the hash was created with.
+func (c *Consistent) Clock() Clock { return c.clock }
+
+// clockOr returns clk, or RealClock if it is nil.
+func clockOr(clk Clock) Clock {
+	if clk == nil {
+		return RealClock
+	}
+	return clk
+}
@@ -0,0 +1,187 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// fakeClock only moves when advanced, running what falls due on the
+// advancing goroutine.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	tickers []*fakeTicker
+}
+
+type fakeTimer struct {
+	clk *fakeClock
+	at  time.Time
+	f   func()
+}
+
+type fakeTicker struct {
+	clk  *fakeClock
+	next time.Time
+	d    time.Duration
+	c    chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) AfterFunc(d time.Duration, fn func()) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clk: f, at: f.now.Add(d), f: fn}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+func (f *fakeClock) NewTicker(d time.Duration) Ticker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clk: f, next: f.now.Add(d), d: d, c: make(chan time.Time, 1)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clk.mu.Lock()
+	defer t.clk.mu.Unlock()
+	for i, u := range t.clk.timers {
+		if u == t {
+			t.clk.timers = append(t.clk.timers[:i], t.clk.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *fakeTicker) Chan() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	t.clk.mu.Lock()
+	defer t.clk.mu.Unlock()
+	for i, u := range t.clk.tickers {
+		if u == t {
+			t.clk.tickers = append(t.clk.tickers[:i], t.clk.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Advance moves the clock on by d, firing timers in order of their time.
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		var due *fakeTimer
+		for _, t := range f.timers {
+			if !t.at.After(end) && (due == nil || t.at.Before(due.at)) {
+				due = t
+			}
+		}
+		if due == nil {
+			break
+		}
+		for i, t := range f.timers {
+			if t == due {
+				f.timers = append(f.timers[:i], f.timers[i+1:]...)
+				break
+			}
+		}
+		f.now = due.at
+		f.mu.Unlock()
+		due.f()
+		f.mu.Lock()
+	}
+	f.now = end
+	for _, t := range f.tickers {
+		for !t.next.After(end) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+	f.mu.Unlock()
+}
+
+func TestClockRamp(t *testing.T) {
+	clk := newFakeClock()
+	x := New(WithClock(clk))
+	x.Add(newMember("abcdefg"))
+	x.AddWithRamp(newMember("hijklmn"), 10*time.Second)
+	checkNum(x.Stats().Vnodes, 22, t)
+	clk.Advance(time.Second)
+	checkNum(x.Stats().Vnodes, 24, t)
+	clk.Advance(9 * time.Second)
+	checkNum(x.Stats().Vnodes, 40, t)
+}
+
+func TestClockExpiry(t *testing.T) {
+	clk := newFakeClock()
+	self := newMember("self")
+	x := New(WithClock(clk), WithDedup(time.Minute))
+	x.Add(self)
+
+	cl := NewClaims(x, self, time.Minute)
+	if _, ok := cl.TryClaim("k"); !ok {
+		t.Fatal("claim failed")
+	}
+	clk.Advance(59 * time.Second)
+	if !cl.Valid("k") {
+		t.Error("claim lapsed before its ttl")
+	}
+	clk.Advance(2 * time.Second)
+	if cl.Valid("k") {
+		t.Error("claim outlived its ttl")
+	}
+
+	x.Write("k", []byte("x"))
+	x.Write("k", []byte("x"))
+	clk.Advance(3 * time.Minute)
+	x.Write("k", []byte("x"))
+	if self.String() != "xx" {
+		t.Errorf("member got %q", self.String())
+	}
+
+	d := NewDebouncer(x, time.Second, 1)
+	d.Refresh([]lineProtocol.WriteCloser{self, newMember("other")})
+	clk.Advance(999 * time.Millisecond)
+	checkNum(len(x.Members()), 1, t)
+	clk.Advance(time.Millisecond)
+	checkNum(len(x.Members()), 2, t)
+}
+
+func TestClockReconnecting(t *testing.T) {
+	clk := newFakeClock()
+	dials := 0
+	r := NewReconnecting("r", func() (lineProtocol.WriteCloser, error) {
+		dials++
+		return nil, ErrMemberDown
+	})
+	r.Clock = clk
+	r.Write([]byte("x"))
+	r.Write([]byte("x"))
+	checkNum(dials, 1, t)
+	clk.Advance(r.MinBackoff)
+	r.Write([]byte("x"))
+	checkNum(dials, 2, t)
+}
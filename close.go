@@ -0,0 +1,56 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// Close shuts the hash down and closes the writer of every member.  From
+// then on Get, GetN, Write and the other routing calls fail with ErrClosed,
+// changes are refused, the methods returning an error returning ErrClosed,
+// and WaitForMembers returns ErrClosed.  Close waits for writes already in
+// flight to finish before closing the writers, so no write ever reaches a
+// closed writer through the hash.  A registered hash is unregistered.
+// Calling Close again does nothing.
+func (c *Consistent) Close() error {
+	c.lock()
+	if c.closed {
+		c.unlock()
+		return nil
+	}
+	c.closed = true
+	for k := range c.ramps {
+		c.stopRamp(k)
+	}
+	members := make([]lineProtocol.WriteCloser, 0, len(c.members))
+	for k := range c.members {
+		members = append(members, k)
+	}
+	c.advance()
+	c.unlock()
+
+	c.writing.Wait()
+	c.bus.closeAll()
+	unregister(c)
+	return closeWriters(append(members, c.reclaim.drain()...))
+}
+
+// Closed reports whether Close was called.
+func (c *Consistent) Closed() bool {
+	c.rlock()
+	defer c.runlock()
+	return c.closed
+}
+
+// beginWrite registers a write with Close, reporting false if the hash is
+// closed.  The caller calls c.writing.Done when the write returns.
+func (c *Consistent) beginWrite() bool {
+	c.rlock()
+	defer c.runlock()
+	if c.closed {
+		return false
+	}
+	c.writing.Add(1)
+	return true
+}
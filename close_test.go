@@ -0,0 +1,48 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestClose(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	if x.Closed() {
+		t.Error("expected a new hash to be open")
+	}
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !x.Closed() || !a.closed || !b.closed {
+		t.Error("expected Close to close the hash and every member")
+	}
+	if _, err := x.Get("foo"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Get: got %v, expected ErrClosed", err)
+	}
+	if _, err := x.GetN("foo", 2); !errors.Is(err, ErrClosed) {
+		t.Errorf("GetN: got %v, expected ErrClosed", err)
+	}
+	if _, err := x.Write("foo", []byte("x")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write: got %v, expected ErrClosed", err)
+	}
+	if err := x.SetWeight(a, 2); !errors.Is(err, ErrClosed) {
+		t.Errorf("SetWeight: got %v, expected ErrClosed", err)
+	}
+	x.Add(newMember("opqrstu"))
+	checkNum(len(x.Members()), 2, t)
+	if err := x.WaitForMembers(context.Background(), 5); !errors.Is(err, ErrClosed) {
+		t.Errorf("WaitForMembers: got %v, expected ErrClosed", err)
+	}
+	if err := x.Close(); err != nil {
+		t.Errorf("got %v, expected a second Close to do nothing", err)
+	}
+	checkNum(int(x.Stats().Refused), 0, t)
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// SnapshotVersion is the format version EncodeSnapshot writes.  It changes
+// when fields of Snapshot change meaning, not when fields are added.
+const SnapshotVersion = 1
+
+// snapshotMagic starts the header line EncodeSnapshot writes.
+const snapshotMagic = "consistent-snapshot"
+
+// ErrSnapshotFormat is the error returned by DecodeSnapshot for input without
+// a valid header, of a newer version, or in a codec that is not registered.
+var ErrSnapshotFormat = errors.New("unknown snapshot format")
+
+// Codec serializes snapshots.  JSONCodec is built in; codecs for formats
+// such as protobuf or msgpack are added with RegisterCodec, so this package
+// does not depend on their libraries.
+type Codec interface {
+	Name() string // recorded in the header; must not contain spaces
+	Marshal(s Snapshot) ([]byte, error)
+	Unmarshal(b []byte, s *Snapshot) error
+}
+
+// JSONCodec encodes snapshots as JSON using the field tags of Snapshot.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                          { return "json" }
+func (jsonCodec) Marshal(s Snapshot) ([]byte, error)    { return json.Marshal(s) }
+func (jsonCodec) Unmarshal(b []byte, s *Snapshot) error { return json.Unmarshal(b, s) }
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{m: map[string]Codec{"json": JSONCodec}}
+
+// RegisterCodec makes c available to DecodeSnapshot under c.Name(),
+// replacing any codec of the same name.
+func RegisterCodec(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[c.Name()] = c
+}
+
+// EncodeSnapshot writes s to w with codec, preceded by the header line
+//
+//	consistent-snapshot <version> <codec>
+//
+// so readers, including non-Go tooling, can tell which format follows.
+func EncodeSnapshot(w io.Writer, s Snapshot, codec Codec) error {
+	b, err := codec.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s %d %s\n", snapshotMagic, SnapshotVersion, codec.Name()); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// DecodeSnapshot reads a snapshot written by EncodeSnapshot, in whichever
+// registered codec its header names.
+func DecodeSnapshot(r io.Reader) (Snapshot, error) {
+	br := bufio.NewReader(r)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return Snapshot{}, ErrSnapshotFormat
+	}
+	f := strings.Fields(line)
+	if len(f) != 3 || f[0] != snapshotMagic {
+		return Snapshot{}, ErrSnapshotFormat
+	}
+	if v, err := strconv.Atoi(f[1]); err != nil || v < 1 || v > SnapshotVersion {
+		return Snapshot{}, ErrSnapshotFormat
+	}
+	codecs.RLock()
+	codec, ok := codecs.m[f[2]]
+	codecs.RUnlock()
+	if !ok {
+		return Snapshot{}, ErrSnapshotFormat
+	}
+	b, err := io.ReadAll(br)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var s Snapshot
+	if err := codec.Unmarshal(b, &s); err != nil {
+		return Snapshot{}, err
+	}
+	return s, nil
+}
+
+// WriteSnapshot writes the current state of the hash to w with codec.
+func (c *Consistent) WriteSnapshot(w io.Writer, codec Codec) error {
+	return EncodeSnapshot(w, c.Snapshot(), codec)
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot and restores it
+// like Restore.
+func (c *Consistent) ReadSnapshot(r io.Reader, lookup func(name string) (lineProtocol.WriteCloser, error)) error {
+	s, err := DecodeSnapshot(r)
+	if err != nil {
+		return err
+	}
+	return c.Restore(s, lookup)
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotCodec(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	x.SetWeight(b, 2)
+	var buf bytes.Buffer
+	if err := x.WriteSnapshot(&buf, JSONCodec); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "consistent-snapshot 1 json\n") {
+		t.Errorf("got header %q", strings.SplitN(buf.String(), "\n", 2)[0])
+	}
+	y := New()
+	if err := y.ReadSnapshot(&buf, lookupIn(a, b)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(x.Snapshot(), y.Snapshot()) {
+		t.Error("expected the snapshot to round-trip")
+	}
+
+	for _, in := range []string{"", "{}", "consistent-snapshot 2 json\n{}", "consistent-snapshot 1 xml\n<a/>"} {
+		if _, err := DecodeSnapshot(strings.NewReader(in)); !errors.Is(err, ErrSnapshotFormat) {
+			t.Errorf("%q: got %v, expected ErrSnapshotFormat", in, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// resolve moves every point of hashes that circle already holds, that an
+// earlier entry repeats, or that is reserved for another standby member, to
+// a free probe point, and returns how many points it moved.  hashes[j] is the
+// point element derives for index from+j.  The member holding a point keeps
+// it: a newcomer whose point collides probes the sequence hash(key#1),
+// hash(key#2) and so on of its vnode key instead of silently taking the point
+// over.
+// need c.rlock() before calling
+func (c *Consistent) resolve(element lineProtocol.WriteCloser, hashes []uint32, from int, circle map[uint32]lineProtocol.WriteCloser) int {
+	moved := 0
+	mine := make(map[uint32]bool, len(hashes))
+	taken := func(h uint32) bool {
+		_, ok := circle[h]
+		return ok || mine[h] || c.reservedFor(h, element)
+	}
+	for j, h := range hashes {
+		if taken(h) {
+			key := c.elementKey(element, from+j) + "#"
+			for p := 1; taken(h); p++ {
+				h = c.hashKey(key + strconv.Itoa(p))
+			}
+			hashes[j] = h
+			moved++
+		}
+		mine[h] = true
+	}
+	return moved
+}
+
+// freshHashes returns the points element takes when added now, with
+// collisions resolved, and how many points collided.
+// need c.rlock() before calling
+func (c *Consistent) freshHashes(element lineProtocol.WriteCloser) ([]uint32, int) {
+	hashes := c.derivedHashes(element)
+	return hashes, c.resolve(element, hashes, 0, c.circle)
+}
+
+// probes returns the points of element that collided and were moved, by
+// vnode index, or nil if none were.
+// need c.rlock() before calling
+func (c *Consistent) probes(element lineProtocol.WriteCloser) map[int]uint32 {
+	var p map[int]uint32
+	for i, h := range c.vnodes[element] {
+		if h != c.hashKey(c.elementKey(element, i)) {
+			if p == nil {
+				p = make(map[int]uint32)
+			}
+			p[i] = h
+		}
+	}
+	return p
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollisions(t *testing.T) {
+	// a hasher with 32 outputs makes collisions certain
+	coarse := WithHasher(func(key []byte) uint32 { return uint32(hash64(string(key)) % 32) })
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New(coarse)
+	x.NumberOfReplicas = 8
+	x.Add(a)
+	x.Add(b)
+	x.Add(c)
+	checkNum(len(x.ExportRoutingTable().Hashes), 24, t)
+	if x.Stats().Collisions == 0 {
+		t.Error("expected collisions to be counted")
+	}
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+	for _, m := range []*member{a, b, c} {
+		x.rlock()
+		owned := 0
+		for _, h := range x.vnodes[m] {
+			if x.circle[h] == m {
+				owned++
+			}
+		}
+		x.runlock()
+		checkNum(owned, 8, t)
+	}
+
+	y := New(coarse)
+	if err := y.Restore(x.Snapshot(), lookupIn(a, b, c)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(x.ExportRoutingTable().Owners, y.ExportRoutingTable().Owners) {
+		t.Error("expected Restore to reproduce the resolved points")
+	}
+
+	x.SetWeight(a, 2)
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+	checkNum(len(x.ExportRoutingTable().Hashes), 32, t)
+}
@@ -0,0 +1,180 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrUnknownEncoding is the error returned for a write to a member whose
+// encoding has no registered Compressor.
+var ErrUnknownEncoding = errors.New("unknown encoding")
+
+// Compressor compresses the writes the hash makes to members.  Every call
+// compresses one write into a self-contained stream, so a member can decode
+// each write on its own.  Gzip is built in; others, such as snappy, are added
+// with RegisterCompressor, so this package does not depend on their libraries.
+type Compressor interface {
+	Name() string
+	// Compress appends p, compressed, to dst and returns the result.
+	Compress(dst, p []byte) ([]byte, error)
+}
+
+// Encoder is implemented by members that want the hash to compress the writes
+// it makes to them.  Encoding returns the name of a registered Compressor, or
+// "" to write uncompressed.
+type Encoder interface {
+	Encoding() string
+}
+
+// Gzip compresses writes as gzip streams at the default level.
+var Gzip Compressor = gzipCompressor{}
+
+type gzipCompressor struct{}
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (gzipCompressor) Compress(dst, p []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(buf)
+	if _, err := zw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var compressors = struct {
+	sync.RWMutex
+	m map[string]Compressor
+}{m: map[string]Compressor{"gzip": Gzip}}
+
+// RegisterCompressor makes c available to members whose Encoding is
+// c.Name(), replacing any compressor of the same name.
+func RegisterCompressor(c Compressor) {
+	compressors.Lock()
+	defer compressors.Unlock()
+	compressors.m[c.Name()] = c
+}
+
+func compressor(name string) (Compressor, bool) {
+	compressors.RLock()
+	defer compressors.RUnlock()
+	c, ok := compressors.m[name]
+	return c, ok
+}
+
+// Compress returns element with the encoding encoding, so the hash compresses
+// what it writes to it.  The result forwards Ping to element if it implements
+// Pinger and keeps its MemberID.
+func Compress(encoding string, element lineProtocol.WriteCloser) lineProtocol.WriteCloser {
+	return &encoded{WriteCloser: element, encoding: encoding}
+}
+
+type encoded struct {
+	lineProtocol.WriteCloser
+	encoding string
+}
+
+func (e *encoded) Encoding() string { return e.encoding }
+
+func (e *encoded) ID() string { return MemberID(e.WriteCloser) }
+
+func (e *encoded) Unwrap() lineProtocol.WriteCloser { return e.WriteCloser }
+
+func (e *encoded) Ping(ctx context.Context) error {
+	if p, ok := e.WriteCloser.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// compression counts the bytes written to a member before and after
+// compression.
+type compression struct {
+	in, out atomic.Int64
+}
+
+// encode compresses p for element if it has an encoding.
+func (c *Consistent) encode(element lineProtocol.WriteCloser, p []byte) ([]byte, error) {
+	enc, ok := element.(Encoder)
+	if !ok {
+		return p, nil
+	}
+	name := enc.Encoding()
+	if name == "" {
+		return p, nil
+	}
+	z, ok := compressor(name)
+	if !ok {
+		return nil, ErrUnknownEncoding
+	}
+	b, err := z.Compress(nil, p)
+	if err != nil {
+		return nil, err
+	}
+	c.rlock()
+	st := c.state[element]
+	c.runlock()
+	if st != nil {
+		st.compression.in.Add(int64(len(p)))
+		st.compression.out.Add(int64(len(b)))
+	}
+	return b, nil
+}
+
+// CompressionStats is the number of bytes written to a member before and
+// after compression.
+type CompressionStats struct {
+	Member   lineProtocol.WriteCloser
+	Encoding string
+	In       int64
+	Out      int64
+}
+
+// Ratio returns Out/In, or 1 if nothing was written.
+func (s CompressionStats) Ratio() float64 {
+	if s.In == 0 {
+		return 1
+	}
+	return float64(s.Out) / float64(s.In)
+}
+
+// Compression returns the compression stats of every member with an
+// encoding, sorted by name.
+func (c *Consistent) Compression() []CompressionStats {
+	c.rlock()
+	defer c.runlock()
+	var res []CompressionStats
+	for k := range c.members {
+		enc, ok := k.(Encoder)
+		if !ok || enc.Encoding() == "" {
+			continue
+		}
+		st := c.state[k]
+		res = append(res, CompressionStats{
+			Member:   k,
+			Encoding: enc.Encoding(),
+			In:       st.compression.in.Load(),
+			Out:      st.compression.out.Load(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Member.Name() < res[j].Member.Name() })
+	return res
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestCompress(t *testing.T) {
+	far, near := newMember("far"), newMember("near")
+	x := New()
+	x.Set([]lineProtocol.WriteCloser{Identify("dc2", Compress("gzip", far)), near})
+
+	line := []byte(strings.Repeat("cpu,host=a usage=1 1\n", 50))
+	var farKey, nearKey string
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		e, _ := x.Get(k)
+		if e.Name() == "far" {
+			farKey = k
+		} else {
+			nearKey = k
+		}
+	}
+	if farKey == "" || nearKey == "" {
+		t.Fatal("keys did not reach both members")
+	}
+	if n, err := x.Write(farKey, line); err != nil || n != len(line) {
+		t.Fatalf("write to far: %d, %v", n, err)
+	}
+	x.Write(farKey, line)
+	x.Write(nearKey, line)
+
+	if !bytes.Equal(near.buf.Bytes(), line) {
+		t.Error("near member got a compressed write")
+	}
+	zr, err := gzip.NewReader(&far.buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, append(append([]byte(nil), line...), line...)) {
+		t.Errorf("far member decoded %d bytes, want %d", len(got), 2*len(line))
+	}
+
+	stats := x.Compression()
+	if len(stats) != 1 || stats[0].Member.Name() != "far" || stats[0].Encoding != "gzip" {
+		t.Fatalf("Compression() = %+v", stats)
+	}
+	if stats[0].In != int64(2*len(line)) || stats[0].Ratio() >= 0.5 {
+		t.Errorf("stats = %+v, ratio %.2f", stats[0], stats[0].Ratio())
+	}
+}
+
+type upperCompressor struct{}
+
+func (upperCompressor) Name() string { return "upper" }
+
+func (upperCompressor) Compress(dst, p []byte) ([]byte, error) {
+	return append(dst, bytes.ToUpper(p)...), nil
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	m := newMember("m")
+	x := New()
+	x.Add(Compress("snappy", m))
+	if _, err := x.Write("k", []byte("x")); !errors.Is(err, ErrUnknownEncoding) {
+		t.Fatalf("unregistered encoding: %v", err)
+	}
+
+	RegisterCompressor(upperCompressor{})
+	y := New()
+	y.Add(Compress("upper", m))
+	y.Write("k", []byte("cpu v=1"))
+	if m.buf.String() != "CPU V=1" {
+		t.Errorf("member got %q", m.buf.String())
+	}
+}
@@ -21,11 +21,15 @@
 package consistent
 
 import (
+	"context"
 	"errors"
 	"hash/crc32"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
 )
@@ -49,67 +53,287 @@ type Consistent struct {
 	circle           map[uint32]lineProtocol.WriteCloser
 	members          map[lineProtocol.WriteCloser]bool
 	sortedHashes     uints
+	overrides        []Override
+	vnodes           map[lineProtocol.WriteCloser][]uint32
+	explicit         map[lineProtocol.WriteCloser]bool
+	ramps            map[lineProtocol.WriteCloser]*ramp
+	capacities       map[lineProtocol.WriteCloser]*capacity
+	overflows        atomic.Int64
+	hasher           Hasher
+	validate         func(lineProtocol.WriteCloser) error
+	epoch            uint64
+	minMembers       int
+	observer         Observer
+	state            map[lineProtocol.WriteCloser]*memberState
+	failureRatio     float64
+	concurrency      int
+	hints            HintStore
+	hintsStored      atomic.Int64
+	hintsReplayed    atomic.Int64
+	repairer         *Repairer
+	isLeader         func() bool
+	churn            churnTracker
+	latency          *latency
+	routed           *routedCounts
+	cardinality      uint8   // HyperLogLog precision, see WithCardinality
+	bgRebuild        int     // see WithBackgroundRebuild
+	runEnd           []int32 // see linkRuns
+	closed           bool
+	writing          sync.WaitGroup // writes in flight, see Close
+	hot              *hotKeys
+	rules            []Rule
+	fallback         *Consistent
+	fallbacks        atomic.Int64
+	shadow           *shadow
+	faults           Faults
+	prefixDelim      string
+	weights          map[lineProtocol.WriteCloser]float64
+	replicas         map[lineProtocol.WriteCloser]int // see AddWithReplicas
+	aliases          map[string]lineProtocol.WriteCloser
+	factory          WriterFactory
+	owned            map[string]lineProtocol.WriteCloser // see AddAddr
+	dialAttempts     int                                 // see WithDialRetries
+	dialBackoff      time.Duration
+	admission        AdmissionPolicy // see WithAdmission
+	pending          *rebalance      // see BeginRebalance
+	statsResets      uint64          // see ResetStats
+	bus              bus             // see Events
+	dormant          atomic.Bool     // points released, see Manager.ReleaseIdle
+	conflicts        map[uint32]lineProtocol.WriteCloser
+	reclaim          reclaimer // see RemoveAddr
+	name             string    // see WithName
+	dedup            *dedupFilter
+	readReplicas     int // see WithReadReplicas
+	zonePolicy       ZonePolicy
+	standby          map[lineProtocol.WriteCloser][]uint32 // see AddStandby
+	reserved         map[uint32]lineProtocol.WriteCloser   // points of standby members
+	audit            *audit
+	clock            Clock
+	generations      map[string]uint64 // by MemberID, see Generation
+	capacityWarn     *capacityWarning
+	capacityWarnings atomic.Int64
+	reads            atomic.Uint64 // rotates GetForRead over replicas
+	queue            int64
+	queuePolicy      QueuePolicy
+	minWrites        int64
+	changed          chan struct{} // closed on the next change, see WaitForMembers
 	NumberOfReplicas int
 	count            int64
 	scratch          [64]byte
+	stats            Stats
+	unlocked         bool
+	guard            useGuard
 	sync.RWMutex
 }
 
 // New creates a new Consistent object with a default setting of 20 replicas for each entry.
 //
 // To change the number of replicas, set NumberOfReplicas before adding entries.
-func New() *Consistent {
+func New(opts ...Option) *Consistent {
 	c := new(Consistent)
 	c.NumberOfReplicas = 20
 	c.circle = make(map[uint32]lineProtocol.WriteCloser)
 	c.members = make(map[lineProtocol.WriteCloser]bool)
+	c.vnodes = make(map[lineProtocol.WriteCloser][]uint32)
+	c.explicit = make(map[lineProtocol.WriteCloser]bool)
+	c.state = make(map[lineProtocol.WriteCloser]*memberState)
+	c.failureRatio = DefaultFailureRatio
+	c.minWrites = DefaultMinWrites
+	c.churn.window = DefaultChurnWindow
+	c.clock = RealClock
+	for _, opt := range opts {
+		opt(c)
+	}
 	return c
 }
 
+func (c *Consistent) lock() {
+	if c.unlocked {
+		c.guard.lock()
+		return
+	}
+	c.Lock()
+	if c.dormant.Load() {
+		c.materialize()
+	}
+}
+
+func (c *Consistent) unlock() {
+	if c.unlocked {
+		c.guard.unlock()
+		return
+	}
+	c.Unlock()
+}
+
+func (c *Consistent) rlock() {
+	if c.unlocked {
+		c.guard.rlock()
+		return
+	}
+	c.RLock()
+	for c.dormant.Load() {
+		c.RUnlock()
+		c.Lock()
+		c.materialize()
+		c.Unlock()
+		c.RLock()
+	}
+}
+
+func (c *Consistent) runlock() {
+	if c.unlocked {
+		c.guard.runlock()
+		return
+	}
+	c.RUnlock()
+}
+
 // elementKey generates a string key for an element with an index.
 func (c *Consistent) elementKey(element lineProtocol.WriteCloser, index int) string {
-	return strconv.Itoa(index) + element.Name()
+	return strconv.Itoa(index) + MemberID(element)
 }
 
 // Add inserts a string element in the consistent hash.
 func (c *Consistent) Add(element lineProtocol.WriteCloser) {
-	c.Lock()
-	defer c.Unlock()
+	if c.bgRebuild > 0 && c.addInBackground(element) {
+		return
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return
+	}
 	c.add(element)
 }
 
-// need c.Lock() before calling
+// need c.lock() before calling
 func (c *Consistent) add(element lineProtocol.WriteCloser) {
-	for i := 0; i < c.NumberOfReplicas; i++ {
-		c.circle[c.hashKey(c.elementKey(element, i))] = element
+	if c.members[element] {
+		return
 	}
+	hashes, collided := c.freshHashes(element)
+	c.stats.Collisions += int64(collided)
+	c.place(element, hashes)
+}
+
+// place puts element on the given points and records them in the reverse
+// index.
+// need c.lock() before calling
+func (c *Consistent) place(element lineProtocol.WriteCloser, hashes []uint32) {
+	c.placeSorted(element, hashes, nil, 0)
+}
+
+// placeSorted is place with the sorted points of the resulting circle
+// prepared by the caller in d, or recomputed if sorted is nil.
+// need c.lock() before calling
+func (c *Consistent) placeSorted(element lineProtocol.WriteCloser, hashes []uint32, sorted uints, d time.Duration) {
+	for _, h := range hashes {
+		c.circle[h] = element
+	}
+	c.vnodes[element] = hashes
 	c.members[element] = true
-	c.updateSortedHashes()
+	c.state[element] = c.newState()
+	if c.standby != nil {
+		c.unreserve(element)
+	}
+	c.bus.emit(MemberAdded{Member: element, Generation: c.joined(element)})
+	c.setSorted(sorted, d)
 	c.count++
+	c.recordChurn(churnAdd, 1)
 }
 
-// Remove removes an element from the hash.
+// Remove removes an element from the hash.  It does nothing if that would
+// leave fewer members than WithMinMembers allows.
 func (c *Consistent) Remove(element lineProtocol.WriteCloser) {
-	c.Lock()
-	defer c.Unlock()
+	if c.bgRebuild > 0 && c.removeInBackground(element) {
+		return
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return
+	}
+	if _, ok := c.members[element]; ok && !c.allowShrink(len(c.members)-1) {
+		return
+	}
 	c.remove(element)
 }
 
-// need c.Lock() before calling
+// addTokens places element on points that are not derived from its name.
+// need c.lock() before calling
+func (c *Consistent) addTokens(element lineProtocol.WriteCloser, tokens []uint32) {
+	c.explicit[element] = true
+	c.place(element, tokens)
+}
+
+// hashesOf returns the points element occupies, or would occupy if added, on
+// the circle.
+// need c.rlock() before calling
+func (c *Consistent) hashesOf(element lineProtocol.WriteCloser) []uint32 {
+	if v, ok := c.vnodes[element]; ok {
+		return v
+	}
+	hashes, _ := c.freshHashes(element)
+	return hashes
+}
+
+// derivedHashes returns the points element's name hashes to.
+func (c *Consistent) derivedHashes(element lineProtocol.WriteCloser) []uint32 {
+	n := c.pointCount(element)
+	hashes := make([]uint32, 0, n)
+	for i := 0; i < n; i++ {
+		hashes = append(hashes, c.hashKey(c.elementKey(element, i)))
+	}
+	return hashes
+}
+
+// need c.lock() before calling
 func (c *Consistent) remove(element lineProtocol.WriteCloser) {
-	for i := 0; i < c.NumberOfReplicas; i++ {
-		delete(c.circle, c.hashKey(c.elementKey(element, i)))
+	c.removeSorted(element, nil, 0)
+}
+
+// removeSorted is remove with the sorted points of the resulting circle
+// prepared by the caller in d, or recomputed if sorted is nil.
+// need c.lock() before calling
+func (c *Consistent) removeSorted(element lineProtocol.WriteCloser, sorted uints, d time.Duration) {
+	if !c.members[element] {
+		return
+	}
+	for _, h := range c.vnodes[element] {
+		if c.circle[h] == element {
+			delete(c.circle, h)
+		}
 	}
 	delete(c.members, element)
-	c.updateSortedHashes()
+	delete(c.vnodes, element)
+	delete(c.explicit, element)
+	delete(c.state, element)
+	c.stopRamp(element)
+	delete(c.capacities, element)
+	delete(c.weights, element)
+	delete(c.replicas, element)
+	c.removeAliases(element)
+	c.removeOverrides(element)
+	c.bus.emit(MemberRemoved{Member: element, Generation: c.generation(element)})
+	c.setSorted(sorted, d)
 	c.count--
+	c.recordChurn(churnRemove, 1)
 }
 
 // Set sets all the elements in the hash.  If there are existing elements not
-// present in elements, they will be removed.
+// present in elements, they will be removed.  The whole change is refused if it
+// would leave fewer members than WithMinMembers allows.
 func (c *Consistent) Set(elements []lineProtocol.WriteCloser) {
-	c.Lock()
-	defer c.Unlock()
+	if c.bgRebuild > 0 && c.largeRing() {
+		c.SetCtx(context.Background(), elements)
+		return
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() || !c.allowShrink(countDistinct(elements)) {
+		return
+	}
 	for k := range c.members {
 		found := false
 		for _, v := range elements {
@@ -132,8 +356,8 @@ func (c *Consistent) Set(elements []lineProtocol.WriteCloser) {
 }
 
 func (c *Consistent) Members() []lineProtocol.WriteCloser {
-	c.RLock()
-	defer c.RUnlock()
+	c.rlock()
+	defer c.runlock()
 	var m []lineProtocol.WriteCloser
 	for k := range c.members {
 		m = append(m, k)
@@ -143,14 +367,159 @@ func (c *Consistent) Members() []lineProtocol.WriteCloser {
 
 // Get returns an element close to where name hashes to in the circle.
 func (c *Consistent) Get(name string) (lineProtocol.WriteCloser, error) {
-	c.RLock()
-	defer c.RUnlock()
+	if c.observer == nil && c.latency == nil && c.shadow == nil {
+		return c.route(name)
+	}
+	start := time.Now()
+	e, err := c.route(name)
+	d := time.Since(start)
+	if c.shadow != nil {
+		c.compareShadow(name, e, err)
+	}
+	if c.latency != nil {
+		c.latency.get.record(d)
+	}
+	if c.observer != nil {
+		c.observer.OnGet(name, e, d)
+	}
+	return e, err
+}
+
+func (c *Consistent) route(name string) (lineProtocol.WriteCloser, error) {
+	locked := c.rlockTimed()
+	defer c.runlock()
+	defer c.searched(locked)
+	return c.routeLocked(name)
+}
+
+// need c.rlock() before calling
+func (c *Consistent) routeLocked(name string) (lineProtocol.WriteCloser, error) {
+	if c.closed {
+		return nil, c.opError("get", name, nil, ErrClosed)
+	}
+	if len(c.rules) > 0 {
+		if r, ok := c.rule(name); ok {
+			switch r.Action {
+			case Redirect:
+				return r.Member, nil
+			case Delegate:
+				return r.Ring.Get(name)
+			default:
+				return nil, c.opError("get", name, nil, ErrDropped)
+			}
+		}
+	}
+	if len(c.circle) == 0 {
+		if c.fallback != nil {
+			return c.fallbackGet(name)
+		}
+		return nil, c.opError("get", name, nil, ErrEmptyCircle)
+	}
+	h := c.keyHash(name)
+	e, overflowed, err := c.get(h)
+	if overflowed {
+		c.overflows.Add(1)
+	}
+	if err != nil {
+		if c.fallback != nil {
+			return c.fallbackGet(name)
+		}
+		return nil, c.opError("get", name, nil, err)
+	}
+	if c.admission == RejectMoving && c.movingTo(h, e) != nil {
+		return nil, c.opError("get", name, e, ErrRebalancing)
+	}
+	if c.routed != nil {
+		c.countRouted(e)
+	}
+	if c.cardinality > 0 {
+		if st, ok := c.state[e]; ok {
+			st.keys.add(name)
+		}
+	}
+	if c.hot != nil {
+		c.hot.record(name, c.clock.Now())
+	}
+	return e, nil
+}
+
+// Location is a routing decision together with the circle positions behind
+// it, compact enough to log and compare across proxies.
+type Location struct {
+	Member     lineProtocol.WriteCloser
+	Hash       uint32 // hash of the key
+	Vnode      uint32 // first point on the circle after Hash
+	Generation uint64 // of Member, see Generation
+}
+
+// Locate is like Get but also returns the key's hash and the point it landed
+// after.
+func (c *Consistent) Locate(name string) (Location, error) {
+	if c.observer == nil {
+		return c.locate(name)
+	}
+	start := time.Now()
+	l, err := c.locate(name)
+	c.observer.OnGet(name, l.Member, time.Since(start))
+	return l, err
+}
+
+func (c *Consistent) locate(name string) (Location, error) {
+	c.rlock()
+	defer c.runlock()
+	if c.closed {
+		return Location{}, c.opError("locate", name, nil, ErrClosed)
+	}
+	if len(c.rules) > 0 {
+		if r, ok := c.rule(name); ok {
+			switch r.Action {
+			case Redirect:
+				return Location{Member: r.Member, Hash: c.keyHash(name), Generation: c.generation(r.Member)}, nil
+			case Delegate:
+				return r.Ring.Locate(name)
+			default:
+				return Location{}, c.opError("locate", name, nil, ErrDropped)
+			}
+		}
+	}
 	if len(c.circle) == 0 {
-		return nil, ErrEmptyCircle
+		return Location{}, c.opError("locate", name, nil, ErrEmptyCircle)
+	}
+	l := Location{Hash: c.keyHash(name)}
+	l.Vnode = c.sortedHashes[c.search(l.Hash)]
+	var (
+		overflowed bool
+		err        error
+	)
+	l.Member, overflowed, err = c.get(l.Hash)
+	if overflowed {
+		c.overflows.Add(1)
+	}
+	if err != nil {
+		return l, c.opError("locate", name, nil, err)
+	}
+	l.Generation = c.generation(l.Member)
+	return l, nil
+}
+
+// get resolves key without touching any counters.  overflowed reports whether
+// the owner was at capacity or down.
+// need c.rlock() before calling
+func (c *Consistent) get(key uint32) (e lineProtocol.WriteCloser, overflowed bool, err error) {
+	if e, ok := c.override(key); ok && !c.isDown(e) && !c.isExcluded(e) {
+		return e, false, nil
 	}
-	key := c.hashKey(name)
 	i := c.search(key)
-	return c.circle[c.sortedHashes[i]], nil
+	e = c.circle[c.sortedHashes[i]]
+	if c.isExcluded(e) {
+		e, err = c.overflow(i, e)
+		return e, false, err
+	}
+	if c.full(e) || c.isDown(e) {
+		e, err = c.overflow(i, e)
+		return e, true, err
+	}
+	return e, false, nil
 }
 
 func (c *Consistent) search(key uint32) (i int) {
@@ -164,79 +533,235 @@ func (c *Consistent) search(key uint32) (i int) {
 	return
 }
 
+// Walk calls fn for each point on the circle in ring order, starting with the
+// first point after from and wrapping around once.  Walk stops early if fn
+// returns false.  The ring is read-locked during the walk, so fn must not
+// modify it.
+func (c *Consistent) Walk(from uint32, fn func(hash uint32, element lineProtocol.WriteCloser) bool) {
+	c.rlock()
+	defer c.runlock()
+	if len(c.sortedHashes) == 0 {
+		return
+	}
+	start := c.search(from)
+	for n := 0; n < len(c.sortedHashes); n++ {
+		h := c.sortedHashes[(start+n)%len(c.sortedHashes)]
+		if !fn(h, c.circle[h]) {
+			return
+		}
+	}
+}
+
+// HashRange is an arc of the circle, from Start to End inclusive.  A range
+// with Start > End wraps past 0.
+type HashRange struct {
+	Start uint32
+	End   uint32
+}
+
+// HashRanges returns the arcs of the circle whose keys land on element's
+// points, ordered by End.  Overrides and capacity are not taken into account.
+func (c *Consistent) HashRanges(element lineProtocol.WriteCloser) []HashRange {
+	c.rlock()
+	defer c.runlock()
+	var ranges []HashRange
+	for _, h := range c.vnodes[element] {
+		if c.circle[h] != element {
+			continue
+		}
+		i := sort.Search(len(c.sortedHashes), func(x int) bool { return c.sortedHashes[x] >= h })
+		prev := c.sortedHashes[(i+len(c.sortedHashes)-1)%len(c.sortedHashes)]
+		ranges = append(ranges, HashRange{Start: prev, End: h - 1})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].End < ranges[j].End })
+	return ranges
+}
+
 // GetTwo returns the two closest distinct elements to the name input in the circle.
+// They are those of GetN(name, 2), so rules, Exclude and the zone policy
+// apply to them alike.
 func (c *Consistent) GetTwo(name string) (lineProtocol.WriteCloser, lineProtocol.WriteCloser, error) {
-	c.RLock()
-	defer c.RUnlock()
-	if len(c.circle) == 0 {
-		return nil, nil, ErrEmptyCircle
+	if c.observer == nil {
+		return c.routeTwo(name)
 	}
-	key := c.hashKey(name)
-	i := c.search(key)
-	a := c.circle[c.sortedHashes[i]]
+	start := time.Now()
+	a, b, err := c.routeTwo(name)
+	c.observer.OnGet(name, a, time.Since(start))
+	return a, b, err
+}
 
-	if c.count == 1 {
-		return a, nil, nil
+func (c *Consistent) routeTwo(name string) (lineProtocol.WriteCloser, lineProtocol.WriteCloser, error) {
+	c.rlock()
+	defer c.runlock()
+	if c.closed {
+		return nil, nil, c.opError("gettwo", name, nil, ErrClosed)
 	}
 
-	start := i
-	var b lineProtocol.WriteCloser
-	for i = start + 1; i != start; i++ {
-		if i >= len(c.sortedHashes) {
-			i = 0
-		}
-		b = c.circle[c.sortedHashes[i]]
-		if b != a {
-			break
+	if len(c.rules) > 0 {
+		if r, ok := c.rule(name); ok {
+			switch r.Action {
+			case Redirect:
+				return r.Member, nil, nil
+			case Delegate:
+				return r.Ring.GetTwo(name)
+			default:
+				return nil, nil, c.opError("gettwo", name, nil, ErrDropped)
+			}
 		}
 	}
-	return a, b, nil
+
+	if len(c.circle) == 0 {
+		return nil, nil, c.opError("gettwo", name, nil, ErrEmptyCircle)
+	}
+
+	var two [2]lineProtocol.WriteCloser
+	i := 0
+	c.walkReplicas(c.keyHash(name), 2, func(elem lineProtocol.WriteCloser, _ uint32) {
+		two[i] = elem
+		i++
+	})
+	return two[0], two[1], nil
 }
 
 // GetN returns the N closest distinct elements to the name input in the circle.
+//
+// The order is part of the API: the first element is the member owning the
+// key, or its override, and the rest follow in the order they are met walking
+// the circle clockwise from the key's hash.  Proxies sharing a topology
+// therefore agree on which replica is primary, secondary and so on; see
+// PreferenceList.  Members marked down are not skipped; members excluded with
+// Exclude that would come first follow the first member that is not.
 func (c *Consistent) GetN(name string, n int) ([]lineProtocol.WriteCloser, error) {
-	c.RLock()
-	defer c.RUnlock()
+	if c.observer == nil && c.latency == nil {
+		return c.routeN(name, n)
+	}
+	start := time.Now()
+	res, err := c.routeN(name, n)
+	d := time.Since(start)
+	if c.latency != nil {
+		c.latency.getN.record(d)
+	}
+	if c.observer != nil {
+		var first lineProtocol.WriteCloser
+		if len(res) > 0 {
+			first = res[0]
+		}
+		c.observer.OnGet(name, first, d)
+	}
+	return res, err
+}
+
+func (c *Consistent) routeN(name string, n int) ([]lineProtocol.WriteCloser, error) {
+	locked := c.rlockTimed()
+	defer c.runlock()
+	defer c.searched(locked)
+	if c.closed {
+		return nil, c.opError("getn", name, nil, ErrClosed)
+	}
+
+	if len(c.rules) > 0 {
+		if r, ok := c.rule(name); ok {
+			switch r.Action {
+			case Redirect:
+				return []lineProtocol.WriteCloser{r.Member}, nil
+			case Delegate:
+				return r.Ring.GetN(name, n)
+			default:
+				return nil, c.opError("getn", name, nil, ErrDropped)
+			}
+		}
+	}
 
 	if len(c.circle) == 0 {
-		return nil, ErrEmptyCircle
+		return nil, c.opError("getn", name, nil, ErrEmptyCircle)
 	}
 
+	return c.getN(c.keyHash(name), n), nil
+}
+
+// need c.rlock() before calling
+func (c *Consistent) getN(key uint32, n int) []lineProtocol.WriteCloser {
+	if n <= 0 {
+		return nil
+	}
+	res := make([]lineProtocol.WriteCloser, 0, min(int64(n), c.count))
+	c.walkReplicas(key, n, func(elem lineProtocol.WriteCloser, _ uint32) {
+		res = append(res, elem)
+	})
+	return res
+}
+
+// walkN calls fn with the first n distinct members of key in preference
+// order, together with the point each was found at.
+// need c.rlock() before calling
+func (c *Consistent) walkN(key uint32, n int, fn func(elem lineProtocol.WriteCloser, point uint32)) {
 	if c.count < int64(n) {
 		n = int(c.count)
 	}
+	if n <= 0 {
+		return
+	}
 
 	var (
-		key   = c.hashKey(name)
-		i     = c.search(key)
-		start = i
-		res   = make([]lineProtocol.WriteCloser, 0, n)
-		elem  = c.circle[c.sortedHashes[i]]
+		start = c.search(key)
+		seen  = make([]lineProtocol.WriteCloser, 0, n)
+		elem  = c.circle[c.sortedHashes[start]]
+		// excluded members met before the first member that can lead, held
+		// back to follow it
+		held    []lineProtocol.WriteCloser
+		points  []uint32
+		led     bool
+		emitted int
 	)
 
-	res = append(res, elem)
-
-	if len(res) == n {
-		return res, nil
+	emit := func(elem lineProtocol.WriteCloser, h uint32) {
+		if emitted < n {
+			emitted++
+			fn(elem, h)
+		}
 	}
-
-	for i = start + 1; i != start; i++ {
-		if i >= len(c.sortedHashes) {
-			i = 0
+	visit := func(elem lineProtocol.WriteCloser, h uint32) {
+		switch {
+		case led:
+			emit(elem, h)
+		case c.isExcluded(elem):
+			held, points = append(held, elem), append(points, h)
+		default:
+			led = true
+			emit(elem, h)
+			for i, e := range held {
+				emit(e, points[i])
+			}
 		}
-		elem = c.circle[c.sortedHashes[i]]
-		if !sliceContainsMember(res, elem) {
-			res = append(res, elem)
+	}
+
+	if e, ok := c.override(key); ok {
+		elem = e
+	}
+	seen = append(seen, elem)
+	visit(elem, c.sortedHashes[start])
+
+	c.walkRuns(start, func(j int) bool {
+		h := c.sortedHashes[j]
+		elem = c.circle[h]
+		if !sliceContainsMember(seen, elem) {
+			seen = append(seen, elem)
+			visit(elem, h)
 		}
-		if len(res) == n {
-			break
+		return len(seen) < n || !led
+	})
+	if !led {
+		// everyone is excluded, keep ring order
+		for i, e := range held {
+			emit(e, points[i])
 		}
 	}
-
-	return res, nil
 }
 
 func (c *Consistent) hashKey(key string) uint32 {
+	if c.hasher != nil {
+		return c.hasher([]byte(key))
+	}
 	if len(key) < 64 {
 		var scratch [64]byte
 		copy(scratch[:], key)
@@ -246,6 +771,7 @@ func (c *Consistent) hashKey(key string) uint32 {
 }
 
 func (c *Consistent) updateSortedHashes() {
+	start := time.Now()
 	hashes := c.sortedHashes[:0]
 	//reallocate if we're holding on to too much (1/4th)
 	if cap(c.sortedHashes)/(c.NumberOfReplicas*4) > len(c.circle) {
@@ -254,8 +780,36 @@ func (c *Consistent) updateSortedHashes() {
 	for k := range c.circle {
 		hashes = append(hashes, k)
 	}
-	sort.Sort(hashes)
+	slices.Sort(hashes)
 	c.sortedHashes = hashes
+	c.linkRuns()
+	c.rebuilt(time.Since(start))
+}
+
+// rebuilt records a change of the circle that took d.
+// need c.lock() before calling
+func (c *Consistent) rebuilt(d time.Duration) {
+	c.stats.recordRebuild(d)
+	c.advance()
+	c.bus.emit(RingRebuilt{Epoch: c.epoch, Points: len(c.sortedHashes), Took: d})
+}
+
+// advance moves to a new epoch and wakes anyone waiting for a change.
+// need c.lock() before calling
+func (c *Consistent) advance() {
+	c.epoch++
+	if c.changed != nil {
+		close(c.changed)
+		c.changed = nil
+	}
+}
+
+func countDistinct(elements []lineProtocol.WriteCloser) int {
+	seen := make(map[lineProtocol.WriteCloser]bool, len(elements))
+	for _, e := range elements {
+		seen[e] = true
+	}
+	return len(seen)
 }
 
 func sliceContainsMember(set []lineProtocol.WriteCloser, member lineProtocol.WriteCloser) bool {
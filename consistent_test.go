@@ -6,6 +6,7 @@ package consistent
 
 import (
 	"bufio"
+	"errors"
 	"math/rand"
 	"os"
 	"runtime"
@@ -15,12 +16,24 @@ import (
 	"testing"
 	"testing/quick"
 	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
 )
 
-func checkNum(num, expected int, t *testing.T) {
-	if num != expected {
-		t.Errorf("got %d, expected %d", num, expected)
+// node is a member equal to every other node of the same name, so tests can
+// refer to members by name.
+type node string
+
+func (n node) Name() string                { return string(n) }
+func (n node) Write(p []byte) (int, error) { return len(p), nil }
+func (n node) Close() error                { return nil }
+
+func nodes(names ...string) []lineProtocol.WriteCloser {
+	res := make([]lineProtocol.WriteCloser, len(names))
+	for i, n := range names {
+		res[i] = node(n)
 	}
+	return res
 }
 
 func TestNew(t *testing.T) {
@@ -33,13 +46,13 @@ func TestNew(t *testing.T) {
 
 func TestAdd(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
+	x.Add(node("abcdefg"))
 	checkNum(len(x.circle), 20, t)
 	checkNum(len(x.sortedHashes), 20, t)
 	if sort.IsSorted(x.sortedHashes) == false {
 		t.Errorf("expected sorted hashes to be sorted")
 	}
-	x.Add("qwer")
+	x.Add(node("qwer"))
 	checkNum(len(x.circle), 40, t)
 	checkNum(len(x.sortedHashes), 40, t)
 	if sort.IsSorted(x.sortedHashes) == false {
@@ -49,16 +62,16 @@ func TestAdd(t *testing.T) {
 
 func TestRemove(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Remove("abcdefg")
+	x.Add(node("abcdefg"))
+	x.Remove(node("abcdefg"))
 	checkNum(len(x.circle), 0, t)
 	checkNum(len(x.sortedHashes), 0, t)
 }
 
 func TestRemoveNonExisting(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Remove("abcdefghijk")
+	x.Add(node("abcdefg"))
+	x.Remove(node("abcdefghijk"))
 	checkNum(len(x.circle), 20, t)
 }
 
@@ -68,14 +81,14 @@ func TestGetEmpty(t *testing.T) {
 	if err == nil {
 		t.Errorf("expected error")
 	}
-	if err != ErrEmptyCircle {
+	if !errors.Is(err, ErrEmptyCircle) {
 		t.Errorf("expected empty circle error")
 	}
 }
 
 func TestGetSingle(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
+	x.Add(node("abcdefg"))
 	f := func(s string) bool {
 		y, err := x.Get(s)
 		if err != nil {
@@ -83,7 +96,7 @@ func TestGetSingle(t *testing.T) {
 			return false
 		}
 		t.Logf("s = %q, y = %q", s, y)
-		return y == "abcdefg"
+		return y == node("abcdefg")
 	}
 	if err := quick.Check(f, nil); err != nil {
 		t.Fatal(err)
@@ -103,15 +116,15 @@ var gmtests = []gtest{
 
 func TestGetMultiple(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	for i, v := range gmtests {
 		result, err := x.Get(v.in)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if result != v.out {
+		if result != node(v.out) {
 			t.Errorf("%d. got %q, expected %q", i, result, v.out)
 		}
 	}
@@ -119,9 +132,9 @@ func TestGetMultiple(t *testing.T) {
 
 func TestGetMultipleQuick(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	f := func(s string) bool {
 		y, err := x.Get(s)
 		if err != nil {
@@ -129,7 +142,7 @@ func TestGetMultipleQuick(t *testing.T) {
 			return false
 		}
 		t.Logf("s = %q, y = %q", s, y)
-		return y == "abcdefg" || y == "hijklmn" || y == "opqrstu"
+		return y == node("abcdefg") || y == node("hijklmn") || y == node("opqrstu")
 	}
 	if err := quick.Check(f, nil); err != nil {
 		t.Fatal(err)
@@ -150,25 +163,25 @@ var rtestsAfter = []gtest{
 
 func TestGetMultipleRemove(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	for i, v := range rtestsBefore {
 		result, err := x.Get(v.in)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if result != v.out {
+		if result != node(v.out) {
 			t.Errorf("%d. got %q, expected %q before rm", i, result, v.out)
 		}
 	}
-	x.Remove("hijklmn")
+	x.Remove(node("hijklmn"))
 	for i, v := range rtestsAfter {
 		result, err := x.Get(v.in)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if result != v.out {
+		if result != node(v.out) {
 			t.Errorf("%d. got %q, expected %q after rm", i, result, v.out)
 		}
 	}
@@ -176,10 +189,10 @@ func TestGetMultipleRemove(t *testing.T) {
 
 func TestGetMultipleRemoveQuick(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
-	x.Remove("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
+	x.Remove(node("opqrstu"))
 	f := func(s string) bool {
 		y, err := x.Get(s)
 		if err != nil {
@@ -187,7 +200,7 @@ func TestGetMultipleRemoveQuick(t *testing.T) {
 			return false
 		}
 		t.Logf("s = %q, y = %q", s, y)
-		return y == "abcdefg" || y == "hijklmn"
+		return y == node("abcdefg") || y == node("hijklmn")
 	}
 	if err := quick.Check(f, nil); err != nil {
 		t.Fatal(err)
@@ -196,9 +209,9 @@ func TestGetMultipleRemoveQuick(t *testing.T) {
 
 func TestGetTwo(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	a, b, err := x.GetTwo("99999999")
 	if err != nil {
 		t.Fatal(err)
@@ -206,19 +219,19 @@ func TestGetTwo(t *testing.T) {
 	if a == b {
 		t.Errorf("a shouldn't equal b")
 	}
-	if a != "abcdefg" {
+	if a != node("abcdefg") {
 		t.Errorf("wrong a: %q", a)
 	}
-	if b != "hijklmn" {
+	if b != node("hijklmn") {
 		t.Errorf("wrong b: %q", b)
 	}
 }
 
 func TestGetTwoQuick(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	f := func(s string) bool {
 		a, b, err := x.GetTwo(s)
 		if err != nil {
@@ -229,12 +242,12 @@ func TestGetTwoQuick(t *testing.T) {
 			t.Logf("a == b")
 			return false
 		}
-		if a != "abcdefg" && a != "hijklmn" && a != "opqrstu" {
+		if a != node("abcdefg") && a != node("hijklmn") && a != node("opqrstu") {
 			t.Logf("invalid a: %q", a)
 			return false
 		}
 
-		if b != "abcdefg" && b != "hijklmn" && b != "opqrstu" {
+		if b != node("abcdefg") && b != node("hijklmn") && b != node("opqrstu") {
 			t.Logf("invalid b: %q", b)
 			return false
 		}
@@ -247,8 +260,8 @@ func TestGetTwoQuick(t *testing.T) {
 
 func TestGetTwoOnlyTwoQuick(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
 	f := func(s string) bool {
 		a, b, err := x.GetTwo(s)
 		if err != nil {
@@ -259,12 +272,12 @@ func TestGetTwoOnlyTwoQuick(t *testing.T) {
 			t.Logf("a == b")
 			return false
 		}
-		if a != "abcdefg" && a != "hijklmn" {
+		if a != node("abcdefg") && a != node("hijklmn") {
 			t.Logf("invalid a: %q", a)
 			return false
 		}
 
-		if b != "abcdefg" && b != "hijklmn" {
+		if b != node("abcdefg") && b != node("hijklmn") {
 			t.Logf("invalid b: %q", b)
 			return false
 		}
@@ -277,7 +290,7 @@ func TestGetTwoOnlyTwoQuick(t *testing.T) {
 
 func TestGetTwoOnlyOneInCircle(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
+	x.Add(node("abcdefg"))
 	a, b, err := x.GetTwo("99999999")
 	if err != nil {
 		t.Fatal(err)
@@ -285,19 +298,19 @@ func TestGetTwoOnlyOneInCircle(t *testing.T) {
 	if a == b {
 		t.Errorf("a shouldn't equal b")
 	}
-	if a != "abcdefg" {
+	if a != node("abcdefg") {
 		t.Errorf("wrong a: %q", a)
 	}
-	if b != "" {
+	if b != nil {
 		t.Errorf("wrong b: %q", b)
 	}
 }
 
 func TestGetN(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	members, err := x.GetN("9999999", 3)
 	if err != nil {
 		t.Fatal(err)
@@ -305,22 +318,22 @@ func TestGetN(t *testing.T) {
 	if len(members) != 3 {
 		t.Errorf("expected 3 members instead of %d", len(members))
 	}
-	if members[0] != "opqrstu" {
+	if members[0] != node("opqrstu") {
 		t.Errorf("wrong members[0]: %q", members[0])
 	}
-	if members[1] != "abcdefg" {
+	if members[1] != node("abcdefg") {
 		t.Errorf("wrong members[1]: %q", members[1])
 	}
-	if members[2] != "hijklmn" {
+	if members[2] != node("hijklmn") {
 		t.Errorf("wrong members[2]: %q", members[2])
 	}
 }
 
 func TestGetNLess(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	members, err := x.GetN("99999999", 2)
 	if err != nil {
 		t.Fatal(err)
@@ -328,19 +341,19 @@ func TestGetNLess(t *testing.T) {
 	if len(members) != 2 {
 		t.Errorf("expected 2 members instead of %d", len(members))
 	}
-	if members[0] != "abcdefg" {
+	if members[0] != node("abcdefg") {
 		t.Errorf("wrong members[0]: %q", members[0])
 	}
-	if members[1] != "hijklmn" {
+	if members[1] != node("hijklmn") {
 		t.Errorf("wrong members[1]: %q", members[1])
 	}
 }
 
 func TestGetNMore(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	members, err := x.GetN("9999999", 5)
 	if err != nil {
 		t.Fatal(err)
@@ -348,22 +361,22 @@ func TestGetNMore(t *testing.T) {
 	if len(members) != 3 {
 		t.Errorf("expected 3 members instead of %d", len(members))
 	}
-	if members[0] != "opqrstu" {
+	if members[0] != node("opqrstu") {
 		t.Errorf("wrong members[0]: %q", members[0])
 	}
-	if members[1] != "abcdefg" {
+	if members[1] != node("abcdefg") {
 		t.Errorf("wrong members[1]: %q", members[1])
 	}
-	if members[2] != "hijklmn" {
+	if members[2] != node("hijklmn") {
 		t.Errorf("wrong members[2]: %q", members[2])
 	}
 }
 
 func TestGetNQuick(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	f := func(s string) bool {
 		members, err := x.GetN(s, 3)
 		if err != nil {
@@ -374,14 +387,14 @@ func TestGetNQuick(t *testing.T) {
 			t.Logf("expected 3 members instead of %d", len(members))
 			return false
 		}
-		set := make(map[string]bool, 4)
+		set := make(map[lineProtocol.WriteCloser]bool, 4)
 		for _, member := range members {
 			if set[member] {
 				t.Logf("duplicate error")
 				return false
 			}
 			set[member] = true
-			if member != "abcdefg" && member != "hijklmn" && member != "opqrstu" {
+			if member != node("abcdefg") && member != node("hijklmn") && member != node("opqrstu") {
 				t.Logf("invalid member: %q", member)
 				return false
 			}
@@ -395,9 +408,9 @@ func TestGetNQuick(t *testing.T) {
 
 func TestGetNLessQuick(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	f := func(s string) bool {
 		members, err := x.GetN(s, 2)
 		if err != nil {
@@ -408,14 +421,14 @@ func TestGetNLessQuick(t *testing.T) {
 			t.Logf("expected 2 members instead of %d", len(members))
 			return false
 		}
-		set := make(map[string]bool, 4)
+		set := make(map[lineProtocol.WriteCloser]bool, 4)
 		for _, member := range members {
 			if set[member] {
 				t.Logf("duplicate error")
 				return false
 			}
 			set[member] = true
-			if member != "abcdefg" && member != "hijklmn" && member != "opqrstu" {
+			if member != node("abcdefg") && member != node("hijklmn") && member != node("opqrstu") {
 				t.Logf("invalid member: %q", member)
 				return false
 			}
@@ -429,9 +442,9 @@ func TestGetNLessQuick(t *testing.T) {
 
 func TestGetNMoreQuick(t *testing.T) {
 	x := New()
-	x.Add("abcdefg")
-	x.Add("hijklmn")
-	x.Add("opqrstu")
+	x.Add(node("abcdefg"))
+	x.Add(node("hijklmn"))
+	x.Add(node("opqrstu"))
 	f := func(s string) bool {
 		members, err := x.GetN(s, 5)
 		if err != nil {
@@ -442,14 +455,14 @@ func TestGetNMoreQuick(t *testing.T) {
 			t.Logf("expected 3 members instead of %d", len(members))
 			return false
 		}
-		set := make(map[string]bool, 4)
+		set := make(map[lineProtocol.WriteCloser]bool, 4)
 		for _, member := range members {
 			if set[member] {
 				t.Logf("duplicate error")
 				return false
 			}
 			set[member] = true
-			if member != "abcdefg" && member != "hijklmn" && member != "opqrstu" {
+			if member != node("abcdefg") && member != node("hijklmn") && member != node("opqrstu") {
 				t.Logf("invalid member: %q", member)
 				return false
 			}
@@ -463,10 +476,10 @@ func TestGetNMoreQuick(t *testing.T) {
 
 func TestSet(t *testing.T) {
 	x := New()
-	x.Add("abc")
-	x.Add("def")
-	x.Add("ghi")
-	x.Set([]string{"jkl", "mno"})
+	x.Add(node("abc"))
+	x.Add(node("def"))
+	x.Add(node("ghi"))
+	x.Set(nodes("jkl", "mno"))
 	if x.count != 2 {
 		t.Errorf("expected 2 elts, got %d", x.count)
 	}
@@ -474,16 +487,16 @@ func TestSet(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if a != "jkl" && a != "mno" {
+	if a != node("jkl") && a != node("mno") {
 		t.Errorf("expected jkl or mno, got %s", a)
 	}
-	if b != "jkl" && b != "mno" {
+	if b != node("jkl") && b != node("mno") {
 		t.Errorf("expected jkl or mno, got %s", b)
 	}
 	if a == b {
 		t.Errorf("expected a != b, they were both %s", a)
 	}
-	x.Set([]string{"pqr", "mno"})
+	x.Set(nodes("pqr", "mno"))
 	if x.count != 2 {
 		t.Errorf("expected 2 elts, got %d", x.count)
 	}
@@ -491,16 +504,16 @@ func TestSet(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if a != "pqr" && a != "mno" {
+	if a != node("pqr") && a != node("mno") {
 		t.Errorf("expected jkl or mno, got %s", a)
 	}
-	if b != "pqr" && b != "mno" {
+	if b != node("pqr") && b != node("mno") {
 		t.Errorf("expected jkl or mno, got %s", b)
 	}
 	if a == b {
 		t.Errorf("expected a != b, they were both %s", a)
 	}
-	x.Set([]string{"pqr", "mno"})
+	x.Set(nodes("pqr", "mno"))
 	if x.count != 2 {
 		t.Errorf("expected 2 elts, got %d", x.count)
 	}
@@ -508,10 +521,10 @@ func TestSet(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if a != "pqr" && a != "mno" {
+	if a != node("pqr") && a != node("mno") {
 		t.Errorf("expected jkl or mno, got %s", a)
 	}
-	if b != "pqr" && b != "mno" {
+	if b != node("pqr") && b != node("mno") {
 		t.Errorf("expected jkl or mno, got %s", b)
 	}
 	if a == b {
@@ -540,12 +553,12 @@ func mallocNum(f func()) uint64 {
 
 func BenchmarkAllocations(b *testing.B) {
 	x := New()
-	x.Add("stays")
+	x.Add(node("stays"))
 	b.ResetTimer()
 	allocSize := allocBytes(func() {
 		for i := 0; i < b.N; i++ {
-			x.Add("Foo")
-			x.Remove("Foo")
+			x.Add(node("Foo"))
+			x.Remove(node("Foo"))
 		}
 	})
 	b.Logf("%d: Allocated %d bytes (%.2fx)", b.N, allocSize, float64(allocSize)/float64(b.N))
@@ -553,12 +566,12 @@ func BenchmarkAllocations(b *testing.B) {
 
 func BenchmarkMalloc(b *testing.B) {
 	x := New()
-	x.Add("stays")
+	x.Add(node("stays"))
 	b.ResetTimer()
 	mallocs := mallocNum(func() {
 		for i := 0; i < b.N; i++ {
-			x.Add("Foo")
-			x.Remove("Foo")
+			x.Add(node("Foo"))
+			x.Remove(node("Foo"))
 		}
 	})
 	b.Logf("%d: Mallocd %d times (%.2fx)", b.N, mallocs, float64(mallocs)/float64(b.N))
@@ -566,29 +579,29 @@ func BenchmarkMalloc(b *testing.B) {
 
 func BenchmarkCycle(b *testing.B) {
 	x := New()
-	x.Add("nothing")
+	x.Add(node("nothing"))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		x.Add("foo" + strconv.Itoa(i))
-		x.Remove("foo" + strconv.Itoa(i))
+		x.Add(node("foo" + strconv.Itoa(i)))
+		x.Remove(node("foo" + strconv.Itoa(i)))
 	}
 }
 
 func BenchmarkCycleLarge(b *testing.B) {
 	x := New()
 	for i := 0; i < 10; i++ {
-		x.Add("start" + strconv.Itoa(i))
+		x.Add(node("start" + strconv.Itoa(i)))
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		x.Add("foo" + strconv.Itoa(i))
-		x.Remove("foo" + strconv.Itoa(i))
+		x.Add(node("foo" + strconv.Itoa(i)))
+		x.Remove(node("foo" + strconv.Itoa(i)))
 	}
 }
 
 func BenchmarkGet(b *testing.B) {
 	x := New()
-	x.Add("nothing")
+	x.Add(node("nothing"))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		x.Get("nothing")
@@ -598,7 +611,7 @@ func BenchmarkGet(b *testing.B) {
 func BenchmarkGetLarge(b *testing.B) {
 	x := New()
 	for i := 0; i < 10; i++ {
-		x.Add("start" + strconv.Itoa(i))
+		x.Add(node("start" + strconv.Itoa(i)))
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -608,7 +621,7 @@ func BenchmarkGetLarge(b *testing.B) {
 
 func BenchmarkGetN(b *testing.B) {
 	x := New()
-	x.Add("nothing")
+	x.Add(node("nothing"))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		x.GetN("nothing", 3)
@@ -618,7 +631,7 @@ func BenchmarkGetN(b *testing.B) {
 func BenchmarkGetNLarge(b *testing.B) {
 	x := New()
 	for i := 0; i < 10; i++ {
-		x.Add("start" + strconv.Itoa(i))
+		x.Add(node("start" + strconv.Itoa(i)))
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -628,7 +641,7 @@ func BenchmarkGetNLarge(b *testing.B) {
 
 func BenchmarkGetTwo(b *testing.B) {
 	x := New()
-	x.Add("nothing")
+	x.Add(node("nothing"))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		x.GetTwo("nothing")
@@ -638,7 +651,7 @@ func BenchmarkGetTwo(b *testing.B) {
 func BenchmarkGetTwoLarge(b *testing.B) {
 	x := New()
 	for i := 0; i < 10; i++ {
-		x.Add("start" + strconv.Itoa(i))
+		x.Add(node("start" + strconv.Itoa(i)))
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -653,8 +666,8 @@ func TestAddCollision(t *testing.T) {
 	const s1 = "abear"
 	const s2 = "solidiform"
 	x := New()
-	x.Add(s1)
-	x.Add(s2)
+	x.Add(node(s1))
+	x.Add(node(s2))
 	elt1, err := x.Get("abear")
 	if err != nil {
 		t.Fatal("unexpected error:", err)
@@ -662,8 +675,8 @@ func TestAddCollision(t *testing.T) {
 
 	y := New()
 	// add elements in opposite order
-	y.Add(s2)
-	y.Add(s1)
+	y.Add(node(s2))
+	y.Add(node(s1))
 	elt2, err := y.Get(s1)
 	if err != nil {
 		t.Fatal("unexpected error:", err)
@@ -689,7 +702,7 @@ func TestCollisionsCRC(t *testing.T) {
 	for scanner.Scan() {
 		word := scanner.Text()
 		for i := 0; i < c.NumberOfReplicas; i++ {
-			ekey := c.eltKey(word, i)
+			ekey := c.elementKey(node(word), i)
 			// ekey := word + "|" + strconv.Itoa(i)
 			k := c.hashKey(ekey)
 			exist, ok := found[k]
@@ -706,16 +719,16 @@ func TestCollisionsCRC(t *testing.T) {
 
 func TestConcurrentGetSet(t *testing.T) {
 	x := New()
-	x.Set([]string{"abc", "def", "ghi", "jkl", "mno"})
+	x.Set(nodes("abc", "def", "ghi", "jkl", "mno"))
 
 	var wg sync.WaitGroup
 	for i := 0; i < 10; i++ {
 		wg.Add(1)
 		go func() {
 			for i := 0; i < 1000; i++ {
-				x.Set([]string{"abc", "def", "ghi", "jkl", "mno"})
+				x.Set(nodes("abc", "def", "ghi", "jkl", "mno"))
 				time.Sleep(time.Duration(rand.Intn(10)) * time.Millisecond)
-				x.Set([]string{"pqr", "stu", "vwx"})
+				x.Set(nodes("pqr", "stu", "vwx"))
 				time.Sleep(time.Duration(rand.Intn(10)) * time.Millisecond)
 			}
 			wg.Done()
@@ -730,7 +743,7 @@ func TestConcurrentGetSet(t *testing.T) {
 				if err != nil {
 					t.Error(err)
 				}
-				if a != "def" && a != "vwx" {
+				if a != node("def") && a != node("vwx") {
 					t.Errorf("got %s, expected abc", a)
 				}
 				time.Sleep(time.Duration(rand.Intn(10)) * time.Millisecond)
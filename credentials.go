@@ -0,0 +1,108 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Credentials are what SecureTCPWriters connects to a member with.
+type Credentials struct {
+	// TLS, if not nil, wraps the connection in TLS.  ServerName defaults to
+	// the host of the member's address.
+	TLS *tls.Config
+	// Handshake, if not nil, runs on every new connection before the first
+	// write, to authenticate it.
+	Handshake func(conn net.Conn) error
+}
+
+// SecretsFunc returns the credentials for the member at addr.  It is called
+// for every connection attempt, so it can hand out rotated credentials.
+type SecretsFunc func(addr string) (Credentials, error)
+
+// SecureTCPWriters returns a WriterFactory like TCPWriters whose connections
+// use the credentials secrets returns for their address.  Because the
+// credentials are fetched on every dial, rotating them only takes
+// RotateCredentials: members reconnect under the same name and no key moves.
+func SecureTCPWriters(secrets SecretsFunc) WriterFactory {
+	return WriterFactoryFunc(func(addr string) (lineProtocol.WriteCloser, error) {
+		return NewReconnecting(addr, func() (lineProtocol.WriteCloser, error) {
+			return dialSecure(addr, secrets)
+		}), nil
+	})
+}
+
+func dialSecure(addr string, secrets SecretsFunc) (lineProtocol.WriteCloser, error) {
+	cred, err := secrets(addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", addr, DefaultDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if cred.TLS != nil {
+		cfg := cred.TLS
+		if cfg.ServerName == "" {
+			cfg = cfg.Clone()
+			cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tc := tls.Client(conn, cfg)
+		tc.SetDeadline(time.Now().Add(DefaultDialTimeout))
+		if err := tc.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tc.SetDeadline(time.Time{})
+		conn = tc
+	}
+	if cred.Handshake != nil {
+		if err := cred.Handshake(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return &connWriter{Conn: conn, name: addr}, nil
+}
+
+// redialer is implemented by members that can reconnect in place, such as
+// Reconnecting.
+type redialer interface {
+	Redial() error
+}
+
+// RotateCredentials makes the members AddAddr created for addrs, or all of
+// them if addrs is empty, drop their connections so the next write dials
+// with fresh credentials.  Members keep their names and points, so no key is
+// remapped.  Members that cannot redial are left alone.
+func (c *Consistent) RotateCredentials(addrs ...string) error {
+	c.rlock()
+	var ws []lineProtocol.WriteCloser
+	if len(addrs) == 0 {
+		for _, w := range c.owned {
+			ws = append(ws, w)
+		}
+	}
+	for _, a := range addrs {
+		if w, ok := c.owned[a]; ok {
+			ws = append(ws, w)
+		}
+	}
+	c.runlock()
+	var errs []error
+	for _, w := range ws {
+		if r, ok := w.(redialer); ok {
+			if err := r.Redial(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// tlsServer accepts TLS connections and records the token each one
+// authenticates with and the lines written after it.
+type tlsServer struct {
+	ln     net.Listener
+	client *tls.Config
+	mu     sync.Mutex
+	tokens []string
+	lines  chan string
+}
+
+func newTLSServer(t *testing.T) *tlsServer {
+	hs := httptest.NewTLSServer(http.NotFoundHandler())
+	client := hs.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: hs.TLS.Certificates})
+	hs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &tlsServer{ln: ln, client: client, lines: make(chan string, 16)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *tlsServer) serve(conn net.Conn) {
+	defer conn.Close()
+	sc := bufio.NewScanner(conn)
+	if !sc.Scan() {
+		return
+	}
+	s.mu.Lock()
+	s.tokens = append(s.tokens, sc.Text())
+	s.mu.Unlock()
+	for sc.Scan() {
+		s.lines <- sc.Text()
+	}
+}
+
+func TestSecureTCPWriters(t *testing.T) {
+	srv := newTLSServer(t)
+	addr := srv.ln.Addr().String()
+	var mu sync.Mutex
+	token := "v1"
+	secrets := func(a string) (Credentials, error) {
+		mu.Lock()
+		tok := token
+		mu.Unlock()
+		return Credentials{
+			TLS: srv.client,
+			Handshake: func(conn net.Conn) error {
+				_, err := fmt.Fprintf(conn, "AUTH %s\n", tok)
+				return err
+			},
+		}, nil
+	}
+	x := New(WithWriterFactory(SecureTCPWriters(secrets)))
+	x.Add(newMember("other"))
+	w, err := x.AddAddr(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprintf("key%d", i)
+		e, _ := x.Get(k)
+		before[k] = e.Name()
+	}
+
+	if _, err := w.Write([]byte("cpu v=1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-srv.lines; got != "cpu v=1" {
+		t.Fatalf("server got %q", got)
+	}
+
+	mu.Lock()
+	token = "v2"
+	mu.Unlock()
+	if err := x.RotateCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("cpu v=2\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-srv.lines; got != "cpu v=2" {
+		t.Fatalf("server got %q", got)
+	}
+	srv.mu.Lock()
+	tokens := append([]string(nil), srv.tokens...)
+	srv.mu.Unlock()
+	if len(tokens) != 2 || tokens[0] != "AUTH v1" || tokens[1] != "AUTH v2" {
+		t.Errorf("server saw tokens %q", tokens)
+	}
+	for k, name := range before {
+		if e, _ := x.Get(k); e.Name() != name {
+			t.Fatalf("%s moved from %s to %s after rotation", k, name, e.Name())
+		}
+	}
+	x.Close()
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Debouncer sits between a discovery source and Set so that flapping
+// members do not remap keys on every refresh.  Changes are batched over a
+// window and applied together, and a member of the hash is only removed
+// once it has been missing from misses consecutive refreshes.  A member
+// that joins and leaves again within one window never reaches the hash.
+type Debouncer struct {
+	c      *Consistent
+	window time.Duration
+	misses int
+
+	mu     sync.Mutex
+	missed map[lineProtocol.WriteCloser]int
+	target []lineProtocol.WriteCloser
+	timer  Timer
+}
+
+// NewDebouncer creates a Debouncer for c.  window 0 applies every refresh
+// at once, and misses < 1 is 1, removing members as soon as they are missed.
+func NewDebouncer(c *Consistent, window time.Duration, misses int) *Debouncer {
+	if misses < 1 {
+		misses = 1
+	}
+	return &Debouncer{c: c, window: window, misses: misses, missed: make(map[lineProtocol.WriteCloser]int)}
+}
+
+// Refresh records the members discovery currently reports.  The hash is
+// updated when the window that Refresh opens ends, if its members differ
+// from what the refreshes so far add up to.
+func (d *Debouncer) Refresh(elements []lineProtocol.WriteCloser) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	seen := make(map[lineProtocol.WriteCloser]bool, len(elements))
+	target := make([]lineProtocol.WriteCloser, 0, len(elements))
+	for _, e := range elements {
+		if !seen[e] {
+			seen[e] = true
+			target = append(target, e)
+		}
+		delete(d.missed, e)
+	}
+	current := d.c.Members()
+	for _, m := range current {
+		if seen[m] {
+			continue
+		}
+		d.missed[m]++
+		if d.missed[m] < d.misses {
+			target = append(target, m)
+		}
+	}
+	d.target = target
+	if sameSet(current, target) {
+		d.stop()
+		return
+	}
+	if d.window <= 0 {
+		d.apply()
+		return
+	}
+	if d.timer == nil {
+		d.timer = d.c.clock.AfterFunc(d.window, d.Flush)
+	}
+}
+
+// Flush applies the pending change now instead of when its window ends.
+func (d *Debouncer) Flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.apply()
+}
+
+// Pending reports whether a change is waiting for its window to end.
+func (d *Debouncer) Pending() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.target != nil
+}
+
+// Stop drops the pending change, if any.
+func (d *Debouncer) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stop()
+}
+
+// need d.mu held before calling
+func (d *Debouncer) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.target = nil
+}
+
+// need d.mu held before calling
+func (d *Debouncer) apply() {
+	target := d.target
+	d.stop()
+	if target == nil {
+		return
+	}
+	d.c.Set(target)
+	kept := make(map[lineProtocol.WriteCloser]bool, len(target))
+	for _, e := range target {
+		kept[e] = true
+	}
+	for m := range d.missed {
+		if !kept[m] {
+			delete(d.missed, m)
+		}
+	}
+}
+
+// sameSet reports whether a and b, each without repeats, hold the same
+// members.
+func sameSet(a, b []lineProtocol.WriteCloser) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	in := make(map[lineProtocol.WriteCloser]bool, len(a))
+	for _, e := range a {
+		in[e] = true
+	}
+	for _, e := range b {
+		if !in[e] {
+			return false
+		}
+	}
+	return true
+}
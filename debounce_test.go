@@ -0,0 +1,72 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestDebouncerMisses(t *testing.T) {
+	a, b, c := newMember("a"), newMember("b"), newMember("c")
+	x := New()
+	x.Set([]lineProtocol.WriteCloser{a, b, c})
+	epoch := x.Epoch()
+	d := NewDebouncer(x, 0, 3)
+
+	d.Refresh([]lineProtocol.WriteCloser{a, b})
+	d.Refresh([]lineProtocol.WriteCloser{a, b})
+	d.Refresh([]lineProtocol.WriteCloser{a, b, c})
+	d.Refresh([]lineProtocol.WriteCloser{a, b})
+	if x.Epoch() != epoch {
+		t.Fatal("c removed before it was missed 3 times in a row")
+	}
+	d.Refresh([]lineProtocol.WriteCloser{a, b})
+	d.Refresh([]lineProtocol.WriteCloser{a, b})
+	checkNum(len(x.Members()), 2, t)
+	for _, m := range x.Members() {
+		if m == c {
+			t.Error("c still a member")
+		}
+	}
+}
+
+func TestDebouncerWindow(t *testing.T) {
+	a, b, c := newMember("a"), newMember("b"), newMember("c")
+	x := New()
+	x.Set([]lineProtocol.WriteCloser{a, b})
+	epoch := x.Epoch()
+	d := NewDebouncer(x, time.Hour, 1)
+
+	d.Refresh([]lineProtocol.WriteCloser{a, b, c})
+	d.Refresh([]lineProtocol.WriteCloser{a, b})
+	if d.Pending() {
+		t.Error("a flap within the window left a change pending")
+	}
+	d.Refresh([]lineProtocol.WriteCloser{a, c})
+	if !d.Pending() || x.Epoch() != epoch {
+		t.Fatal("expected the change to wait for the window")
+	}
+	d.Flush()
+	if d.Pending() {
+		t.Error("change still pending after Flush")
+	}
+	ms := x.Members()
+	if len(ms) != 2 || !sliceContainsMember(ms, a) || !sliceContainsMember(ms, c) {
+		t.Errorf("members = %v", ms)
+	}
+
+	d = NewDebouncer(x, 5*time.Millisecond, 1)
+	d.Refresh([]lineProtocol.WriteCloser{a})
+	for deadline := time.Now().Add(time.Second); d.Pending(); {
+		if time.Now().After(deadline) {
+			t.Fatal("window never ended")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	checkNum(len(x.Members()), 1, t)
+}
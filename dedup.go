@@ -0,0 +1,121 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"hash/maphash"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// dedupFilter remembers the fingerprints of recent writes in two
+// generations, swapped every window, so a fingerprint is remembered for
+// between one and two windows without tracking the age of each.
+type dedupFilter struct {
+	seed   maphash.Seed
+	window time.Duration
+
+	mu      sync.Mutex
+	cur     map[uint64]struct{}
+	prev    map[uint64]struct{}
+	rotated time.Time
+}
+
+// WithDedup makes Write drop a write whose key and payload match one
+// written successfully within the last window, as upstream agents resend on
+// timeout.  A dropped write succeeds without reaching the member and is
+// counted for it in Duplicates.  Writes are compared by a 64-bit hash, and
+// identical writes racing each other may both get through.
+func WithDedup(window time.Duration) Option {
+	return func(c *Consistent) {
+		c.dedup = &dedupFilter{
+			seed:   maphash.MakeSeed(),
+			window: window,
+			cur:    make(map[uint64]struct{}),
+			prev:   make(map[uint64]struct{}),
+		}
+	}
+}
+
+func (f *dedupFilter) fingerprint(key string, p []byte) uint64 {
+	var h maphash.Hash
+	h.SetSeed(f.seed)
+	h.WriteString(key)
+	h.WriteByte(0)
+	h.Write(p)
+	return h.Sum64()
+}
+
+// need f.mu.Lock() before calling
+func (f *dedupFilter) rotate(now time.Time) {
+	if d := now.Sub(f.rotated); d >= f.window {
+		if d >= 2*f.window {
+			// nothing in cur is recent enough to keep either
+			clear(f.cur)
+		}
+		f.prev, f.cur = f.cur, f.prev
+		clear(f.cur)
+		f.rotated = now
+	}
+}
+
+// seen reports whether fp was written within the window.
+func (f *dedupFilter) seen(fp uint64, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rotate(now)
+	if _, ok := f.cur[fp]; ok {
+		return true
+	}
+	_, ok := f.prev[fp]
+	return ok
+}
+
+// record remembers fp as written now.
+func (f *dedupFilter) record(fp uint64, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rotate(now)
+	f.cur[fp] = struct{}{}
+}
+
+// duplicate reports whether the write to e fingerprinted fp repeats a
+// recent one, counting it for e if so.
+func (c *Consistent) duplicate(e lineProtocol.WriteCloser, fp uint64) bool {
+	if !c.dedup.seen(fp, c.clock.Now()) {
+		return false
+	}
+	c.rlock()
+	defer c.runlock()
+	if st, ok := c.state[e]; ok {
+		st.duplicates.Add(1)
+	}
+	return true
+}
+
+// DuplicateCount is the number of writes to a member dropped as duplicates.
+type DuplicateCount struct {
+	Member     lineProtocol.WriteCloser
+	Suppressed int64
+}
+
+// Duplicates returns the writes dropped as duplicates for every member,
+// sorted by name, or nil if the hash was not created WithDedup.
+func (c *Consistent) Duplicates() []DuplicateCount {
+	if c.dedup == nil {
+		return nil
+	}
+	c.rlock()
+	defer c.runlock()
+	res := make([]DuplicateCount, 0, len(c.members))
+	for k := range c.members {
+		res = append(res, DuplicateCount{Member: k, Suppressed: c.state[k].duplicates.Load()})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Member.Name() < res[j].Member.Name() })
+	return res
+}
@@ -0,0 +1,51 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	m := newMember("a")
+	x := New(WithDedup(20 * time.Millisecond))
+	x.Add(m)
+	x.Write("cpu", []byte("cpu v=1 1\n"))
+	if n, err := x.Write("cpu", []byte("cpu v=1 1\n")); err != nil || n != 10 {
+		t.Fatalf("duplicate write: %d, %v", n, err)
+	}
+	x.Write("cpu", []byte("cpu v=2 1\n"))
+	x.Write("mem", []byte("cpu v=1 1\n"))
+	if got := m.String(); got != "cpu v=1 1\ncpu v=2 1\ncpu v=1 1\n" {
+		t.Errorf("member got %q", got)
+	}
+	if d := x.Duplicates(); len(d) != 1 || d[0].Suppressed != 1 {
+		t.Errorf("Duplicates() = %+v", d)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	x.Write("cpu", []byte("cpu v=1 1\n"))
+	if d := x.Duplicates(); d[0].Suppressed != 1 {
+		t.Error("suppressed a write outside the window")
+	}
+}
+
+func TestDedupFailedWrite(t *testing.T) {
+	m := newMember("a")
+	x := New(WithDedup(time.Minute))
+	x.Add(m)
+	m.err = errors.New("timeout")
+	x.Write("cpu", []byte("x"))
+	m.err = nil
+	x.Write("cpu", []byte("x"))
+	if m.String() != "x" {
+		t.Error("a retry of a failed write was dropped")
+	}
+	if New().Duplicates() != nil {
+		t.Error("expected no counts without WithDedup")
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ChangeSet is what changed between two snapshots, as reported by
+// DiffSnapshots.
+type ChangeSet struct {
+	Added   []string     // IDs of members only in the second snapshot
+	Removed []string     // IDs of members only in the first snapshot
+	Weights []WeightDiff // members in both whose weight changed
+	Moved   []RangeMove  // arcs of the circle that changed owner, in hash order
+}
+
+// WeightDiff is the change of weight of one member between snapshots.  A
+// member without a weight of its own has weight 1.
+type WeightDiff struct {
+	Member   string
+	From, To float64
+}
+
+// RangeMove is an arc of the circle whose keys moved from one member to
+// another.  From is "" for keys that had no owner, and To for keys left
+// without one.
+type RangeMove struct {
+	HashRange
+	From, To string
+}
+
+// DiffSnapshots compares the snapshots a and b.  The moved ranges are found
+// by placing both on rings created with opts, which must give the hash
+// function the snapshots were taken with since snapshots do not record it.
+// Moves compare primary placement on the circle; overrides, exclusions and
+// members marked down are not taken into account.
+func DiffSnapshots(a, b Snapshot, opts ...Option) ChangeSet {
+	var cs ChangeSet
+	cs.Added, cs.Removed, _ = memberDiff(a, b)
+	sort.Strings(cs.Added)
+	gone := make(map[string]bool, len(cs.Removed))
+	for _, m := range cs.Removed {
+		gone[m] = true
+	}
+	for _, m := range a.Members {
+		if from, to := snapshotWeight(a, m), snapshotWeight(b, m); !gone[m] && from != to {
+			cs.Weights = append(cs.Weights, WeightDiff{Member: m, From: from, To: to})
+		}
+	}
+	sort.Slice(cs.Weights, func(i, j int) bool { return cs.Weights[i].Member < cs.Weights[j].Member })
+	cs.Moved = movedRanges(snapshotRing(a, opts), snapshotRing(b, opts))
+	return cs
+}
+
+func snapshotWeight(s Snapshot, id string) float64 {
+	if w, ok := s.Weights[id]; ok {
+		return w
+	}
+	return 1
+}
+
+// snapshotRing places s on a new ring, with a placeholder writer per member.
+// Overrides are dropped since DiffSnapshots ignores them, which leaves
+// nothing for Restore to reject.
+func snapshotRing(s Snapshot, opts []Option) *Consistent {
+	s.Overrides = nil
+	c := New(opts...)
+	c.Restore(s, func(id string) (lineProtocol.WriteCloser, error) { return simMember(id), nil })
+	return c
+}
+
+// movedRanges returns the arcs owned by different members on a and b.
+func movedRanges(a, b *Consistent) []RangeMove {
+	a.rlock()
+	defer a.runlock()
+	b.rlock()
+	defer b.runlock()
+	bounds := mergeSorted(a.sortedHashes, b.sortedHashes)
+	if len(bounds) == 0 {
+		return nil
+	}
+	owner := func(c *Consistent, h uint32) string {
+		if len(c.sortedHashes) == 0 {
+			return ""
+		}
+		return MemberID(c.circle[c.sortedHashes[c.search(h)]])
+	}
+	// Arcs run from one point up to just before the next; the last one
+	// wraps past 0 to the first point.
+	var moves []RangeMove
+	for i, start := range bounds {
+		end := bounds[(i+1)%len(bounds)] - 1
+		from, to := owner(a, start), owner(b, start)
+		if from == to {
+			continue
+		}
+		if n := len(moves); n > 0 && moves[n-1].End+1 == start && moves[n-1].From == from && moves[n-1].To == to {
+			moves[n-1].End = end
+			continue
+		}
+		moves = append(moves, RangeMove{HashRange: HashRange{Start: start, End: end}, From: from, To: to})
+	}
+	if n := len(moves); n > 1 && moves[n-1].End+1 == moves[0].Start && moves[n-1].From == moves[0].From && moves[n-1].To == moves[0].To {
+		moves[0].Start = moves[n-1].Start
+		moves = moves[:n-1]
+	}
+	return moves
+}
+
+// mergeSorted returns the distinct values of the sorted slices a and b, in
+// order.
+func mergeSorted(a, b []uint32) []uint32 {
+	res := make([]uint32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		var v uint32
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			v = a[i]
+			i++
+		case i == len(a) || b[j] < a[i]:
+			v = b[j]
+			j++
+		default:
+			v = a[i]
+			i++
+			j++
+		}
+		if len(res) == 0 || res[len(res)-1] != v {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// Size returns the number of hashes in r.
+func (r HashRange) Size() uint64 {
+	return uint64(r.End-r.Start) + 1
+}
+
+// MovedFraction returns the share of the hash space that changed owner.
+func (s ChangeSet) MovedFraction() float64 {
+	var n uint64
+	for _, m := range s.Moved {
+		n += m.Size()
+	}
+	return float64(n) / (1 << 32)
+}
+
+// String reports the change set for people, with moves summed up by the
+// pair of members involved.
+func (s ChangeSet) String() string {
+	var b strings.Builder
+	for _, m := range s.Added {
+		fmt.Fprintf(&b, "+ %s\n", m)
+	}
+	for _, m := range s.Removed {
+		fmt.Fprintf(&b, "- %s\n", m)
+	}
+	for _, w := range s.Weights {
+		fmt.Fprintf(&b, "~ %s weight %g -> %g\n", w.Member, w.From, w.To)
+	}
+	fmt.Fprintf(&b, "moved %.2f%% of the hash space in %d ranges\n", 100*s.MovedFraction(), len(s.Moved))
+	type pair struct{ from, to string }
+	sizes := make(map[pair]uint64)
+	var pairs []pair
+	for _, m := range s.Moved {
+		p := pair{m.From, m.To}
+		if _, ok := sizes[p]; !ok {
+			pairs = append(pairs, p)
+		}
+		sizes[p] += m.Size()
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].from != pairs[j].from {
+			return pairs[i].from < pairs[j].from
+		}
+		return pairs[i].to < pairs[j].to
+	})
+	for _, p := range pairs {
+		from, to := p.from, p.to
+		if from == "" {
+			from = "(none)"
+		}
+		if to == "" {
+			to = "(none)"
+		}
+		fmt.Fprintf(&b, "  %s -> %s: %.2f%%\n", from, to, 100*float64(sizes[p])/(1<<32))
+	}
+	return b.String()
+}
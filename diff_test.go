@@ -0,0 +1,83 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	a, b, c := newMember("a"), newMember("b"), newMember("c")
+	x := New()
+	x.Set([]lineProtocol.WriteCloser{a, b})
+	before := x.Snapshot()
+	owners := make(map[string]string)
+	for i := 0; i < 2000; i++ {
+		k := fmt.Sprint(i)
+		e, _ := x.Get(k)
+		owners[k] = e.Name()
+	}
+	x.Remove(a)
+	x.Add(c)
+	x.SetWeight(b, 2)
+	after := x.Snapshot()
+
+	cs := DiffSnapshots(before, after)
+	if len(cs.Added) != 1 || cs.Added[0] != "c" || len(cs.Removed) != 1 || cs.Removed[0] != "a" {
+		t.Fatalf("added %v, removed %v", cs.Added, cs.Removed)
+	}
+	if len(cs.Weights) != 1 || cs.Weights[0] != (WeightDiff{"b", 1, 2}) {
+		t.Errorf("weights %+v", cs.Weights)
+	}
+	var total uint64
+	for _, m := range cs.Moved {
+		total += m.Size()
+		if m.From == m.To {
+			t.Errorf("move %+v to the same member", m)
+		}
+	}
+	if total != uint64(cs.MovedFraction()*(1<<32)) || total == 0 {
+		t.Errorf("moved %d hashes, fraction %f", total, cs.MovedFraction())
+	}
+	for k, was := range owners {
+		e, _ := x.Get(k)
+		h := x.keyHash(k)
+		moved := false
+		for _, m := range cs.Moved {
+			if in := m.Start <= m.End && h >= m.Start && h <= m.End || m.Start > m.End && (h >= m.Start || h <= m.End); in {
+				if m.From != was || m.To != e.Name() {
+					t.Fatalf("%s in %+v, but moved %s -> %s", k, m, was, e.Name())
+				}
+				moved = true
+			}
+		}
+		if moved != (was != e.Name()) {
+			t.Fatalf("%s moved %s -> %s, in a moved range: %v", k, was, e.Name(), moved)
+		}
+	}
+	report := cs.String()
+	for _, s := range []string{"+ c\n", "- a\n", "~ b weight 1 -> 2\n", "  a -> b: ", "  a -> c: "} {
+		if !strings.Contains(report, s) {
+			t.Errorf("report lacks %q:\n%s", s, report)
+		}
+	}
+
+	if cs := DiffSnapshots(after, after); len(cs.Moved) != 0 || len(cs.Added)+len(cs.Removed)+len(cs.Weights) != 0 {
+		t.Errorf("diff of a snapshot with itself: %+v", cs)
+	}
+	cs = DiffSnapshots(Snapshot{}, after)
+	if cs.MovedFraction() != 1 {
+		t.Errorf("diff from empty moved %f of the circle", cs.MovedFraction())
+	}
+	for _, m := range cs.Moved {
+		if m.From != "" {
+			t.Errorf("move %+v from an empty ring", m)
+		}
+	}
+}
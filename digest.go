@@ -0,0 +1,102 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// Digests keeps an order-independent digest of the writes seen for each arc
+// of a Consistent's circle.  Replicas of the same arcs that observed the same
+// writes have equal digests whatever the order, so comparing them finds the
+// ranges a repair has to look at without comparing any data.
+//
+// Arcs are identified by the point that ends them.  Digests are only
+// comparable between replicas that saw the same topology; call Reset after the
+// circle changes and repair everything once.
+type Digests struct {
+	c    *Consistent
+	mu   sync.Mutex
+	arcs map[uint32]uint64
+}
+
+// NewDigests creates empty Digests over the circle of c.
+func NewDigests(c *Consistent) *Digests {
+	return &Digests{c: c, arcs: make(map[uint32]uint64)}
+}
+
+// Observe folds a write of p for key into the digest of key's arc.  The fold
+// is a sum, so a write observed twice is not the same as one never observed.
+func (d *Digests) Observe(key string, p []byte) {
+	c := d.c
+	c.rlock()
+	if len(c.sortedHashes) == 0 {
+		c.runlock()
+		return
+	}
+	arc := c.sortedHashes[c.search(c.keyHash(key))]
+	c.runlock()
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write(p)
+	d.mu.Lock()
+	d.arcs[arc] += h.Sum64()
+	d.mu.Unlock()
+}
+
+// Snapshot returns the digest of every arc written to, keyed by the point
+// ending the arc.
+func (d *Digests) Snapshot() map[uint32]uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := make(map[uint32]uint64, len(d.arcs))
+	for k, v := range d.arcs {
+		s[k] = v
+	}
+	return s
+}
+
+// Diverging returns the ranges whose digest differs from other, a Snapshot
+// taken on another replica, ordered by End.
+func (d *Digests) Diverging(other map[uint32]uint64) []HashRange {
+	mine := d.Snapshot()
+	var arcs []uint32
+	for k, v := range mine {
+		if other[k] != v {
+			arcs = append(arcs, k)
+		}
+	}
+	for k, v := range other {
+		if _, ok := mine[k]; !ok && v != 0 {
+			arcs = append(arcs, k)
+		}
+	}
+	c := d.c
+	c.rlock()
+	defer c.runlock()
+	ranges := make([]HashRange, 0, len(arcs))
+	for _, h := range arcs {
+		i := sort.Search(len(c.sortedHashes), func(x int) bool { return c.sortedHashes[x] >= h })
+		if i == len(c.sortedHashes) || c.sortedHashes[i] != h {
+			// the arc is gone; report the widest range it can have covered
+			ranges = append(ranges, HashRange{Start: h + 1, End: h})
+			continue
+		}
+		prev := c.sortedHashes[(i+len(c.sortedHashes)-1)%len(c.sortedHashes)]
+		ranges = append(ranges, HashRange{Start: prev, End: h - 1})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].End < ranges[j].End })
+	return ranges
+}
+
+// Reset forgets all digests.
+func (d *Digests) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.arcs = make(map[uint32]uint64)
+}
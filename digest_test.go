@@ -0,0 +1,45 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "testing"
+
+func TestDigests(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	x.Add(newMember("hijklmn"))
+	a, b := NewDigests(x), NewDigests(x)
+	a.Observe("ggg", []byte("cpu value=1"))
+	a.Observe("hhh", []byte("cpu value=2"))
+	b.Observe("hhh", []byte("cpu value=2"))
+	b.Observe("ggg", []byte("cpu value=1"))
+	if r := a.Diverging(b.Snapshot()); len(r) != 0 {
+		t.Errorf("got diverging ranges %v for identical writes", r)
+	}
+	a.Observe("iiiii", []byte("cpu value=3"))
+	r := a.Diverging(b.Snapshot())
+	if len(r) != 1 {
+		t.Fatalf("got %v, expected one diverging range", r)
+	}
+	h := x.hashKey("iiiii")
+	if r[0].Start <= r[0].End && (h < r[0].Start || h > r[0].End) {
+		t.Errorf("range %+v does not contain the key's hash %d", r[0], h)
+	}
+	if r := b.Diverging(a.Snapshot()); len(r) != 1 {
+		t.Errorf("got %v from the other side, expected one diverging range", r)
+	}
+	a.Reset()
+	b.Reset()
+	if r := a.Diverging(b.Snapshot()); len(r) != 0 {
+		t.Errorf("got %v after reset", r)
+	}
+
+	// a write seen twice must not cancel out
+	a.Observe("ggg", []byte("cpu value=1"))
+	a.Observe("ggg", []byte("cpu value=1"))
+	if r := a.Diverging(b.Snapshot()); len(r) != 1 {
+		t.Errorf("got %v for a repeated write, expected one diverging range", r)
+	}
+}
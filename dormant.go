@@ -0,0 +1,84 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"slices"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// release drops the circle and sorted points of the hash, and the points of
+// every member that can derive them from its name again, keeping only the
+// member list and what cannot be recomputed.  The next lock or rlock
+// materializes them again, exactly as they were.  It reports whether anything
+// was released.
+func (c *Consistent) release() bool {
+	if c.unlocked {
+		return false
+	}
+	c.lock()
+	defer c.unlock()
+	if c.closed || c.pending != nil || len(c.ramps) > 0 || len(c.circle) == 0 {
+		return false
+	}
+	// points whose owner is not the member they were derived for, as when
+	// AddTokens overlaps a member's points
+	var conflicts map[uint32]lineProtocol.WriteCloser
+	for e, hashes := range c.vnodes {
+		for _, h := range hashes {
+			if o := c.circle[h]; o != e {
+				if conflicts == nil {
+					conflicts = make(map[uint32]lineProtocol.WriteCloser)
+				}
+				conflicts[h] = o
+			}
+		}
+	}
+	for e, hashes := range c.vnodes {
+		if !c.explicit[e] && slices.Equal(hashes, c.derivedHashes(e)) {
+			delete(c.vnodes, e)
+		}
+	}
+	c.conflicts = conflicts
+	c.circle = nil
+	c.sortedHashes = nil
+	c.runEnd = nil
+	c.dormant.Store(true)
+	return true
+}
+
+// materialize rebuilds what release dropped.  The epoch does not change:
+// the circle is the same as before.
+// need c.lock() before calling
+func (c *Consistent) materialize() {
+	if !c.dormant.Load() {
+		return
+	}
+	circle := make(map[uint32]lineProtocol.WriteCloser, len(c.members)*c.NumberOfReplicas)
+	for e := range c.members {
+		hashes, ok := c.vnodes[e]
+		if !ok {
+			hashes = c.derivedHashes(e)
+			c.vnodes[e] = hashes
+		}
+		for _, h := range hashes {
+			circle[h] = e
+		}
+	}
+	for h, e := range c.conflicts {
+		circle[h] = e
+	}
+	sorted := make(uints, 0, len(circle))
+	for h := range circle {
+		sorted = append(sorted, h)
+	}
+	slices.Sort(sorted)
+	c.circle = circle
+	c.sortedHashes = sorted
+	c.linkRuns()
+	c.conflicts = nil
+	c.dormant.Store(false)
+}
@@ -0,0 +1,147 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"hash/crc32"
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DxHash is a consistent hash using the DxHash algorithm (Dong and Wang,
+// "DxHash: A Scalable Consistent Hash Based on the Pseudo-Random Sequence",
+// 2021).  Members occupy buckets in a power-of-two array; a key probes the
+// array along a pseudo-random sequence seeded by its hash and lands on the
+// first occupied bucket.  Memory is one slot per bucket.
+//
+// Adding a member fills the most recently freed bucket.  When every bucket is
+// taken the array doubles, which moves about half of the keys.
+type DxHash struct {
+	buckets []lineProtocol.WriteCloser
+	free    []uint32 // stack of empty buckets
+	members map[lineProtocol.WriteCloser]uint32
+	sync.RWMutex
+}
+
+// NewDxHash creates an empty DxHash with room for size members before it has
+// to grow.  size is rounded up to a power of two.
+func NewDxHash(size int) *DxHash {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	x := &DxHash{members: make(map[lineProtocol.WriteCloser]uint32)}
+	x.grow(n)
+	return x
+}
+
+// need x.Lock() before calling
+func (x *DxHash) grow(n int) {
+	old := len(x.buckets)
+	x.buckets = append(x.buckets, make([]lineProtocol.WriteCloser, n-old)...)
+	for b := n - 1; b >= old; b-- {
+		x.free = append(x.free, uint32(b))
+	}
+}
+
+// Add inserts element in the hash.
+func (x *DxHash) Add(element lineProtocol.WriteCloser) error {
+	x.Lock()
+	defer x.Unlock()
+	if _, ok := x.members[element]; ok {
+		return ErrMemberExists
+	}
+	if len(x.free) == 0 {
+		x.grow(2 * len(x.buckets))
+	}
+	b := x.free[len(x.free)-1]
+	x.free = x.free[:len(x.free)-1]
+	x.buckets[b] = element
+	x.members[element] = b
+	return nil
+}
+
+// Remove removes an element from the hash.
+func (x *DxHash) Remove(element lineProtocol.WriteCloser) {
+	x.Lock()
+	defer x.Unlock()
+	b, ok := x.members[element]
+	if !ok {
+		return
+	}
+	x.buckets[b] = nil
+	x.free = append(x.free, b)
+	delete(x.members, element)
+}
+
+// Members returns the elements in the hash.
+func (x *DxHash) Members() []lineProtocol.WriteCloser {
+	x.RLock()
+	defer x.RUnlock()
+	var m []lineProtocol.WriteCloser
+	for k := range x.members {
+		m = append(m, k)
+	}
+	return m
+}
+
+// Get returns the element name hashes to.
+func (x *DxHash) Get(name string) (lineProtocol.WriteCloser, error) {
+	x.RLock()
+	defer x.RUnlock()
+	if len(x.members) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	var res lineProtocol.WriteCloser
+	x.probe(crc32.ChecksumIEEE([]byte(name)), func(e lineProtocol.WriteCloser) bool {
+		res = e
+		return false
+	})
+	return res, nil
+}
+
+// GetN returns the first n distinct elements on name's probe sequence.
+func (x *DxHash) GetN(name string, n int) ([]lineProtocol.WriteCloser, error) {
+	x.RLock()
+	defer x.RUnlock()
+	if len(x.members) == 0 {
+		return nil, ErrEmptyCircle
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	if n > len(x.members) {
+		n = len(x.members)
+	}
+	res := make([]lineProtocol.WriteCloser, 0, n)
+	x.probe(crc32.ChecksumIEEE([]byte(name)), func(e lineProtocol.WriteCloser) bool {
+		if !sliceContainsMember(res, e) {
+			res = append(res, e)
+		}
+		return len(res) < n
+	})
+	return res, nil
+}
+
+// probe calls fn with each occupied bucket along key's probe sequence until fn
+// returns false.  After 4*len(buckets) pseudo-random probes it finishes with a
+// linear scan so a sparse array still terminates.
+// need x.RLock() before calling
+func (x *DxHash) probe(key uint32, fn func(lineProtocol.WriteCloser) bool) {
+	mask := uint32(len(x.buckets) - 1)
+	var b uint32
+	for i := uint32(0); i < uint32(4*len(x.buckets)); i++ {
+		b = mix32(key+i*0x9e3779b9) & mask
+		if e := x.buckets[b]; e != nil && !fn(e) {
+			return
+		}
+	}
+	for i := range x.buckets {
+		if e := x.buckets[(b+uint32(i))&mask]; e != nil && !fn(e) {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestDxHashMinimalDisruption(t *testing.T) {
+	x := NewDxHash(8)
+	var ms []*member
+	for i := 0; i < 8; i++ {
+		m := newMember("node" + strconv.Itoa(i))
+		ms = append(ms, m)
+		x.Add(m)
+	}
+	before := make(map[string]lineProtocol.WriteCloser)
+	for i := 0; i < 10000; i++ {
+		k := strconv.Itoa(i)
+		before[k], _ = x.Get(k)
+	}
+	x.Remove(ms[5])
+	for k, was := range before {
+		got, _ := x.Get(k)
+		if was != ms[5] && got != was || got == ms[5] {
+			t.Fatalf("%s moved from %v to %v", k, was, got)
+		}
+	}
+	x.Add(ms[5])
+	for k, was := range before {
+		if got, _ := x.Get(k); got != was {
+			t.Fatalf("%s: got %v after re-add, expected %v", k, got, was)
+		}
+	}
+}
+
+func TestDxHashGrowAndGetN(t *testing.T) {
+	x := NewDxHash(2)
+	for i := 0; i < 5; i++ {
+		x.Add(newMember("node" + strconv.Itoa(i)))
+	}
+	checkNum(len(x.buckets), 8, t)
+	res, err := x.GetN("key", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(res), 3, t)
+	if first, _ := x.Get("key"); res[0] != first {
+		t.Errorf("got %v first, expected %v", res[0], first)
+	}
+}
+
+func TestRouterGetNSmall(t *testing.T) {
+	for _, alg := range []Algorithm{Ring, AnchorHash, DxHashAlgorithm} {
+		r := NewRouter(alg, 8)
+		r.Add(newMember("abcdefg"))
+		r.Add(newMember("hijklmn"))
+		for _, tt := range []struct {
+			n    int
+			want int
+		}{{-1, 0}, {0, 0}, {1, 1}} {
+			res, err := r.GetN("key", tt.n)
+			if err != nil || len(res) != tt.want || tt.want == 0 && res != nil {
+				t.Errorf("algorithm %d: GetN(%d) = %v, %v, expected %d members", alg, tt.n, res, err, tt.want)
+			}
+		}
+	}
+}
+
+func benchmarkRouterGet(b *testing.B, alg Algorithm) {
+	r := NewRouter(alg, 1000)
+	for i := 0; i < 1000; i++ {
+		r.Add(newMember("node" + strconv.Itoa(i)))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Get("nothing")
+	}
+}
+
+func BenchmarkRouterGetRing(b *testing.B)   { benchmarkRouterGet(b, Ring) }
+func BenchmarkRouterGetAnchor(b *testing.B) { benchmarkRouterGet(b, AnchorHash) }
+func BenchmarkRouterGetDxHash(b *testing.B) { benchmarkRouterGet(b, DxHashAlgorithm) }
@@ -0,0 +1,83 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// UpdateEndpoint replaces the member named or aliased name with element,
+// for a backend that moved to a new address.  element takes over the points,
+// health and load state, weight, overrides and aliases of the old member, so
+// no key moves.  The old writer is neither written to nor closed afterwards.
+// If element's ID differs from the old member's, its points are kept as
+// explicit tokens since they can no longer be derived from it.  element's
+// Generation is the one after the old member's.
+func (c *Consistent) UpdateEndpoint(name string, element lineProtocol.WriteCloser) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("update", name, element, c.refusal())
+	}
+	old, ok := c.byName(name)
+	if !ok {
+		return c.opError("update", name, element, ErrUnknownMember)
+	}
+	if old == element {
+		return nil
+	}
+	if c.members[element] {
+		return c.opError("update", name, element, ErrMemberExists)
+	}
+	for _, h := range c.vnodes[old] {
+		if c.circle[h] == old {
+			c.circle[h] = element
+		}
+	}
+	c.members[element] = true
+	delete(c.members, old)
+	c.vnodes[element] = c.vnodes[old]
+	delete(c.vnodes, old)
+	if c.explicit[old] || MemberID(old) != MemberID(element) {
+		c.explicit[element] = true
+	}
+	delete(c.explicit, old)
+	c.state[element] = c.state[old]
+	delete(c.state, old)
+	if r, ok := c.ramps[old]; ok {
+		r.element = element
+		c.ramps[element] = r
+		delete(c.ramps, old)
+	}
+	if v, ok := c.capacities[old]; ok {
+		c.capacities[element] = v
+		delete(c.capacities, old)
+	}
+	if v, ok := c.weights[old]; ok {
+		c.weights[element] = v
+		delete(c.weights, old)
+	}
+	if v, ok := c.replicas[old]; ok {
+		c.replicas[element] = v
+		delete(c.replicas, old)
+	}
+	for a, e := range c.aliases {
+		if e == old {
+			c.aliases[a] = element
+		}
+	}
+	for a, e := range c.owned {
+		if e == old {
+			c.owned[a] = element
+		}
+	}
+	for i := range c.overrides {
+		if c.overrides[i].Member == old {
+			c.overrides[i].Member = element
+		}
+	}
+	g := c.nextGeneration(MemberID(element), c.generation(old))
+	c.bus.emit(MemberReplaced{Old: old, New: element, Generation: g})
+	c.advance()
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUpdateEndpoint(t *testing.T) {
+	x := New()
+	old := newMember("10.0.0.2:8086")
+	x.Add(newMember("10.0.0.1:8086"))
+	x.Add(old)
+	x.Add(newMember("10.0.0.3:8086"))
+	x.SetWeight(old, 2)
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("key%d", i)
+		e, _ := x.Get(k)
+		before[k] = e.Name()
+	}
+
+	moved := newMember("10.0.9.9:8086")
+	if err := x.UpdateEndpoint("10.0.0.2:8086", moved); err != nil {
+		t.Fatal(err)
+	}
+	for k, name := range before {
+		e, _ := x.Get(k)
+		if name == old.name {
+			name = moved.name
+		}
+		if e.Name() != name {
+			t.Fatalf("%s moved to %s, expected %s", k, e.Name(), name)
+		}
+	}
+	if x.Healthy(old) || !x.Healthy(moved) {
+		t.Error("expected the new writer to replace the old one")
+	}
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+	if err := x.UpdateEndpoint("10.0.0.2:8086", newMember("x")); !errors.Is(err, ErrUnknownMember) {
+		t.Errorf("got %v, expected ErrUnknownMember", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrMemberDown is the error returned when the member a key routes to is not
+// able to take traffic.
+var ErrMemberDown = errors.New("member down")
+
+// ErrWriteTimeout is the error returned when a write to a member does not
+// finish in time, such as past the deadline of its connection.  The error
+// from the member is wrapped as well.
+var ErrWriteTimeout = errors.New("write timeout")
+
+// ErrClosed is the error returned by operations on a closed hash.
+var ErrClosed = errors.New("closed")
+
+// Error records an operation that failed on a Consistent, with the key and
+// member involved and the epoch of the circle at the time.  Use errors.Is on
+// it to test for causes such as ErrEmptyCircle or ErrMemberDown.
+type Error struct {
+	Op     string // operation, e.g. "get"
+	Key    string // key being routed, if any
+	Member string // name of the member involved, if any
+	Epoch  uint64 // see Consistent.Epoch
+	Err    error
+}
+
+func (e *Error) Error() string {
+	s := "consistent: " + e.Op
+	if e.Key != "" {
+		s += " key " + strconv.Quote(e.Key)
+	}
+	if e.Member != "" {
+		s += " member " + strconv.Quote(e.Member)
+	}
+	return s + " (epoch " + strconv.FormatUint(e.Epoch, 10) + "): " + e.Err.Error()
+}
+
+// Unwrap returns the underlying cause.
+func (e *Error) Unwrap() error { return e.Err }
+
+// opError wraps err with the context of an operation.
+// need c.rlock() before calling
+func (c *Consistent) opError(op, key string, member lineProtocol.WriteCloser, err error) error {
+	e := &Error{Op: op, Key: key, Epoch: c.epoch, Err: err}
+	if member != nil {
+		e.Member = member.Name()
+	}
+	return e
+}
+
+// Epoch returns a counter that advances every time the circle changes.
+func (c *Consistent) Epoch() uint64 {
+	c.rlock()
+	defer c.runlock()
+	return c.epoch
+}
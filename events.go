@@ -0,0 +1,173 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Event is something that happened to a hash, delivered to the
+// Subscriptions made with Events.  It is one of MemberAdded, MemberRemoved,
+// MemberEvicted, MemberRecovered, MemberReplaced, RingRebuilt, RingSwapped,
+// WriteFailed and HintStored.  Member events carry the Generation of the
+// member after the event.
+type Event interface {
+	event()
+}
+
+// MemberAdded is sent when a member joins the hash.
+type MemberAdded struct {
+	Member     lineProtocol.WriteCloser
+	Generation uint64
+}
+
+// MemberRemoved is sent when a member leaves the hash.
+type MemberRemoved struct {
+	Member     lineProtocol.WriteCloser
+	Generation uint64
+}
+
+// MemberEvicted is sent when a member is marked down.
+type MemberEvicted struct {
+	Member     lineProtocol.WriteCloser
+	Generation uint64
+}
+
+// MemberRecovered is sent when a member marked down is marked up again.
+type MemberRecovered struct {
+	Member     lineProtocol.WriteCloser
+	Generation uint64
+}
+
+// MemberReplaced is sent when UpdateEndpoint replaces a member.
+type MemberReplaced struct {
+	Old, New   lineProtocol.WriteCloser
+	Generation uint64 // of New
+}
+
+// RingRebuilt is sent when the points of the circle change.
+type RingRebuilt struct {
+	Epoch  uint64        // epoch of the new circle
+	Points int           // points on the new circle
+	Took   time.Duration // time spent building it
+}
+
+// RingSwapped is sent, instead of member and RingRebuilt events, when Swap
+// replaces the topology.
+type RingSwapped struct {
+	Epoch uint64 // epoch of the new circle
+	Diff  Diff
+}
+
+// WriteFailed is sent when a write the hash makes to a member fails.
+type WriteFailed struct {
+	Member lineProtocol.WriteCloser
+	Err    error
+}
+
+// HintStored is sent when a write for a member that is down is kept in the
+// HintStore.
+type HintStored struct {
+	Member lineProtocol.WriteCloser
+	Key    string
+	Bytes  int
+	Token  string // idempotency token the hint is replayed with
+}
+
+func (MemberAdded) event()     {}
+func (MemberRemoved) event()   {}
+func (MemberEvicted) event()   {}
+func (MemberRecovered) event() {}
+func (MemberReplaced) event()  {}
+func (RingRebuilt) event()     {}
+func (RingSwapped) event()     {}
+func (WriteFailed) event()     {}
+func (HintStored) event()      {}
+
+// Subscription receives the events of a hash on C until it is closed.
+// Events are sent without blocking the hash: if C is full, the event is
+// dropped and counted in Dropped.
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	b       *bus
+	dropped atomic.Int64
+}
+
+// Dropped returns the number of events dropped because C was full.
+func (s *Subscription) Dropped() int64 { return s.dropped.Load() }
+
+// Close stops the subscription and closes C.  Calling Close again does
+// nothing.
+func (s *Subscription) Close() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	if _, ok := s.b.subs[s]; !ok {
+		return
+	}
+	delete(s.b.subs, s)
+	s.b.n.Add(-1)
+	close(s.ch)
+}
+
+// bus fans events out to Subscriptions.  Events are emitted with or without
+// the ring lock held, so sending never blocks.
+type bus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	n      atomic.Int64 // len(subs), read without mu
+	closed bool         // set by closeAll, guarded by mu
+}
+
+// Events subscribes to the events of the hash with a channel holding up to
+// buffer events.  Metrics, logging and audit integrations consume these
+// instead of hooking into the hash.  C is closed when the hash is.
+func (c *Consistent) Events(buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, ch: ch, b: &c.bus}
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	if c.bus.closed {
+		close(ch)
+		return s
+	}
+	if c.bus.subs == nil {
+		c.bus.subs = make(map[*Subscription]struct{})
+	}
+	c.bus.subs[s] = struct{}{}
+	c.bus.n.Add(1)
+	return s
+}
+
+func (b *bus) emit(ev Event) {
+	if b.n.Load() == 0 {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// closeAll closes every subscription and any opened later.
+func (b *bus) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		close(s.ch)
+	}
+	b.subs = nil
+	b.n.Store(0)
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	a, b := newMember("a"), newMember("b")
+	x := New()
+	sub := x.Events(16)
+	x.Add(a)
+	x.Add(b)
+	x.Remove(b)
+	a.err = errors.New("broken")
+	x.Write("k", []byte("x"))
+
+	want := []string{"added a", "rebuilt", "added b", "rebuilt", "removed b", "rebuilt", "failed a"}
+	for _, w := range want {
+		var got string
+		switch ev := (<-sub.C).(type) {
+		case MemberAdded:
+			got = "added " + ev.Member.Name()
+		case MemberRemoved:
+			got = "removed " + ev.Member.Name()
+		case RingRebuilt:
+			got = "rebuilt"
+		case WriteFailed:
+			got = "failed " + ev.Member.Name()
+		default:
+			got = "unexpected"
+		}
+		if got != w {
+			t.Fatalf("got event %q, want %q", got, w)
+		}
+	}
+
+	x.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("expected Close to close the subscription")
+	}
+	if _, ok := <-x.Events(1).C; ok {
+		t.Error("expected a subscription after Close to be closed")
+	}
+}
+
+func TestEventsDropped(t *testing.T) {
+	x := New()
+	sub := x.Events(1)
+	x.Add(newMember("a"))
+	if sub.Dropped() != 1 {
+		t.Errorf("got %d dropped, want 1", sub.Dropped())
+	}
+	sub.Close()
+	sub.Close()
+	x.Add(newMember("b"))
+	if _, ok := <-sub.C; !ok {
+		t.Fatal("expected the buffered event before the close")
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("expected C to be closed")
+	}
+}
@@ -7,14 +7,22 @@ package consistent_test
 import (
 	"fmt"
 	"log"
-	"stathat.com/c/consistent"
+
+	"github.com/lvqian/consistent"
 )
 
+// cache stands in for a connection to a backend called by its name.
+type cache string
+
+func (c cache) Name() string                { return string(c) }
+func (c cache) Write(p []byte) (int, error) { return len(p), nil }
+func (c cache) Close() error                { return nil }
+
 func ExampleNew() {
 	c := consistent.New()
-	c.Add("cacheA")
-	c.Add("cacheB")
-	c.Add("cacheC")
+	c.Add(cache("cacheA"))
+	c.Add(cache("cacheB"))
+	c.Add(cache("cacheC"))
 	users := []string{"user_mcnulty", "user_bunk", "user_omar", "user_bunny", "user_stringer"}
 	for _, u := range users {
 		server, err := c.Get(u)
@@ -31,11 +39,11 @@ func ExampleNew() {
 	// user_stringer => cacheC
 }
 
-func ExampleAdd() {
+func ExampleConsistent_Add() {
 	c := consistent.New()
-	c.Add("cacheA")
-	c.Add("cacheB")
-	c.Add("cacheC")
+	c.Add(cache("cacheA"))
+	c.Add(cache("cacheB"))
+	c.Add(cache("cacheC"))
 	users := []string{"user_mcnulty", "user_bunk", "user_omar", "user_bunny", "user_stringer"}
 	fmt.Println("initial state [A, B, C]")
 	for _, u := range users {
@@ -45,8 +53,8 @@ func ExampleAdd() {
 		}
 		fmt.Printf("%s => %s\n", u, server)
 	}
-	c.Add("cacheD")
-	c.Add("cacheE")
+	c.Add(cache("cacheD"))
+	c.Add(cache("cacheE"))
 	fmt.Println("\nwith cacheD, cacheE [A, B, C, D, E]")
 	for _, u := range users {
 		server, err := c.Get(u)
@@ -71,11 +79,11 @@ func ExampleAdd() {
 	// user_stringer => cacheE
 }
 
-func ExampleRemove() {
+func ExampleConsistent_Remove() {
 	c := consistent.New()
-	c.Add("cacheA")
-	c.Add("cacheB")
-	c.Add("cacheC")
+	c.Add(cache("cacheA"))
+	c.Add(cache("cacheB"))
+	c.Add(cache("cacheC"))
 	users := []string{"user_mcnulty", "user_bunk", "user_omar", "user_bunny", "user_stringer"}
 	fmt.Println("initial state [A, B, C]")
 	for _, u := range users {
@@ -85,7 +93,7 @@ func ExampleRemove() {
 		}
 		fmt.Printf("%s => %s\n", u, server)
 	}
-	c.Remove("cacheC")
+	c.Remove(cache("cacheC"))
 	fmt.Println("\ncacheC removed [A, B]")
 	for _, u := range users {
 		server, err := c.Get(u)
@@ -0,0 +1,51 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// Exclude stops element from being chosen as the primary owner of any key
+// while keeping its points, so it stays in GetN and PreferenceList replica
+// sets, right after the member standing in as primary.  Use it to let a
+// member being decommissioned keep serving the data it holds.  Include
+// reverts it.
+func (c *Consistent) Exclude(element lineProtocol.WriteCloser) error {
+	return c.setExcluded("exclude", element, true)
+}
+
+// Include makes an excluded element eligible as a primary owner again.
+func (c *Consistent) Include(element lineProtocol.WriteCloser) error {
+	return c.setExcluded("include", element, false)
+}
+
+func (c *Consistent) setExcluded(op string, element lineProtocol.WriteCloser, excluded bool) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError(op, "", element, c.refusal())
+	}
+	st, ok := c.state[element]
+	if !ok {
+		return c.opError(op, "", element, ErrUnknownMember)
+	}
+	if st.excluded != excluded {
+		st.excluded = excluded
+		c.advance()
+	}
+	return nil
+}
+
+// Excluded reports whether element is excluded from primary placement.
+func (c *Consistent) Excluded(element lineProtocol.WriteCloser) bool {
+	c.rlock()
+	defer c.runlock()
+	return c.isExcluded(element)
+}
+
+// need c.rlock() before calling
+func (c *Consistent) isExcluded(element lineProtocol.WriteCloser) bool {
+	st, ok := c.state[element]
+	return ok && st.excluded
+}
@@ -0,0 +1,51 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestExclude(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	x.Add(c)
+	if err := x.Exclude(a); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		e, err := x.Get(key)
+		if err != nil || e == a {
+			t.Fatalf("got %v, %v for %q, expected a never to be primary", e, err, key)
+		}
+		all, _ := x.GetN(key, 3)
+		if len(all) != 3 || all[0] != e {
+			t.Fatalf("got %v for %q, expected 3 replicas led by %v", all, key, e)
+		}
+		if two, _ := x.GetN(key, 2); two[0] != e || (two[1] != a && sliceContainsMember(two, a)) {
+			t.Fatalf("got %v for %q, expected %v first", two, key, e)
+		}
+		if p, _, _ := x.GetTwo(key); p != e {
+			t.Fatalf("got GetTwo primary %v for %q, expected %v", p, key, e)
+		}
+	}
+	if !x.Excluded(a) {
+		t.Error("expected a to be excluded")
+	}
+
+	y := New()
+	y.Restore(x.Snapshot(), lookupIn(a, b, c))
+	if !y.Excluded(a) {
+		t.Error("expected the exclusion to survive a snapshot")
+	}
+	x.Include(a)
+	if x.Excluded(a) {
+		t.Error("expected a to be included again")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// Explanation traces how a key is routed.
+type Explanation struct {
+	Key        string
+	Hash       uint32                     // hash of Key
+	Vnode      uint32                     // first point on the circle after Hash
+	VnodeOwner lineProtocol.WriteCloser   // member owning Vnode
+	Member     lineProtocol.WriteCloser   // member Get returns for Key
+	Override   bool                       // Member comes from an AssignRange override
+	Overflow   bool                       // VnodeOwner was at capacity or down
+	Tier       int                        // 0 if this hash chose Member, 1 for its fallback and so on
+	Rule       string                     // name of the rule that routed Key, if one did
+	Replicas   []lineProtocol.WriteCloser // what GetN returns for Key
+}
+
+// Explain returns the routing decision for key and its next n replicas,
+// without routing anything.  Use it to answer why a key went where it did.
+func (c *Consistent) Explain(key string, n int) (Explanation, error) {
+	c.rlock()
+	defer c.runlock()
+	x := Explanation{Key: key}
+	if c.closed {
+		return x, c.opError("explain", key, nil, ErrClosed)
+	}
+	if len(c.rules) > 0 {
+		if r, ok := c.rule(key); ok {
+			switch r.Action {
+			case Redirect:
+				x.Rule, x.Member = r.Name, r.Member
+				if n > 0 {
+					x.Replicas = []lineProtocol.WriteCloser{r.Member}
+				}
+				return x, nil
+			case Delegate:
+				d, err := r.Ring.Explain(key, n)
+				d.Rule = r.Name
+				return d, err
+			default:
+				x.Rule = r.Name
+				return x, c.opError("explain", key, nil, ErrDropped)
+			}
+		}
+	}
+	if len(c.circle) == 0 {
+		if c.fallback != nil {
+			return c.explainFallback(x, n)
+		}
+		return x, c.opError("explain", key, nil, ErrEmptyCircle)
+	}
+	x.Hash = c.keyHash(key)
+	x.Vnode = c.sortedHashes[c.search(x.Hash)]
+	x.VnodeOwner = c.circle[x.Vnode]
+	_, x.Override = c.override(x.Hash)
+	var err error
+	x.Member, x.Overflow, err = c.get(x.Hash)
+	if n > 0 {
+		x.Replicas = c.getN(x.Hash, n)
+	}
+	if err != nil {
+		if c.fallback != nil {
+			return c.explainFallback(x, n)
+		}
+		return x, c.opError("explain", key, nil, err)
+	}
+	return x, nil
+}
+
+// explainFallback completes x with the decision of the fallback ring.
+// need c.rlock() before calling
+func (c *Consistent) explainFallback(x Explanation, n int) (Explanation, error) {
+	f, err := c.fallback.Explain(x.Key, n)
+	x.Member, x.Tier = f.Member, f.Tier+1
+	return x, err
+}
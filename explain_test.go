@@ -0,0 +1,120 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	x := New()
+	if _, err := x.Explain("ggg", 2); !errors.Is(err, ErrEmptyCircle) {
+		t.Errorf("expected empty circle error, got %v", err)
+	}
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x.Add(a)
+	x.Add(b)
+	x.Add(c)
+	e, err := x.Explain("ggg", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := x.Get("ggg")
+	replicas, _ := x.GetN("ggg", 2)
+	if e.Hash != x.hashKey("ggg") || e.Member != want || e.VnodeOwner != want || e.Override || e.Overflow {
+		t.Errorf("got %+v, expected plain routing to %v", e, want)
+	}
+	if x.circle[e.Vnode] != want || e.Vnode < e.Hash && e.Vnode != x.sortedHashes[0] {
+		t.Errorf("vnode %d does not follow hash %d", e.Vnode, e.Hash)
+	}
+	if len(e.Replicas) != 2 || e.Replicas[0] != replicas[0] || e.Replicas[1] != replicas[1] {
+		t.Errorf("got replicas %v, expected %v", e.Replicas, replicas)
+	}
+
+	x.SetCapacity(want, 1)
+	x.AddLoad(want, 1)
+	if e, _ = x.Explain("ggg", 0); !e.Overflow || e.Member == want {
+		t.Errorf("got %+v, expected overflow", e)
+	}
+	if s := x.Stats(); s.Overflows != 0 {
+		t.Errorf("got %d overflows, expected Explain not to count", s.Overflows)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	x := New()
+	if _, err := x.Locate("ggg"); !errors.Is(err, ErrEmptyCircle) {
+		t.Errorf("expected empty circle error, got %v", err)
+	}
+	x.Add(newMember("abcdefg"))
+	x.Add(newMember("hijklmn"))
+	l, err := x.Locate("ggg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := x.Get("ggg")
+	if l.Member != want || l.Hash != x.hashKey("ggg") || x.circle[l.Vnode] != want {
+		t.Errorf("got %+v, expected %v at its vnode", l, want)
+	}
+}
+
+func TestExplainRules(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	archive := New()
+	archive.Add(c)
+	x.SetRules([]Rule{
+		{Name: "pin", Match: Exact("pinned"), Action: Redirect, Member: b},
+		{Name: "archive", Match: Prefix("old"), Action: Delegate, Ring: archive},
+		{Name: "scratch", Match: Prefix("tmp"), Action: Drop},
+	})
+	for _, key := range []string{"pinned", "old1", "other"} {
+		want, _ := x.Get(key)
+		e, err := x.Explain(key, 1)
+		if err != nil || e.Member != want {
+			t.Errorf("%s: explained %v, %v, expected %v", key, e.Member, err, want)
+		}
+		if l, err := x.Locate(key); err != nil || l.Member != want {
+			t.Errorf("%s: located %v, %v, expected %v", key, l.Member, err, want)
+		}
+	}
+	if e, _ := x.Explain("pinned", 0); e.Rule != "pin" {
+		t.Errorf("got rule %q, expected pin", e.Rule)
+	}
+	if _, err := x.Explain("tmp1", 0); !errors.Is(err, ErrDropped) {
+		t.Errorf("got %v, expected ErrDropped", err)
+	}
+	if _, err := x.Locate("tmp1"); !errors.Is(err, ErrDropped) {
+		t.Errorf("got %v, expected ErrDropped from Locate", err)
+	}
+	x.Close()
+	if _, err := x.Explain("other", 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, expected ErrClosed", err)
+	}
+}
+
+func TestErrorContext(t *testing.T) {
+	x := New()
+	_, err := x.Get("ggg")
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("got %T, expected *Error", err)
+	}
+	if e.Op != "get" || e.Key != "ggg" || !errors.Is(err, ErrEmptyCircle) {
+		t.Errorf("got %+v", e)
+	}
+	a := newMember("abcdefg")
+	x.Add(a)
+	err = x.AssignRange(2, 1, a)
+	if !errors.As(err, &e) || e.Member != "abcdefg" || e.Epoch != x.Epoch() || !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("got %v", err)
+	}
+	if got := err.Error(); got != `consistent: assignrange member "abcdefg" (epoch 1): invalid hash range` {
+		t.Errorf("got message %q", got)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// WithFallback chains next behind the hash: when Get cannot find a member for
+// a key, because the circle is empty or the key's member and every stand-in
+// are down or at capacity, the key is routed through next instead.  next may
+// have a fallback of its own but must not lead back to this hash.  Keys served
+// this way are counted in Stats and Explain reports the tier that served them.
+func WithFallback(next *Consistent) Option {
+	return func(c *Consistent) {
+		c.fallback = next
+	}
+}
+
+// fallbackGet routes name through the fallback ring.
+func (c *Consistent) fallbackGet(name string) (lineProtocol.WriteCloser, error) {
+	c.fallbacks.Add(1)
+	return c.fallback.Get(name)
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "testing"
+
+func TestFallback(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	archive := New()
+	archive.Add(c)
+	tier1 := New(WithFallback(archive))
+	tier1.Add(b)
+	x := New(WithFallback(tier1))
+	x.Add(a)
+
+	if got, err := x.Get("foo"); err != nil || got != a {
+		t.Fatalf("got %v, %v, expected %v", got, err, a)
+	}
+	x.MarkDown(a)
+	if got, err := x.Get("foo"); err != nil || got != b {
+		t.Errorf("got %v, %v, expected %v from the fallback", got, err, b)
+	}
+	tier1.MarkDown(b)
+	ex, err := x.Explain("foo", 0)
+	if err != nil || ex.Member != c || ex.Tier != 2 {
+		t.Errorf("got %v at tier %d, %v, expected %v at tier 2", ex.Member, ex.Tier, err, c)
+	}
+	if got, err := x.Get("foo"); err != nil || got != c {
+		t.Errorf("got %v, %v, expected %v from the second fallback", got, err, c)
+	}
+	checkNum(int(x.Stats().Fallbacks), 2, t)
+	checkNum(int(tier1.Stats().Fallbacks), 1, t)
+}
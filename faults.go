@@ -0,0 +1,181 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrInjected is the error a FaultPlan injects when none is given.
+var ErrInjected = errors.New("injected fault")
+
+// Faults decides which failures to inject, for testing failover, hinted
+// handoff and circuit breaking deterministically.  The hash consults it, by
+// member name, before every write it makes through Write, WriteReplicas or a
+// hint replay, and before judging a member in CheckHealth.
+type Faults interface {
+	// WriteFault returns how long to stall a write to the member and the
+	// error to fail it with instead of writing, if any.
+	WriteFault(member string) (delay time.Duration, err error)
+	// HealthFault returns an error to make CheckHealth find the member
+	// unhealthy, or nil to check it as usual.
+	HealthFault(member string) error
+}
+
+// WithFaults makes the hash inject the failures f decides on.
+func WithFaults(f Faults) Option {
+	return func(c *Consistent) {
+		c.faults = f
+	}
+}
+
+// write writes p to element, subject to injected faults and compressed if
+// element has an Encoding.  It fails with ErrClosed once Close has started,
+// and with ErrWriteTimeout, wrapping the cause, if the member's write timed
+// out.
+func (c *Consistent) write(element lineProtocol.WriteCloser, p []byte) (int, error) {
+	if !c.beginWrite() {
+		return 0, ErrClosed
+	}
+	defer c.writing.Done()
+	if c.faults != nil {
+		delay, err := c.faults.WriteFault(element.Name())
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	b, err := c.encode(element, p)
+	if err != nil {
+		return 0, err
+	}
+	n, err := element.Write(b)
+	if err == nil {
+		n = len(p)
+	} else if isTimeout(err) {
+		err = fmt.Errorf("%w: %w", ErrWriteTimeout, err)
+	}
+	return n, err
+}
+
+// isTimeout reports whether err is a deadline or timeout error from a
+// member's connection.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &ne) && ne.Timeout()
+}
+
+// FaultPlan is a Faults scripted per member.  The zero value injects nothing.
+type FaultPlan struct {
+	mu     sync.Mutex
+	faults map[string]*plannedFault
+}
+
+type plannedFault struct {
+	writeErr  error
+	delay     time.Duration
+	down      bool
+	flap      int // toggle down every flap health checks
+	checks    int
+	flapState bool
+}
+
+func (f *FaultPlan) fault(member string) *plannedFault {
+	if f.faults == nil {
+		f.faults = make(map[string]*plannedFault)
+	}
+	p, ok := f.faults[member]
+	if !ok {
+		p = new(plannedFault)
+		f.faults[member] = p
+	}
+	return p
+}
+
+// FailWrites makes writes to member fail with err, or ErrInjected if err is
+// nil.
+func (f *FaultPlan) FailWrites(member string, err error) {
+	if err == nil {
+		err = ErrInjected
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fault(member).writeErr = err
+}
+
+// SlowWrites stalls every write to member for d.
+func (f *FaultPlan) SlowWrites(member string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fault(member).delay = d
+}
+
+// Fail makes member fail its health checks.
+func (f *FaultPlan) Fail(member string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fault(member).down = true
+}
+
+// Flap makes member fail n health checks, pass the next n, and so on.
+func (f *FaultPlan) Flap(member string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := f.fault(member)
+	p.flap, p.checks, p.flapState = n, 0, true
+}
+
+// Heal removes every fault planned for member.
+func (f *FaultPlan) Heal(member string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.faults, member)
+}
+
+// WriteFault implements Faults.
+func (f *FaultPlan) WriteFault(member string) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.faults[member]
+	if !ok {
+		return 0, nil
+	}
+	return p.delay, p.writeErr
+}
+
+// HealthFault implements Faults.
+func (f *FaultPlan) HealthFault(member string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.faults[member]
+	if !ok {
+		return nil
+	}
+	if p.flap > 0 {
+		down := p.flapState
+		if p.checks++; p.checks >= p.flap {
+			p.checks, p.flapState = 0, !p.flapState
+		}
+		if down {
+			return ErrInjected
+		}
+		return nil
+	}
+	if p.down {
+		return ErrInjected
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFaultPlanWrites(t *testing.T) {
+	var f FaultPlan
+	a := newMember("abcdefg")
+	x := New(WithFaults(&f))
+	x.Add(a)
+	f.FailWrites("abcdefg", nil)
+	if _, err := x.Write("foo", []byte("x")); !errors.Is(err, ErrInjected) {
+		t.Errorf("got %v, expected ErrInjected", err)
+	}
+	f.Heal("abcdefg")
+	f.SlowWrites("abcdefg", 20*time.Millisecond)
+	start := time.Now()
+	if _, err := x.Write("foo", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("write took %v, expected it to be stalled", d)
+	}
+	if got := a.String(); got != "x" {
+		t.Errorf("got %q, expected only the healed write", got)
+	}
+}
+
+func TestFaultPlanHealth(t *testing.T) {
+	var f FaultPlan
+	a, b := &pingMember{member: newMember("abcdefg")}, &pingMember{member: newMember("hijklmn")}
+	x := New(WithFaults(&f), WithHintStore(NewMemoryHints(10)))
+	x.Add(a)
+	x.Add(b)
+	ctx := context.Background()
+
+	f.Fail("abcdefg")
+	x.CheckHealth(ctx)
+	if x.Healthy(a) {
+		t.Fatal("expected an injected failure to mark a down")
+	}
+	for _, k := range []string{"1", "2", "3", "4", "5", "6", "7", "8"} {
+		x.Write(k, []byte(k))
+	}
+	hinted := int(x.Stats().HintsStored)
+	if hinted == 0 {
+		t.Fatal("expected writes for a to be hinted")
+	}
+	f.Heal("abcdefg")
+	x.CheckHealth(ctx)
+	if !x.Healthy(a) {
+		t.Fatal("expected a to recover once healed")
+	}
+	checkNum(int(x.Stats().HintsReplayed), hinted, t)
+
+	f.Flap("hijklmn", 2)
+	var got []bool
+	for i := 0; i < 4; i++ {
+		x.CheckHealth(ctx)
+		got = append(got, x.Healthy(b))
+	}
+	want := []bool{false, false, true, true}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got health %v, expected %v", got, want)
+		}
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	a := newMember("a")
+	a.err = fmt.Errorf("write tcp: %w", os.ErrDeadlineExceeded)
+	x := New()
+	x.Add(a)
+	_, err := x.Write("k", []byte("x"))
+	if !errors.Is(err, ErrWriteTimeout) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("timed out write: %v", err)
+	}
+	a.err = errors.New("broken pipe")
+	if _, err := x.Write("k", []byte("x")); errors.Is(err, ErrWriteTimeout) {
+		t.Errorf("failed write reported as a timeout: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// DefaultFeedHistory is the number of past states a Feed keeps for clients
+// resuming a stream when NewFeed is given history <= 0.
+const DefaultFeedHistory = 64
+
+// RingUpdate is one step of a Feed stream.  Clients start from Snapshot and
+// apply each following update with Apply.  proto/ring.proto defines the same
+// message for serving a Feed over gRPC.
+type RingUpdate struct {
+	Epoch    uint64    `json:"epoch"`
+	Snapshot *Snapshot `json:"snapshot,omitempty"` // full state, replacing the client's
+	Added    []string  `json:"added,omitempty"`    // member IDs joined since the previous update
+	Removed  []string  `json:"removed,omitempty"`  // member IDs gone since the previous update
+}
+
+// Apply returns s updated by u.
+func (u RingUpdate) Apply(s Snapshot) Snapshot {
+	if u.Snapshot != nil {
+		return *u.Snapshot
+	}
+	gone := make(map[string]bool, len(u.Removed))
+	for _, m := range u.Removed {
+		gone[m] = true
+	}
+	members := make([]string, 0, len(s.Members)+len(u.Added))
+	for _, m := range s.Members {
+		if !gone[m] {
+			members = append(members, m)
+		}
+	}
+	members = append(members, u.Added...)
+	sort.Strings(members)
+	s.Members = members
+	return s
+}
+
+// Feed streams the state of a hash to subscribers, as a full snapshot
+// followed by incremental updates.  Changes that are more than members
+// joining or leaving are sent as full snapshots.  A client that reconnects
+// with the epoch it last applied gets only what changed since, as long as
+// that epoch is still in the Feed's history.  The transport is up to the
+// caller; proto/ring.proto describes it for gRPC.
+type Feed struct {
+	c       *Consistent
+	history int
+	mu      sync.Mutex
+	past    map[uint64]Snapshot
+	order   []uint64
+}
+
+// NewFeed creates a Feed over c remembering history past states.
+func NewFeed(c *Consistent, history int) *Feed {
+	if history <= 0 {
+		history = DefaultFeedHistory
+	}
+	return &Feed{c: c, history: history, past: make(map[uint64]Snapshot)}
+}
+
+// Subscribe streams updates from the epoch after from until ctx is done,
+// then closes the channel.  from 0 starts with a full snapshot.  Updates a
+// slow subscriber has not taken yet are coalesced into the next one.
+func (f *Feed) Subscribe(ctx context.Context, from uint64) <-chan RingUpdate {
+	ch := make(chan RingUpdate)
+	go func() {
+		defer close(ch)
+		prev, ok := f.lookup(from)
+		has := ok && from != 0
+		for {
+			s, epoch, changed := f.current()
+			if !has || epoch != from {
+				u := RingUpdate{Epoch: epoch}
+				if has {
+					u.Added, u.Removed, ok = memberDiff(prev, s)
+				}
+				if !has || !ok {
+					snap := s
+					u.Snapshot = &snap
+				}
+				select {
+				case ch <- u:
+				case <-ctx.Done():
+					return
+				}
+				prev, from, has = s, epoch, true
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// current returns the state of the hash, its epoch and a channel closed on
+// the next change, all taken atomically, and records the state in history.
+func (f *Feed) current() (Snapshot, uint64, <-chan struct{}) {
+	f.c.lock()
+	s, epoch, changed := f.c.snapshot(), f.c.epoch, f.c.changes()
+	f.c.unlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.past[epoch]; !ok {
+		f.past[epoch] = s
+		f.order = append(f.order, epoch)
+		if len(f.order) > f.history {
+			delete(f.past, f.order[0])
+			f.order = f.order[1:]
+		}
+	}
+	return s, epoch, changed
+}
+
+func (f *Feed) lookup(epoch uint64) (Snapshot, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.past[epoch]
+	return s, ok
+}
+
+// memberDiff returns the members added and removed from prev to next, and
+// false if anything else changed too.
+func memberDiff(prev, next Snapshot) (added, removed []string, ok bool) {
+	was := make(map[string]bool, len(prev.Members))
+	for _, m := range prev.Members {
+		was[m] = true
+	}
+	for _, m := range next.Members {
+		if !was[m] {
+			added = append(added, m)
+		}
+		delete(was, m)
+	}
+	for m := range was {
+		removed = append(removed, m)
+	}
+	sort.Strings(removed)
+	prev.Members = next.Members
+	return added, removed, reflect.DeepEqual(prev, next)
+}
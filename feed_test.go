@@ -0,0 +1,70 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFeed(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	f := NewFeed(x, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	next := func(ch <-chan RingUpdate) RingUpdate {
+		t.Helper()
+		select {
+		case u := <-ch:
+			return u
+		case <-time.After(time.Second):
+			t.Fatal("no update")
+			return RingUpdate{}
+		}
+	}
+
+	ch := f.Subscribe(ctx, 0)
+	u := next(ch)
+	if u.Snapshot == nil {
+		t.Fatal("expected the stream to start with a snapshot")
+	}
+	s := u.Apply(Snapshot{})
+	x.Add(b)
+	u = next(ch)
+	if u.Snapshot != nil || !reflect.DeepEqual(u.Added, []string{"hijklmn"}) {
+		t.Errorf("got %+v, expected hijklmn to be added", u)
+	}
+	s = u.Apply(s)
+	if !reflect.DeepEqual(s, x.Snapshot()) {
+		t.Errorf("got %+v, expected %+v", s, x.Snapshot())
+	}
+	resume := u.Epoch
+
+	x.Add(c)
+	x.Remove(a)
+	ch2 := f.Subscribe(ctx, resume)
+	u = next(ch2)
+	if u.Snapshot != nil {
+		t.Fatalf("got %+v, expected a resumed stream to send a diff", u)
+	}
+	if !reflect.DeepEqual(u.Apply(s), x.Snapshot()) {
+		t.Errorf("got %+v, expected %+v", u.Apply(s), x.Snapshot())
+	}
+
+	x.SetWeight(b, 2)
+	for u = next(ch); u.Epoch != x.Epoch(); u = next(ch) {
+		s = u.Apply(s)
+	}
+	if u.Snapshot == nil {
+		t.Error("expected a weight change to send a snapshot")
+	}
+	if !reflect.DeepEqual(u.Apply(s), x.Snapshot()) {
+		t.Error("expected the stream to converge on the hash")
+	}
+}
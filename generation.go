@@ -0,0 +1,53 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// Generation returns the generation of element and whether it is a member.
+// A member starts at generation 0 and moves to the next one every time it
+// is marked down or up, rejoins after being removed, or is replaced with
+// UpdateEndpoint, so state cached by member, such as a client's view of
+// what a backend holds, can be dropped when the generation it was filled
+// at has passed.  Generations are remembered by MemberID across removals.
+func (c *Consistent) Generation(element lineProtocol.WriteCloser) (uint64, bool) {
+	c.rlock()
+	defer c.runlock()
+	if !c.members[element] {
+		return 0, false
+	}
+	return c.generations[MemberID(element)], true
+}
+
+// joined records element joining and returns its generation, which moves on
+// if it was a member before.
+// need c.lock() before calling
+func (c *Consistent) joined(element lineProtocol.WriteCloser) uint64 {
+	id := MemberID(element)
+	g, ok := c.generations[id]
+	if !ok {
+		if c.generations == nil {
+			c.generations = make(map[string]uint64)
+		}
+		c.generations[id] = 0
+		return 0
+	}
+	return c.nextGeneration(id, g)
+}
+
+// need c.lock() before calling
+func (c *Consistent) nextGeneration(id string, from uint64) uint64 {
+	if c.generations == nil {
+		c.generations = make(map[string]uint64)
+	}
+	c.generations[id] = from + 1
+	return from + 1
+}
+
+// generation returns the current generation of element.
+// need c.rlock() before calling
+func (c *Consistent) generation(element lineProtocol.WriteCloser) uint64 {
+	return c.generations[MemberID(element)]
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "testing"
+
+func TestGeneration(t *testing.T) {
+	a, b := newMember("a"), newMember("b")
+	x := New()
+	sub := x.Events(32)
+	x.Add(a)
+	x.Add(b)
+	gen := func(e *member) uint64 {
+		g, ok := x.Generation(e)
+		if !ok {
+			t.Fatalf("%s not a member", e.name)
+		}
+		return g
+	}
+	checkNum(int(gen(a)), 0, t)
+
+	x.MarkDown(a)
+	x.MarkUp(a)
+	checkNum(int(gen(a)), 2, t)
+	checkNum(int(gen(b)), 0, t)
+
+	x.Remove(a)
+	if _, ok := x.Generation(a); ok {
+		t.Error("removed member still has a generation")
+	}
+	x.Add(a)
+	checkNum(int(gen(a)), 3, t)
+
+	a2 := newMember("a")
+	if err := x.UpdateEndpoint("a", a2); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(int(gen(a2)), 4, t)
+	l, _ := x.Locate("k")
+	if l.Generation != gen(l.Member.(*member)) {
+		t.Errorf("Locate generation %d, want %d", l.Generation, gen(l.Member.(*member)))
+	}
+
+	var last uint64
+	for len(sub.C) > 0 {
+		switch ev := (<-sub.C).(type) {
+		case MemberEvicted:
+			last = ev.Generation
+		case MemberRecovered:
+			last = ev.Generation
+		case MemberAdded:
+			if ev.Member == a {
+				last = ev.Generation
+			}
+		case MemberReplaced:
+			if ev.Old != a || ev.New != a2 {
+				t.Errorf("replaced %v with %v", ev.Old, ev.New)
+			}
+			last = ev.Generation
+		}
+	}
+	checkNum(int(last), 4, t)
+}
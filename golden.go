@@ -0,0 +1,64 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// GoldenVector is a key and the member it routes to in one hash mode of the
+// canonical configuration described at GoldenVectors.
+type GoldenVector struct {
+	Mode   string `json:"mode"`
+	Key    string `json:"key"`
+	Member string `json:"member"`
+}
+
+// goldenModes builds the routers GoldenVectors covers, by mode name.
+var goldenModes = []struct {
+	name string
+	new  func() Router
+}{
+	{"ring-crc32", func() Router { return NewRouter(Ring, 0) }},
+	{"ring-md5-low32le", func() Router { return ringRouter{New(WithHasher128(MD5, FoldLow32LE))} }},
+	{"ring-md5-low32be", func() Router { return ringRouter{New(WithHasher128(MD5, FoldLow32BE))} }},
+	{"ring-md5-xor", func() Router { return ringRouter{New(WithHasher128(MD5, FoldXor))} }},
+	{"anchor", func() Router { return NewRouter(AnchorHash, 8) }},
+	{"dxhash", func() Router { return NewRouter(DxHashAlgorithm, 8) }},
+}
+
+// GoldenVectors returns the placement of keys "key-0" to "key-63" over the
+// members "member-0" to "member-4", added in that order with the default
+// settings, for every hash mode: the ring with CRC-32 and with MD5 under each
+// Fold, Anchor and DxHash, each with capacity 8.
+//
+// The result must never change: a different placement for the same
+// configuration silently moves data for everyone upgrading.  The package
+// tests hold it against a frozen copy, and implementations in other
+// languages can check themselves against it.
+func GoldenVectors() []GoldenVector {
+	members := make([]lineProtocol.WriteCloser, 5)
+	for i := range members {
+		members[i] = NopMember("member-" + strconv.Itoa(i))
+	}
+	var v []GoldenVector
+	for _, m := range goldenModes {
+		r := m.new()
+		for _, e := range members {
+			r.Add(e)
+		}
+		for i := 0; i < 64; i++ {
+			k := "key-" + strconv.Itoa(i)
+			e, err := r.Get(k)
+			if err != nil {
+				panic("consistent: golden vectors: " + err.Error())
+			}
+			v = append(v, GoldenVector{Mode: m.name, Key: k, Member: e.Name()})
+		}
+	}
+	return v
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite testdata/golden_vectors.json")
+
+func TestGoldenVectors(t *testing.T) {
+	got := GoldenVectors()
+	if *updateGolden {
+		b, _ := json.MarshalIndent(got, "", "  ")
+		if err := os.WriteFile("testdata/golden_vectors.json", append(b, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile("testdata/golden_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []GoldenVector
+	if err := json.Unmarshal(b, &want); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(got), len(want), t)
+	for i := range want {
+		if i < len(got) && got[i] != want[i] {
+			t.Errorf("%s %s: routes to %s, expected %s", want[i].Mode, want[i].Key, got[i].Member, want[i].Member)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// gossipTimeout bounds one exchange with a peer.
+const gossipTimeout = 5 * time.Second
+
+// gossipMessage is one newline-delimited JSON message of the gossip protocol.
+type gossipMessage struct {
+	Version     uint64    `json:"version"`
+	Fingerprint uint64    `json:"fingerprint"`
+	Want        bool      `json:"want,omitempty"`
+	Snapshot    *Snapshot `json:"snapshot,omitempty"`
+}
+
+// Gossiper keeps the ring of several proxy instances identical without a
+// central coordinator.  Instances periodically exchange a version and a
+// fingerprint of their Snapshot over TCP; when the fingerprints differ the one
+// with the lower version (or, on a tie, the lower fingerprint) pulls the other's
+// snapshot and restores it.  A local change to the ring bumps the version, so
+// the latest edit spreads to every instance.
+type Gossiper struct {
+	c       *Consistent
+	lookup  func(name string) (lineProtocol.WriteCloser, error)
+	mu      sync.Mutex
+	version uint64
+	print   uint64
+}
+
+// NewGossiper creates a Gossiper for c.  lookup turns member names found in a
+// peer's snapshot into writers, as for Restore.
+func NewGossiper(c *Consistent, lookup func(name string) (lineProtocol.WriteCloser, error)) *Gossiper {
+	g := &Gossiper{c: c, lookup: lookup}
+	g.print = fingerprint(c.Snapshot())
+	return g
+}
+
+func fingerprint(s Snapshot) uint64 {
+	b, _ := json.Marshal(s)
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// state returns the current version, fingerprint and snapshot, bumping the
+// version if the ring changed since the last look.
+func (g *Gossiper) state() (gossipMessage, Snapshot) {
+	s := g.c.Snapshot()
+	f := fingerprint(s)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f != g.print {
+		g.version++
+		g.print = f
+	}
+	return gossipMessage{Version: g.version, Fingerprint: g.print}, s
+}
+
+// adopt restores a peer's snapshot that won over the local state.
+func (g *Gossiper) adopt(m gossipMessage) error {
+	if m.Snapshot == nil {
+		return nil
+	}
+	if err := g.c.Replicate(*m.Snapshot, g.lookup); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.version = m.Version
+	g.print = fingerprint(g.c.Snapshot())
+	return nil
+}
+
+// wins reports whether a is newer than b.
+func wins(a, b gossipMessage) bool {
+	if a.Version != b.Version {
+		return a.Version > b.Version
+	}
+	return a.Fingerprint > b.Fingerprint
+}
+
+// Version returns the gossip version and fingerprint of the local ring.
+func (g *Gossiper) Version() (version, fingerprint uint64) {
+	m, _ := g.state()
+	return m.Version, m.Fingerprint
+}
+
+// Serve answers exchanges from peers on ln until it fails.
+func (g *Gossiper) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go g.serve(conn)
+	}
+}
+
+func (g *Gossiper) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(gossipTimeout))
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	enc := json.NewEncoder(conn)
+	var peer gossipMessage
+	if dec.Decode(&peer) != nil {
+		return
+	}
+	local, s := g.state()
+	switch {
+	case local.Fingerprint == peer.Fingerprint:
+	case wins(local, peer):
+		local.Snapshot = &s
+	default:
+		local.Want = true
+	}
+	if enc.Encode(local) != nil || !local.Want {
+		return
+	}
+	if dec.Decode(&peer) != nil || g.adopt(peer) != nil {
+		return
+	}
+	// acknowledge, so the peer knows the snapshot was taken
+	local, _ = g.state()
+	enc.Encode(local)
+}
+
+// Exchange runs one round of gossip with the peer at addr.
+func (g *Gossiper) Exchange(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(gossipTimeout))
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	enc := json.NewEncoder(conn)
+	local, s := g.state()
+	if err := enc.Encode(local); err != nil {
+		return err
+	}
+	var peer gossipMessage
+	if err := dec.Decode(&peer); err != nil {
+		return err
+	}
+	if peer.Want {
+		local.Snapshot = &s
+		if err := enc.Encode(local); err != nil {
+			return err
+		}
+		return dec.Decode(&peer)
+	}
+	return g.adopt(peer)
+}
+
+// Start gossips with a random peer from peers every interval until ctx is
+// done.
+func (g *Gossiper) Start(ctx context.Context, peers []string, interval time.Duration) {
+	if len(peers) == 0 {
+		return
+	}
+	go func() {
+		t := g.c.clock.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.Chan():
+				g.Exchange(ctx, peers[rand.Intn(len(peers))])
+			}
+		}
+	}()
+}
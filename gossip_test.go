@@ -0,0 +1,45 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestGossipConverges(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	lookup := lookupIn(a, b, c)
+	x, y := New(), New()
+	x.Add(a)
+	y.Add(b)
+	gx, gy := NewGossiper(x, lookup), NewGossiper(y, lookup)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	go gy.Serve(ln)
+
+	// x changes last, so its state wins
+	x.Add(c)
+	if err := gx.Exchange(context.Background(), ln.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	vx, fx := gx.Version()
+	vy, fy := gy.Version()
+	if fx != fy || vx != vy {
+		t.Errorf("got x %d/%x and y %d/%x, expected the same state", vx, fx, vy, fy)
+	}
+	checkNum(len(y.Members()), 2, t)
+
+	// a change on y flows back to x when x gossips again
+	y.Remove(c)
+	if err := gx.Exchange(context.Background(), ln.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.Members()), 1, t)
+}
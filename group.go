@@ -0,0 +1,107 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrNoEndpoints is the error returned by a Group without endpoints.
+var ErrNoEndpoints = errors.New("no endpoints")
+
+// Group is a WriteCloser made of several endpoints of one backend, for
+// example its ingest servers, that the ring places as a single member.
+// Writes go to the endpoints in turn; a write that fails on one endpoint is
+// retried on the next ones, so the member fails only when every endpoint
+// does.  Each Write goes to a single endpoint in full.
+type Group struct {
+	name      string
+	endpoints []lineProtocol.WriteCloser
+	next      atomic.Uint64
+	mu        sync.Mutex
+	closed    bool
+}
+
+// NewGroup creates a Group called name over endpoints.
+func NewGroup(name string, endpoints ...lineProtocol.WriteCloser) *Group {
+	return &Group{name: name, endpoints: endpoints}
+}
+
+// Name returns the name given to NewGroup.
+func (g *Group) Name() string { return g.name }
+
+// Endpoints returns the endpoints of the group.
+func (g *Group) Endpoints() []lineProtocol.WriteCloser {
+	return append([]lineProtocol.WriteCloser(nil), g.endpoints...)
+}
+
+// Write writes b to the next endpoint, failing over to the others in turn.
+// It returns the joined errors if every endpoint fails.
+func (g *Group) Write(b []byte) (int, error) {
+	g.mu.Lock()
+	closed := g.closed
+	g.mu.Unlock()
+	if closed {
+		return 0, ErrClosed
+	}
+	if len(g.endpoints) == 0 {
+		return 0, ErrNoEndpoints
+	}
+	start := g.next.Add(1) - 1
+	var errs []error
+	for i := range g.endpoints {
+		e := g.endpoints[(start+uint64(i))%uint64(len(g.endpoints))]
+		n, err := e.Write(b)
+		if err == nil {
+			return n, nil
+		}
+		errs = append(errs, err)
+	}
+	return 0, errors.Join(errs...)
+}
+
+// Ping reports the group healthy if any endpoint is.  Endpoints that do not
+// implement Pinger count as healthy.
+func (g *Group) Ping(ctx context.Context) error {
+	if len(g.endpoints) == 0 {
+		return ErrNoEndpoints
+	}
+	var errs []error
+	for _, e := range g.endpoints {
+		p, ok := e.(Pinger)
+		if !ok {
+			return nil
+		}
+		err := p.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every endpoint and returns their joined errors.
+func (g *Group) Close() error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return nil
+	}
+	g.closed = true
+	g.mu.Unlock()
+	var errs []error
+	for _, e := range g.endpoints {
+		if err := e.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	a, b := newMember("a"), newMember("b")
+	g := NewGroup("backend", a, b)
+	x := New()
+	x.Add(g)
+	for i := 0; i < 4; i++ {
+		if _, err := x.Write("foo", []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.String() != "xx" || b.String() != "xx" {
+		t.Errorf("got %q and %q, expected writes spread evenly", a.String(), b.String())
+	}
+
+	a.err = errors.New("connection reset")
+	for i := 0; i < 2; i++ {
+		if _, err := g.Write([]byte("y")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkNum(len(b.String()), 4, t)
+	b.err = errors.New("connection reset")
+	if _, err := g.Write([]byte("z")); err == nil {
+		t.Error("expected a write to fail once every endpoint fails")
+	}
+	if err := g.Ping(context.Background()); err != nil {
+		t.Errorf("got %v, expected endpoints without Ping to count as healthy", err)
+	}
+	g.Close()
+	if !a.closed || !b.closed {
+		t.Error("expected Close to close every endpoint")
+	}
+	if _, err := g.Write([]byte("z")); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, expected ErrClosed", err)
+	}
+}
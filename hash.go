@@ -0,0 +1,63 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+)
+
+// Hasher maps a key to a point on the 32-bit circle.  The default is CRC-32
+// (IEEE).
+type Hasher func(key []byte) uint32
+
+// Hasher128 produces a 128-bit digest of a key, such as MD5 or Murmur3-128.
+type Hasher128 func(key []byte) [16]byte
+
+// Fold reduces a 128-bit digest to a point on the 32-bit circle.  Each fold is
+// defined on the digest bytes so placement can be reproduced in any language.
+type Fold int
+
+const (
+	// FoldLow32LE takes digest bytes 0-3 as a little-endian uint32, the fold
+	// used by ketama and libmemcached.
+	FoldLow32LE Fold = iota
+	// FoldLow32BE takes digest bytes 0-3 as a big-endian uint32.
+	FoldLow32BE
+	// FoldXor xors the four big-endian uint32 words of the digest.
+	FoldXor
+)
+
+// Fold128 returns a Hasher that folds the output of h with f.
+func Fold128(h Hasher128, f Fold) Hasher {
+	return func(key []byte) uint32 {
+		d := h(key)
+		switch f {
+		case FoldLow32BE:
+			return binary.BigEndian.Uint32(d[0:4])
+		case FoldXor:
+			return binary.BigEndian.Uint32(d[0:4]) ^ binary.BigEndian.Uint32(d[4:8]) ^
+				binary.BigEndian.Uint32(d[8:12]) ^ binary.BigEndian.Uint32(d[12:16])
+		}
+		return binary.LittleEndian.Uint32(d[0:4])
+	}
+}
+
+// MD5 is a Hasher128 computing the MD5 digest of key.
+func MD5(key []byte) [16]byte {
+	return md5.Sum(key)
+}
+
+// WithHasher places members and keys on the circle with h instead of CRC-32.
+func WithHasher(h Hasher) Option {
+	return func(c *Consistent) {
+		c.hasher = h
+	}
+}
+
+// WithHasher128 places members and keys with a 128-bit hash folded by f.
+func WithHasher128(h Hasher128, f Fold) Option {
+	return WithHasher(Fold128(h, f))
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "testing"
+
+func TestFold128(t *testing.T) {
+	// MD5("") = d41d8cd98f00b204e9800998ecf8427e
+	for _, v := range []struct {
+		fold Fold
+		want uint32
+	}{
+		{FoldLow32LE, 0xd98c1dd4},
+		{FoldLow32BE, 0xd41d8cd9},
+		{FoldXor, 0x5e65753b},
+	} {
+		if got := Fold128(MD5, v.fold)(nil); got != v.want {
+			t.Errorf("fold %d: got %#x, expected %#x", v.fold, got, v.want)
+		}
+	}
+}
+
+func TestWithHasher128(t *testing.T) {
+	x := New(WithHasher128(MD5, FoldLow32LE))
+	x.Add(newMember("abcdefg"))
+	h := Fold128(MD5, FoldLow32LE)
+	if got, want := x.hashKey("ggg"), h([]byte("ggg")); got != want {
+		t.Errorf("got %#x, expected %#x", got, want)
+	}
+	if _, ok := x.circle[h([]byte(x.elementKey(newMember("abcdefg"), 0)))]; !ok {
+		t.Errorf("expected vnodes placed with the configured hasher")
+	}
+}
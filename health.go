@@ -0,0 +1,288 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DefaultFailureRatio is the share of failed writes at which CheckHealth marks
+// a member without Ping down.
+const DefaultFailureRatio = 0.5
+
+// DefaultMinWrites is the number of writes a member without Ping needs between
+// two CheckHealth rounds before its failure ratio is trusted.
+const DefaultMinWrites = 10
+
+// Pinger is implemented by members that can check their own health.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// memberState is the runtime state kept for every member.
+type memberState struct {
+	down     bool
+	excluded bool // see Exclude
+	writes   atomic.Int64
+	failures atomic.Int64
+	sem      chan struct{} // in-flight writes, see WithConcurrencyLimit
+	waiting  atomic.Int64
+	routed   [2]atomic.Int64 // current and previous window, see WithRoutedCounts
+	keys     *hyperLogLog    // distinct keys routed, see WithCardinality
+
+	compression compression  // bytes written before and after, see Encoder
+	duplicates  atomic.Int64 // writes dropped, see WithDedup
+
+	// write outcomes since the last WeightController round
+	fbWrites   atomic.Int64
+	fbFailures atomic.Int64
+	fbLatency  atomic.Int64
+}
+
+// need c.lock() before calling
+func (c *Consistent) newState() *memberState {
+	st := new(memberState)
+	if c.concurrency > 0 {
+		st.sem = make(chan struct{}, c.concurrency)
+	}
+	if c.cardinality > 0 {
+		st.keys = newHyperLogLog(c.cardinality)
+	}
+	return st
+}
+
+// WithFailureThreshold sets the failure ratio and minimum number of writes
+// CheckHealth uses for members that do not implement Pinger.
+func WithFailureThreshold(ratio float64, minWrites int64) Option {
+	return func(c *Consistent) {
+		c.failureRatio = ratio
+		c.minWrites = minWrites
+	}
+}
+
+// Write routes key and writes p to the member it lands on, recording the
+// outcome for CheckHealth.  Writes are subject to WithConcurrencyLimit and
+// WithDedup, and sampled by WithAudit.  Under WriteBoth, a key moving in the
+// pending rebalance is also written to its next owner.
+func (c *Consistent) Write(key string, p []byte) (int, error) {
+	defer c.exit(c.enter())
+	e, err := c.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	var fp uint64
+	if c.dedup != nil {
+		if fp = c.dedup.fingerprint(key, p); c.duplicate(e, fp) {
+			return len(p), nil
+		}
+	}
+	var next lineProtocol.WriteCloser
+	if c.admission == WriteBoth {
+		_, next, _ = c.Moving(key)
+	}
+	e, release, err := c.acquire(key, e)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	start := time.Now()
+	n, err := c.write(e, p)
+	c.recordWrite(e, err, time.Since(start))
+	if c.audit != nil {
+		c.auditWrite(key, e, p, err)
+	}
+	if err != nil {
+		c.rlock()
+		defer c.runlock()
+		return n, c.opError("write", key, e, err)
+	}
+	if c.hints != nil {
+		c.storeHint(key, p)
+	}
+	if next != nil && next != e {
+		start = time.Now()
+		_, err = c.write(next, p)
+		c.recordWrite(next, err, time.Since(start))
+		if err != nil {
+			c.rlock()
+			defer c.runlock()
+			return n, c.opError("write", key, next, err)
+		}
+	}
+	if c.dedup != nil {
+		c.dedup.record(fp, c.clock.Now())
+	}
+	return n, nil
+}
+
+func (c *Consistent) recordWrite(element lineProtocol.WriteCloser, err error, d time.Duration) {
+	c.rlock()
+	defer c.runlock()
+	if st, ok := c.state[element]; ok {
+		st.writes.Add(1)
+		st.fbWrites.Add(1)
+		st.fbLatency.Add(int64(d))
+		if err != nil {
+			st.failures.Add(1)
+			st.fbFailures.Add(1)
+		}
+	}
+	if err != nil {
+		c.bus.emit(WriteFailed{Member: element, Err: err})
+	}
+}
+
+// CheckHealth runs one round of health checks.  Members implementing Pinger
+// are pinged concurrently; the others are judged by the writes made through
+// Write since the previous round.  Members found unhealthy are marked down and
+// Get routes their keys to the next member on the circle until they recover;
+// their points stay in place so nothing else moves.
+func (c *Consistent) CheckHealth(ctx context.Context) {
+	c.rlock()
+	type verdict struct {
+		element lineProtocol.WriteCloser
+		healthy bool
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		verdicts []verdict
+	)
+	for k, h := range c.state {
+		if c.faults != nil {
+			if err := c.faults.HealthFault(k.Name()); err != nil {
+				h.writes.Store(0)
+				h.failures.Store(0)
+				mu.Lock()
+				verdicts = append(verdicts, verdict{k, false})
+				mu.Unlock()
+				continue
+			}
+		}
+		if p, ok := k.(Pinger); ok {
+			wg.Add(1)
+			go func(k lineProtocol.WriteCloser) {
+				defer wg.Done()
+				err := p.Ping(ctx)
+				mu.Lock()
+				verdicts = append(verdicts, verdict{k, err == nil})
+				mu.Unlock()
+			}(k)
+			continue
+		}
+		writes, failures := h.writes.Swap(0), h.failures.Swap(0)
+		if writes >= c.minWrites && writes > 0 {
+			mu.Lock()
+			verdicts = append(verdicts, verdict{k, float64(failures)/float64(writes) < c.failureRatio})
+			mu.Unlock()
+		}
+	}
+	c.runlock()
+	wg.Wait()
+
+	var rejoin []lineProtocol.WriteCloser
+	c.lock()
+	for _, v := range verdicts {
+		if v.healthy && c.isDown(v.element) {
+			rejoin = append(rejoin, v.element)
+			continue
+		}
+		c.setDown(v.element, !v.healthy)
+	}
+	c.unlock()
+	for _, e := range rejoin {
+		c.MarkUp(e)
+	}
+}
+
+// StartHealthChecks runs CheckHealth every interval until ctx is done.
+func (c *Consistent) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := c.clock.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.Chan():
+				c.CheckHealth(ctx)
+			}
+		}
+	}()
+}
+
+// MarkDown marks element down.  Like Remove, it does nothing if that would
+// leave fewer members up than WithMinMembers allows.
+func (c *Consistent) MarkDown(element lineProtocol.WriteCloser) {
+	c.lock()
+	defer c.unlock()
+	c.setDown(element, true)
+}
+
+// MarkUp marks element up again.  With WithHintStore, the writes its
+// stand-ins took while it was down are replayed to it first; if that fails
+// element stays down and the error is returned.
+func (c *Consistent) MarkUp(element lineProtocol.WriteCloser) error {
+	if err := c.backfill(element); err != nil {
+		return err
+	}
+	c.lock()
+	c.setDown(element, false)
+	c.unlock()
+	// pick up hints stored while the first replay ran
+	return c.backfill(element)
+}
+
+// Healthy reports whether element is a member that is not marked down.
+func (c *Consistent) Healthy(element lineProtocol.WriteCloser) bool {
+	c.rlock()
+	defer c.runlock()
+	h, ok := c.state[element]
+	return ok && !h.down
+}
+
+// need c.lock() before calling
+func (c *Consistent) setDown(element lineProtocol.WriteCloser, down bool) {
+	h, ok := c.state[element]
+	if !ok || h.down == down {
+		return
+	}
+	if down && !c.allowShrink(c.active()-1) {
+		return
+	}
+	h.down = down
+	id := MemberID(element)
+	g := c.nextGeneration(id, c.generations[id])
+	if down {
+		c.recordChurn(churnEvict, 1)
+		c.bus.emit(MemberEvicted{Member: element, Generation: g})
+	} else {
+		c.bus.emit(MemberRecovered{Member: element, Generation: g})
+	}
+	c.advance()
+}
+
+// active returns the number of members that are not down.
+// need c.rlock() before calling
+func (c *Consistent) active() int {
+	n := 0
+	for _, h := range c.state {
+		if !h.down {
+			n++
+		}
+	}
+	return n
+}
+
+// need c.rlock() before calling
+func (c *Consistent) isDown(element lineProtocol.WriteCloser) bool {
+	h, ok := c.state[element]
+	return ok && h.down
+}
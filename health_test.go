@@ -0,0 +1,92 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type pingMember struct {
+	*member
+	err error
+}
+
+func (p *pingMember) Ping(ctx context.Context) error { return p.err }
+
+func TestMarkDownRoutesAround(t *testing.T) {
+	x := New()
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x.Add(a)
+	x.Add(b)
+	owner, _ := x.Get("ggg")
+	other := a
+	if owner == a {
+		other = b
+	}
+	x.MarkDown(owner)
+	if x.Healthy(owner) {
+		t.Errorf("expected %v to be down", owner)
+	}
+	if got, _ := x.Get("ggg"); got != other {
+		t.Errorf("got %v, expected %v while owner is down", got, other)
+	}
+	checkNum(len(x.circle), 40, t)
+	x.MarkDown(other)
+	if _, err := x.Get("ggg"); !errors.Is(err, ErrMemberDown) {
+		t.Errorf("got %v, expected member down error", err)
+	}
+	x.MarkUp(owner)
+	if got, _ := x.Get("ggg"); got != owner {
+		t.Errorf("got %v, expected %v after recovery", got, owner)
+	}
+}
+
+func TestCheckHealthPing(t *testing.T) {
+	a := &pingMember{member: newMember("abcdefg")}
+	x := New(WithMinMembers(1))
+	x.Add(a)
+	x.Add(newMember("hijklmn"))
+	a.err = errors.New("connection refused")
+	x.CheckHealth(context.Background())
+	if x.Healthy(a) {
+		t.Errorf("expected failed ping to mark member down")
+	}
+	a.err = nil
+	x.CheckHealth(context.Background())
+	if !x.Healthy(a) {
+		t.Errorf("expected successful ping to mark member up")
+	}
+}
+
+func TestCheckHealthWrites(t *testing.T) {
+	a := newMember("abcdefg")
+	x := New(WithFailureThreshold(0.5, 4))
+	x.Add(a)
+	a.err = errors.New("broken pipe")
+	for i := 0; i < 4; i++ {
+		if _, err := x.Write("ggg", []byte("cpu value=1\n")); !errors.Is(err, a.err) {
+			t.Errorf("got %v, expected write error", err)
+		}
+	}
+	x.CheckHealth(context.Background())
+	if x.Healthy(a) {
+		t.Errorf("expected failing writes to mark member down")
+	}
+	if _, err := x.Write("ggg", nil); !errors.Is(err, ErrMemberDown) {
+		t.Errorf("got %v, expected member down error", err)
+	}
+}
+
+func TestMarkDownMinMembers(t *testing.T) {
+	a := newMember("abcdefg")
+	x := New(WithMinMembers(1))
+	x.Add(a)
+	x.MarkDown(a)
+	if !x.Healthy(a) {
+		t.Errorf("expected MarkDown to be refused below the minimum")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// member is a lineProtocol.WriteCloser that remembers what was written to it.
+type member struct {
+	name   string
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+	err    error
+}
+
+func newMember(name string) *member { return &member{name: name} }
+
+func (m *member) Name() string { return m.name }
+
+func (m *member) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return 0, m.err
+	}
+	return m.buf.Write(p)
+}
+
+func (m *member) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func (m *member) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buf.String()
+}
+
+func checkNum(num, expected int, t *testing.T) {
+	if num != expected {
+		t.Errorf("got %d, expected %d", num, expected)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// HintStore keeps writes meant for a member that was down, so they can be
+// replayed when it comes back (hinted handoff).
+type HintStore interface {
+	// Store keeps a copy of p for the member called name.
+	Store(name string, p []byte) error
+	// Replay calls fn with each payload kept for name, oldest first, and
+	// forgets the ones fn accepted.  It stops at the first error from fn.
+	Replay(name string, fn func(p []byte) error) error
+}
+
+// WithHintStore makes Write store a hint in s whenever a key's owner is down
+// and a stand-in takes the write, and MarkUp replay those hints before the
+// owner takes traffic again.  Hints carry an idempotency token, see
+// WithIdempotencyToken, so a replay that is retried can be deduplicated
+// downstream.  No hint is kept for a write the stand-in fails: the caller
+// gets the error and a retry stores its own.
+func WithHintStore(s HintStore) Option {
+	return func(c *Consistent) {
+		c.hints = s
+	}
+}
+
+// storeHint keeps p for the owner of key if it is down.
+func (c *Consistent) storeHint(key string, p []byte) {
+	c.rlock()
+	var owner lineProtocol.WriteCloser
+	if len(c.circle) > 0 {
+		h := c.keyHash(key)
+		if o, ok := c.override(h); ok {
+			owner = o
+		} else {
+			owner = c.circle[c.sortedHashes[c.search(h)]]
+		}
+	}
+	down := owner != nil && c.isDown(owner)
+	c.runlock()
+	if !down {
+		return
+	}
+	token := NewIdempotencyToken()
+	if c.hints.Store(MemberID(owner), WithIdempotencyToken(token, p)) == nil {
+		c.hintsStored.Add(1)
+		c.bus.emit(HintStored{Member: owner, Key: key, Bytes: len(p), Token: token})
+	}
+}
+
+// backfill replays the hints kept for element.
+func (c *Consistent) backfill(element lineProtocol.WriteCloser) error {
+	if c.hints == nil {
+		return nil
+	}
+	return c.hints.Replay(MemberID(element), func(p []byte) error {
+		if _, err := c.write(element, p); err != nil {
+			return err
+		}
+		c.hintsReplayed.Add(1)
+		return nil
+	})
+}
+
+// MemoryHints is a HintStore kept in memory, holding up to a fixed number of
+// payloads per member and dropping the oldest beyond that.
+type MemoryHints struct {
+	mu    sync.Mutex
+	limit int
+	hints map[string][][]byte
+}
+
+// NewMemoryHints creates a MemoryHints keeping up to limit payloads per member.
+func NewMemoryHints(limit int) *MemoryHints {
+	return &MemoryHints{limit: limit, hints: make(map[string][][]byte)}
+}
+
+// Store implements HintStore.
+func (m *MemoryHints) Store(name string, p []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := append(m.hints[name], p)
+	if len(h) > m.limit {
+		h = h[len(h)-m.limit:]
+	}
+	m.hints[name] = h
+	return nil
+}
+
+// Replay implements HintStore.
+func (m *MemoryHints) Replay(name string, fn func(p []byte) error) error {
+	for {
+		m.mu.Lock()
+		h := m.hints[name]
+		if len(h) == 0 {
+			delete(m.hints, name)
+			m.mu.Unlock()
+			return nil
+		}
+		p := h[0]
+		m.hints[name] = h[1:]
+		m.mu.Unlock()
+		if err := fn(p); err != nil {
+			m.mu.Lock()
+			m.hints[name] = append([][]byte{p}, m.hints[name]...)
+			m.mu.Unlock()
+			return err
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHintedHandoff(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New(WithHintStore(NewMemoryHints(10)))
+	x.Add(a)
+	x.Add(b)
+	owner, _ := x.Get("ggg")
+	standIn := a
+	if owner == a {
+		standIn = b
+	}
+	o := owner.(*member)
+	x.MarkDown(o)
+	x.Write("ggg", []byte("cpu value=1\n"))
+	x.Write("ggg", []byte("cpu value=2\n"))
+	if standIn.String() != "cpu value=1\ncpu value=2\n" || o.String() != "" {
+		t.Fatalf("expected stand-in to take the writes")
+	}
+
+	o.err = errors.New("still starting")
+	if err := x.MarkUp(o); err != o.err {
+		t.Errorf("got %v, expected replay error", err)
+	}
+	if x.Healthy(o) {
+		t.Errorf("expected member to stay down after failed replay")
+	}
+	o.err = nil
+	if err := x.MarkUp(o); err != nil {
+		t.Fatal(err)
+	}
+	var replayed []string
+	for _, line := range strings.SplitAfter(o.String(), "\n") {
+		if !strings.HasPrefix(line, tokenPrefix) {
+			replayed = append(replayed, line)
+		}
+	}
+	if strings.Join(replayed, "") != "cpu value=1\ncpu value=2\n" {
+		t.Errorf("got %q replayed, expected both writes in order", o.String())
+	}
+	if strings.Count(o.String(), tokenPrefix) != 2 {
+		t.Errorf("got %q replayed, expected every hint to carry a token", o.String())
+	}
+	if s := x.Stats(); s.HintsStored != 2 || s.HintsReplayed != 2 {
+		t.Errorf("got %d stored %d replayed, expected 2 and 2", s.HintsStored, s.HintsReplayed)
+	}
+}
+
+func TestHintNotStoredForFailedWrite(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New(WithHintStore(NewMemoryHints(10)))
+	x.Add(a)
+	x.Add(b)
+	owner, _ := x.Get("ggg")
+	x.MarkDown(owner)
+	standIn, _ := x.Get("ggg")
+	standIn.(*member).err = errors.New("broken")
+	if _, err := x.Write("ggg", []byte("cpu value=1\n")); err == nil {
+		t.Fatal("expected the stand-in write to fail")
+	}
+	if s := x.Stats(); s.HintsStored != 0 {
+		t.Errorf("got %d hints stored, expected none for a failed write", s.HintsStored)
+	}
+}
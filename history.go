@@ -0,0 +1,108 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StatsHistory samples the Stats of a hash at a fixed interval so the
+// counters and histograms can be read over a trailing window, such as the
+// last minute or hour, instead of since New.
+type StatsHistory struct {
+	c        *Consistent
+	interval time.Duration
+	retain   time.Duration
+
+	mu      sync.Mutex
+	samples []statsSample // oldest first
+}
+
+type statsSample struct {
+	at     time.Time
+	resets uint64
+	stats  Stats
+}
+
+// NewStatsHistory creates a StatsHistory for c keeping samples for retain,
+// the longest window Window can report exactly.  Samples are taken by Sample,
+// or every interval once Start is called.
+func NewStatsHistory(c *Consistent, interval, retain time.Duration) *StatsHistory {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if retain < interval {
+		retain = interval
+	}
+	h := &StatsHistory{c: c, interval: interval, retain: retain}
+	h.Sample()
+	return h
+}
+
+// current returns the stats of the hash and how often they were reset.
+func (h *StatsHistory) current() (Stats, uint64) {
+	h.c.rlock()
+	defer h.c.runlock()
+	return h.c.currentStats(), h.c.statsResets
+}
+
+// Sample records the current stats.
+func (h *StatsHistory) Sample() {
+	s, resets := h.current()
+	now := h.c.clock.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = append(h.samples, statsSample{at: now, resets: resets, stats: s})
+	cut := 0
+	// keep one sample older than retain so a full window has a base
+	for cut+1 < len(h.samples) && now.Sub(h.samples[cut+1].at) >= h.retain {
+		cut++
+	}
+	h.samples = append(h.samples[:0], h.samples[cut:]...)
+}
+
+// Start calls Sample every interval until ctx is done.
+func (h *StatsHistory) Start(ctx context.Context) {
+	go func() {
+		t := h.c.clock.NewTicker(h.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.Chan():
+				h.Sample()
+			}
+		}
+	}()
+}
+
+// Window returns the counters and histograms accumulated over about the last
+// d, measured from the newest sample taken at least d ago, or the oldest one
+// kept if there is none.  If ResetStats was called within the window, it
+// returns what accumulated since, which is Stats itself.
+func (h *StatsHistory) Window(d time.Duration) Stats {
+	s, resets := h.current()
+	now := h.c.clock.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var base *statsSample
+	for i := len(h.samples) - 1; i >= 0; i-- {
+		if h.samples[i].resets != resets {
+			// reset within the window: everything counted is in it
+			return s
+		}
+		base = &h.samples[i]
+		if now.Sub(base.at) >= d {
+			break
+		}
+	}
+	if base == nil {
+		return s
+	}
+	return s.Sub(base.stats)
+}
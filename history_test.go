@@ -0,0 +1,50 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStatsHistory(t *testing.T) {
+	x := New(WithLatencyHistograms())
+	x.Add(newMember("a"))
+	h := NewStatsHistory(x, time.Millisecond, time.Hour)
+	for i := 0; i < 10; i++ {
+		x.Get(fmt.Sprint(i))
+	}
+	time.Sleep(2 * time.Millisecond)
+	h.Sample()
+	x.Add(newMember("b"))
+	for i := 0; i < 5; i++ {
+		x.Get(fmt.Sprint(i))
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	recent := h.Window(2 * time.Millisecond)
+	if recent.Latency.Get.Count != 5 || recent.Churn.Adds != 1 || recent.Rebuilds != 1 {
+		t.Errorf("last window: %d gets, %d adds, %d rebuilds; want 5, 1, 1",
+			recent.Latency.Get.Count, recent.Churn.Adds, recent.Rebuilds)
+	}
+	var n int64
+	for _, b := range recent.Latency.Get.Buckets {
+		n += b.Count
+	}
+	checkNum(int(n), 5, t)
+	if recent.Members != 2 {
+		t.Errorf("got %d members, want the current 2", recent.Members)
+	}
+	if all := h.Window(time.Hour); all.Latency.Get.Count != 15 || all.Churn.Adds != 1 {
+		t.Errorf("whole history: %d gets, %d adds; want 15, 1", all.Latency.Get.Count, all.Churn.Adds)
+	}
+
+	x.ResetStats()
+	x.Get("k")
+	if s := h.Window(time.Hour); s.Latency.Get.Count != 1 {
+		t.Errorf("got %d gets since the reset, want 1", s.Latency.Get.Count)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// HotKey is one of the most frequently routed keys.
+type HotKey struct {
+	Key    string
+	Count  int64                    // estimated Gets of Key in the window
+	Error  int64                    // how much Count may overestimate
+	Rate   float64                  // estimated Gets per second
+	Member lineProtocol.WriteCloser // current owner, nil if there is none or a rule delegates Key
+}
+
+// hotEntry is a key tracked by the sketch.
+type hotEntry struct {
+	key   string
+	count int64
+	err   int64
+	index int
+}
+
+// hotHeap orders the tracked keys by count, least first.
+type hotHeap []*hotEntry
+
+func (h hotHeap) Len() int           { return len(h) }
+func (h hotHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h hotHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *hotHeap) Push(x any) {
+	e := x.(*hotEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *hotHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// hotKeys is a Space-Saving sketch of the keys routed by Get within a window.
+// It tracks a fixed number of keys; a key seen for the first time when the
+// sketch is full replaces the least counted one and inherits its count as
+// error.  At the end of each window the result is kept for TopKeys and the
+// sketch starts over.
+type hotKeys struct {
+	mu       sync.Mutex
+	capacity int
+	window   time.Duration
+	start    time.Time
+	keys     map[string]*hotEntry
+	heap     hotHeap
+	last     []HotKey // result of the previous window
+	lastSpan time.Duration
+}
+
+// WithHotKeys makes Get track the approximately capacity most frequent keys
+// it routes, per window, for TopKeys.  Tracking takes a mutex on every Get.
+func WithHotKeys(capacity int, window time.Duration) Option {
+	return func(c *Consistent) {
+		c.hot = &hotKeys{
+			capacity: capacity,
+			window:   window,
+			keys:     make(map[string]*hotEntry, capacity),
+		}
+	}
+}
+
+func (s *hotKeys) record(key string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotate(now)
+	if e, ok := s.keys[key]; ok {
+		e.count++
+		heap.Fix(&s.heap, e.index)
+		return
+	}
+	if len(s.heap) < s.capacity {
+		e := &hotEntry{key: key, count: 1}
+		s.keys[key] = e
+		heap.Push(&s.heap, e)
+		return
+	}
+	if s.capacity <= 0 {
+		return
+	}
+	e := s.heap[0]
+	delete(s.keys, e.key)
+	e.key, e.err = key, e.count
+	e.count++
+	s.keys[key] = e
+	heap.Fix(&s.heap, 0)
+}
+
+// need s.mu held
+func (s *hotKeys) rotate(now time.Time) {
+	span := now.Sub(s.start)
+	if span < s.window {
+		return
+	}
+	s.last = s.top()
+	s.lastSpan = span
+	if span >= 2*s.window {
+		// nothing was routed for a whole window
+		s.last = nil
+	}
+	s.start = now
+	s.keys = make(map[string]*hotEntry, s.capacity)
+	s.heap = s.heap[:0]
+}
+
+// top returns the tracked keys, most counted first.
+// need s.mu held
+func (s *hotKeys) top() []HotKey {
+	res := make([]HotKey, 0, len(s.heap))
+	for _, e := range s.heap {
+		res = append(res, HotKey{Key: e.key, Count: e.count, Error: e.err})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+// TopKeys returns up to n of the keys Get routed most often in the last full
+// window, or in the current one if none has completed yet, with their
+// estimated rates and current owners.  It returns nil if n <= 0 or the hash
+// was not created WithHotKeys.
+func (c *Consistent) TopKeys(n int) []HotKey {
+	if c.hot == nil || n <= 0 {
+		return nil
+	}
+	s := c.hot
+	s.mu.Lock()
+	now := c.clock.Now()
+	s.rotate(now)
+	res, span := s.last, s.lastSpan
+	if res == nil {
+		res, span = s.top(), now.Sub(s.start)
+	}
+	s.mu.Unlock()
+	if len(res) > n {
+		res = res[:n]
+	}
+	res = append([]HotKey(nil), res...)
+
+	c.rlock()
+	defer c.runlock()
+	for i := range res {
+		if span > 0 {
+			res[i].Rate = float64(res[i].Count) / span.Seconds()
+		}
+		res[i].Member = c.owner(res[i].Key)
+	}
+	return res
+}
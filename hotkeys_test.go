@@ -0,0 +1,57 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTopKeys(t *testing.T) {
+	a := newMember("abcdefg")
+	x := New(WithHotKeys(10, time.Hour))
+	x.Add(a)
+	for i := 0; i < 1000; i++ {
+		x.Get("hot")
+		if i%2 == 0 {
+			x.Get("warm")
+		}
+		x.Get("cold" + strconv.Itoa(i))
+	}
+	top := x.TopKeys(2)
+	checkNum(len(top), 2, t)
+	if top[0].Key != "hot" || top[1].Key != "warm" {
+		t.Fatalf("got %q and %q, expected hot and warm", top[0].Key, top[1].Key)
+	}
+	if top[0].Count-top[0].Error > 1000 || top[0].Count < 1000 {
+		t.Errorf("got count %d with error %d, expected it to bound 1000", top[0].Count, top[0].Error)
+	}
+	if top[0].Member != a || top[0].Rate <= 0 {
+		t.Errorf("got owner %v at %v/s, expected %v and a rate", top[0].Member, top[0].Rate, a)
+	}
+	if x.TopKeys(0) != nil || x.TopKeys(-1) != nil {
+		t.Error("expected no hot keys for n <= 0")
+	}
+
+	if New().TopKeys(1) != nil {
+		t.Error("expected no hot keys by default")
+	}
+}
+
+func TestTopKeysRules(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New(WithHotKeys(10, time.Hour))
+	x.Add(a)
+	x.Add(b)
+	other := a
+	if e, _ := x.Get("hot"); e == a {
+		other = b
+	}
+	x.SetRules([]Rule{{Name: "pin", Match: Exact("hot"), Action: Redirect, Member: other}})
+	if top := x.TopKeys(1); len(top) != 1 || top[0].Member != other {
+		t.Errorf("got %+v, expected hot now owned by %v", top, other)
+	}
+}
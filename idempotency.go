@@ -0,0 +1,113 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// tokenPrefix starts the line protocol comment that carries an idempotency
+// token in front of a payload.
+const tokenPrefix = "# idempotency-key="
+
+// NewIdempotencyToken returns a random token for WithIdempotencyToken.
+func NewIdempotencyToken() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithIdempotencyToken returns p preceded by a line protocol comment carrying
+// token.  Backends ignore comments, and receivers that care can strip the
+// token with SplitIdempotencyToken to recognize retried or replayed payloads.
+func WithIdempotencyToken(token string, p []byte) []byte {
+	b := make([]byte, 0, len(tokenPrefix)+len(token)+1+len(p))
+	b = append(b, tokenPrefix...)
+	b = append(b, token...)
+	b = append(b, '\n')
+	return append(b, p...)
+}
+
+// SplitIdempotencyToken returns the token added by WithIdempotencyToken and
+// the rest of p.  If p carries no token it returns "" and p.
+func SplitIdempotencyToken(p []byte) (token string, payload []byte) {
+	if !bytes.HasPrefix(p, []byte(tokenPrefix)) {
+		return "", p
+	}
+	i := bytes.IndexByte(p, '\n')
+	if i < 0 {
+		return string(p[len(tokenPrefix):]), nil
+	}
+	return string(p[len(tokenPrefix):i]), p[i+1:]
+}
+
+// WriteReplicas writes p to the first n members GetN returns for key.  Every
+// copy carries the same idempotency token, so replays of the payload can be
+// deduplicated downstream.  It returns the token and the joined errors of the
+// replicas that failed.
+func (c *Consistent) WriteReplicas(key string, p []byte, n int) (string, error) {
+	defer c.exit(c.enter())
+	replicas, err := c.GetN(key, n)
+	if err != nil {
+		return "", err
+	}
+	token := NewIdempotencyToken()
+	b := WithIdempotencyToken(token, p)
+	var errs []error
+	for _, e := range replicas {
+		start := time.Now()
+		_, err := c.write(e, b)
+		c.recordWrite(e, err, time.Since(start))
+		if err != nil {
+			c.rlock()
+			errs = append(errs, c.opError("write", key, e, err))
+			c.runlock()
+		}
+	}
+	return token, errors.Join(errs...)
+}
+
+// Deduper remembers the most recent idempotency tokens it has seen, for
+// receivers that must not apply a payload twice.
+type Deduper struct {
+	mu    sync.Mutex
+	seen  map[string]bool
+	order []string
+	next  int
+}
+
+// NewDeduper creates a Deduper remembering up to size tokens.
+func NewDeduper(size int) *Deduper {
+	if size < 1 {
+		size = 1
+	}
+	return &Deduper{seen: make(map[string]bool, size), order: make([]string, size)}
+}
+
+// Check strips the idempotency token from p and reports whether the token was
+// seen before.  Payloads without a token are never duplicates.
+func (d *Deduper) Check(p []byte) (payload []byte, duplicate bool) {
+	token, payload := SplitIdempotencyToken(p)
+	if token == "" {
+		return payload, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen[token] {
+		return payload, true
+	}
+	if old := d.order[d.next]; old != "" {
+		delete(d.seen, old)
+	}
+	d.order[d.next] = token
+	d.next = (d.next + 1) % len(d.order)
+	d.seen[token] = true
+	return payload, false
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "testing"
+
+func TestWriteReplicasTokens(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	token, err := x.WriteReplicas("ggg", []byte("cpu value=1\n"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDeduper(2)
+	for i, m := range []*member{a, b} {
+		got, payload := SplitIdempotencyToken([]byte(m.String()))
+		if got != token || string(payload) != "cpu value=1\n" {
+			t.Errorf("%s: got token %q payload %q", m.name, got, payload)
+		}
+		if _, dup := d.Check([]byte(m.String())); dup != (i == 1) {
+			t.Errorf("%s: got duplicate %v", m.name, dup)
+		}
+	}
+}
+
+func TestDeduperWindow(t *testing.T) {
+	d := NewDeduper(2)
+	p1 := WithIdempotencyToken("1", nil)
+	d.Check(p1)
+	d.Check(WithIdempotencyToken("2", nil))
+	d.Check(WithIdempotencyToken("3", nil))
+	if _, dup := d.Check(p1); dup {
+		t.Errorf("expected token 1 to have left the window")
+	}
+	if payload, dup := d.Check([]byte("cpu value=1\n")); dup || string(payload) != "cpu value=1\n" {
+		t.Errorf("expected payload without token to pass through")
+	}
+}
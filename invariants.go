@@ -0,0 +1,143 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrInvariant is wrapped by the errors CheckInvariants returns.
+var ErrInvariant = errors.New("invariant violated")
+
+func violated(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvariant}, args...)...)
+}
+
+// CheckInvariants verifies the internal consistency of the hash: the sorted
+// points match the circle, every point belongs to a member, every member
+// has its points and runtime state, overrides are ordered, disjoint and point
+// at members, and a key routes to a member whenever one is up.  It is meant
+// for tests and fuzz targets and is not cheap.
+func (c *Consistent) CheckInvariants() error {
+	c.rlock()
+	defer c.runlock()
+	if len(c.sortedHashes) != len(c.circle) {
+		return violated("%d sorted points for %d on the circle", len(c.sortedHashes), len(c.circle))
+	}
+	for i, h := range c.sortedHashes {
+		if i > 0 && c.sortedHashes[i-1] >= h {
+			return violated("points %d and %d out of order", c.sortedHashes[i-1], h)
+		}
+		e, ok := c.circle[h]
+		if !ok {
+			return violated("sorted point %d missing from the circle", h)
+		}
+		if !c.members[e] {
+			return violated("point %d owned by %q, not a member", h, e.Name())
+		}
+	}
+	if int64(len(c.members)) != c.count {
+		return violated("%d members counted as %d", len(c.members), c.count)
+	}
+	for k := range c.members {
+		if _, ok := c.vnodes[k]; !ok {
+			return violated("member %q has no points", k.Name())
+		}
+		if _, ok := c.state[k]; !ok {
+			return violated("member %q has no state", k.Name())
+		}
+		for _, h := range c.vnodes[k] {
+			if _, ok := c.circle[h]; !ok {
+				return violated("point %d of %q missing from the circle", h, k.Name())
+			}
+		}
+	}
+	if len(c.vnodes) != len(c.members) || len(c.state) != len(c.members) {
+		return violated("points or state kept for %d and %d members, expected %d", len(c.vnodes), len(c.state), len(c.members))
+	}
+	for i, o := range c.overrides {
+		if o.Start > o.End || (i > 0 && c.overrides[i-1].End >= o.Start) {
+			return violated("override [%d, %d] out of order or overlapping", o.Start, o.End)
+		}
+		if !c.members[o.Member] {
+			return violated("override [%d, %d] for %q, not a member", o.Start, o.End, o.Member.Name())
+		}
+	}
+	if c.active() > 0 && len(c.capacities) == 0 {
+		for i := 0; i < 16; i++ {
+			if _, _, err := c.get(c.keyHash(strconv.Itoa(i))); err != nil {
+				return violated("key %d routed nowhere with members up: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NopMember returns a member named name that discards everything written to
+// it, for tests and fuzz targets that only need placement decisions.
+func NopMember(name string) lineProtocol.WriteCloser {
+	return simMember(name)
+}
+
+// fuzzMembers is the pool of members RunOps draws from.
+var fuzzMembers = func() []lineProtocol.WriteCloser {
+	m := make([]lineProtocol.WriteCloser, 8)
+	for i := range m {
+		m[i] = NopMember("member-" + strconv.Itoa(i))
+	}
+	return m
+}()
+
+// RunOps decodes data into a sequence of Add, Remove, Set, MarkDown, MarkUp,
+// AssignRange and Get calls over a fixed pool of eight members, applies them
+// to a new hash and runs CheckInvariants after each.  It returns the hash and
+// the first violation found.  The same data always makes the same calls, so a
+// fuzz target can simply be
+//
+//	f.Fuzz(func(t *testing.T, data []byte) {
+//		if _, err := consistent.RunOps(data); err != nil {
+//			t.Fatal(err)
+//		}
+//	})
+func RunOps(data []byte) (*Consistent, error) {
+	c := New()
+	c.NumberOfReplicas = 4
+	for len(data) >= 2 {
+		op, arg := data[0], data[1]
+		data = data[2:]
+		e := fuzzMembers[int(arg)%len(fuzzMembers)]
+		switch op % 7 {
+		case 0:
+			c.Add(e)
+		case 1:
+			c.Remove(e)
+		case 2:
+			var set []lineProtocol.WriteCloser
+			for i, m := range fuzzMembers {
+				if arg&(1<<uint(i)) != 0 {
+					set = append(set, m)
+				}
+			}
+			c.Set(set)
+		case 3:
+			c.MarkDown(e)
+		case 4:
+			c.MarkUp(e)
+		case 5:
+			start := uint32(arg) << 24
+			c.AssignRange(start, start+1<<20, e)
+		case 6:
+			c.Get(string(data))
+		}
+		if err := c.CheckInvariants(); err != nil {
+			return c, fmt.Errorf("after op %d(%d): %w", op%7, arg, err)
+		}
+	}
+	return c, nil
+}
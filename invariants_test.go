@@ -0,0 +1,35 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	x := New()
+	x.Add(NopMember("abcdefg"))
+	x.Add(NopMember("hijklmn"))
+	if err := x.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	x.sortedHashes[0], x.sortedHashes[1] = x.sortedHashes[1], x.sortedHashes[0]
+	if err := x.CheckInvariants(); !errors.Is(err, ErrInvariant) {
+		t.Errorf("got %v, expected ErrInvariant", err)
+	}
+}
+
+func FuzzRunOps(f *testing.F) {
+	f.Add([]byte{0, 1, 0, 2, 0, 3, 3, 1, 6, 0, 1, 2, 4, 1})
+	f.Add([]byte{2, 0xff, 5, 3, 2, 0x0f, 1, 0, 1, 1})
+	f.Add([]byte{1, 48})            // remove a member that was never added
+	f.Add([]byte{0, 1, 0, 1, 0, 1}) // add the same member twice
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if _, err := RunOps(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
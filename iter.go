@@ -0,0 +1,75 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"iter"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// The iterators below read-lock the ring while they run, like Walk, so the
+// body of the range loop must not modify it.  Breaking out of the loop
+// releases the lock.
+
+// Members2 iterates over the members of the hash in no particular order,
+// without copying them into a slice as Members does.
+func (c *Consistent) Members2() iter.Seq[lineProtocol.WriteCloser] {
+	return func(yield func(lineProtocol.WriteCloser) bool) {
+		c.rlock()
+		defer c.runlock()
+		for k := range c.members {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Ring iterates over the points of the circle in ascending order, with the
+// member holding each.
+func (c *Consistent) Ring() iter.Seq2[uint32, lineProtocol.WriteCloser] {
+	return func(yield func(uint32, lineProtocol.WriteCloser) bool) {
+		c.rlock()
+		defer c.runlock()
+		for _, h := range c.sortedHashes {
+			if !yield(h, c.circle[h]) {
+				return
+			}
+		}
+	}
+}
+
+// Closest iterates over the distinct members of the circle in ring order
+// from where key hashes to, the member Get would pick first when nothing is
+// down, full or overridden.  Stopping early costs nothing, which makes it
+// cheaper than GetN for a caller that wants the first few members passing a
+// test of its own.
+func (c *Consistent) Closest(key string) iter.Seq[lineProtocol.WriteCloser] {
+	return func(yield func(lineProtocol.WriteCloser) bool) {
+		c.rlock()
+		defer c.runlock()
+		if len(c.sortedHashes) == 0 {
+			return
+		}
+		start := c.search(c.keyHash(key))
+		first := c.circle[c.sortedHashes[start]]
+		if !yield(first) {
+			return
+		}
+		seen := []lineProtocol.WriteCloser{first}
+		c.walkRuns(start, func(i int) bool {
+			if len(seen) == len(c.members) {
+				return false
+			}
+			e := c.circle[c.sortedHashes[i]]
+			if sliceContainsMember(seen, e) {
+				return true
+			}
+			seen = append(seen, e)
+			return yield(e)
+		})
+	}
+}
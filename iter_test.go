@@ -0,0 +1,59 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestIterators(t *testing.T) {
+	x := New()
+	for i := 0; i < 5; i++ {
+		x.Add(newMember(fmt.Sprintf("m%d", i)))
+	}
+
+	n := 0
+	for range x.Members2() {
+		n++
+	}
+	checkNum(n, 5, t)
+
+	var prev uint32
+	points := 0
+	for h, e := range x.Ring() {
+		if points > 0 && h <= prev {
+			t.Fatalf("point %d after %d", h, prev)
+		}
+		if e == nil {
+			t.Fatalf("no member at %d", h)
+		}
+		prev = h
+		points++
+	}
+	checkNum(points, 100, t)
+
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprintf("key%d", i)
+		want, _ := x.GetN(k, 5)
+		var got []lineProtocol.WriteCloser
+		for e := range x.Closest(k) {
+			got = append(got, e)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Closest(%s) = %v, GetN = %v", k, got, want)
+		}
+		for e := range x.Closest(k) {
+			if e != want[0] {
+				t.Fatalf("Closest(%s) started at %v", k, e)
+			}
+			break
+		}
+	}
+	// the lock is released after breaking out
+	x.Add(newMember("m5"))
+}
@@ -0,0 +1,201 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// subBucketBits sets the precision of the latency histograms: every power of
+// two is split into 1<<subBucketBits buckets, for a relative error below 7%.
+const subBucketBits = 4
+
+const (
+	subBuckets = 1 << subBucketBits
+	numBuckets = (64 - subBucketBits) * subBuckets
+)
+
+// histogram is a log-linear (HDR style) histogram of durations that can be
+// recorded into concurrently without locking.
+type histogram struct {
+	count   atomic.Int64
+	sum     atomic.Int64
+	buckets [numBuckets]atomic.Int64
+}
+
+func bucketOf(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	e := bits.Len64(v) - subBucketBits - 1
+	return (e+1)*subBuckets + int(v>>uint(e)) - subBuckets
+}
+
+// bucketLimit returns the largest value recorded into bucket i.
+func bucketLimit(i int) uint64 {
+	if i < subBuckets {
+		return uint64(i)
+	}
+	e := uint(i/subBuckets - 1)
+	m := uint64(i%subBuckets + subBuckets)
+	return (m+1)<<e - 1
+}
+
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+	h.buckets[bucketOf(uint64(d))].Add(1)
+}
+
+func (h *histogram) snapshot() LatencyHistogram {
+	s := LatencyHistogram{Count: h.count.Load(), Sum: time.Duration(h.sum.Load())}
+	for i := range h.buckets {
+		if n := h.buckets[i].Load(); n > 0 {
+			s.Buckets = append(s.Buckets, LatencyBucket{Le: time.Duration(bucketLimit(i)), Count: n})
+		}
+	}
+	return s
+}
+
+// LatencyBucket is one non-empty bucket of a LatencyHistogram.
+type LatencyBucket struct {
+	Le    time.Duration // largest duration counted in the bucket
+	Count int64         // durations counted in the bucket alone
+}
+
+// LatencyHistogram is a copy of one of the latency histograms kept by a hash
+// created WithLatencyHistograms.  Buckets are ordered by Le.
+type LatencyHistogram struct {
+	Count   int64
+	Sum     time.Duration
+	Buckets []LatencyBucket
+}
+
+// Quantile returns the upper bound of the bucket holding the q-th quantile, 0
+// <= q <= 1, or 0 if nothing was recorded.
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int64(q*float64(h.Count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for _, b := range h.Buckets {
+		seen += b.Count
+		if seen >= rank {
+			return b.Le
+		}
+	}
+	return h.Buckets[len(h.Buckets)-1].Le
+}
+
+// LatencyStats holds the routing latency histograms of a hash.  Get and GetN
+// time the whole call; LockWait and Search split every routing call into the
+// time spent waiting for the ring lock and the time spent hashing and
+// searching while holding it, so contention shows up on its own.
+type LatencyStats struct {
+	Get      LatencyHistogram
+	GetN     LatencyHistogram
+	LockWait LatencyHistogram
+	Search   LatencyHistogram
+}
+
+type latency struct {
+	get, getN, lockWait, search histogram
+}
+
+// WithLatencyHistograms makes Get and GetN record their latency into
+// histograms reported in Stats.  Without it, routing calls do not read the
+// clock on their behalf.
+func WithLatencyHistograms() Option {
+	return func(c *Consistent) {
+		c.latency = new(latency)
+	}
+}
+
+// rlockTimed takes the read lock, recording how long that took, and returns
+// the time it was acquired.
+func (c *Consistent) rlockTimed() time.Time {
+	if c.latency == nil {
+		c.rlock()
+		return time.Time{}
+	}
+	start := time.Now()
+	c.rlock()
+	now := time.Now()
+	c.latency.lockWait.record(now.Sub(start))
+	return now
+}
+
+// searched records the time spent holding the read lock since locked.
+func (c *Consistent) searched(locked time.Time) {
+	if c.latency != nil {
+		c.latency.search.record(time.Since(locked))
+	}
+}
+
+// need c.rlock() before calling
+func (c *Consistent) latencyStats() LatencyStats {
+	if c.latency == nil {
+		return LatencyStats{}
+	}
+	return LatencyStats{
+		Get:      c.latency.get.snapshot(),
+		GetN:     c.latency.getN.snapshot(),
+		LockWait: c.latency.lockWait.snapshot(),
+		Search:   c.latency.search.snapshot(),
+	}
+}
+
+func (h *histogram) reset() {
+	h.count.Store(0)
+	h.sum.Store(0)
+	for i := range h.buckets {
+		h.buckets[i].Store(0)
+	}
+}
+
+func (l *latency) reset() {
+	l.get.reset()
+	l.getN.reset()
+	l.lockWait.reset()
+	l.search.reset()
+}
+
+// Sub returns the durations recorded into h since prev, an earlier copy of
+// the same histogram.
+func (h LatencyHistogram) Sub(prev LatencyHistogram) LatencyHistogram {
+	d := LatencyHistogram{Count: h.Count - prev.Count, Sum: h.Sum - prev.Sum}
+	j := 0
+	for _, b := range h.Buckets {
+		for j < len(prev.Buckets) && prev.Buckets[j].Le < b.Le {
+			j++
+		}
+		if j < len(prev.Buckets) && prev.Buckets[j].Le == b.Le {
+			b.Count -= prev.Buckets[j].Count
+		}
+		if b.Count > 0 {
+			d.Buckets = append(d.Buckets, b)
+		}
+	}
+	return d
+}
+
+// Sub returns the latencies recorded since prev.
+func (s LatencyStats) Sub(prev LatencyStats) LatencyStats {
+	return LatencyStats{
+		Get:      s.Get.Sub(prev.Get),
+		GetN:     s.GetN.Sub(prev.GetN),
+		LockWait: s.LockWait.Sub(prev.LockWait),
+		Search:   s.Search.Sub(prev.Search),
+	}
+}
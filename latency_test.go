@@ -0,0 +1,50 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	for _, v := range []uint64{0, 1, 15, 16, 17, 31, 32, 1000, 123456789, 1<<63 + 5} {
+		i := bucketOf(v)
+		if v > bucketLimit(i) || (i > 0 && v <= bucketLimit(i-1)) {
+			t.Errorf("%d went to bucket %d covering (%d, %d]", v, i, bucketLimit(i-1), bucketLimit(i))
+		}
+	}
+}
+
+func TestLatencyQuantile(t *testing.T) {
+	var h histogram
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Microsecond)
+	}
+	s := h.snapshot()
+	checkNum(int(s.Count), 100, t)
+	p50 := s.Quantile(0.5)
+	if p50 < 50*time.Microsecond || p50 > 54*time.Microsecond {
+		t.Errorf("got p50 %v, expected about 50µs", p50)
+	}
+}
+
+func TestLatencyHistograms(t *testing.T) {
+	x := New(WithLatencyHistograms())
+	x.Add(newMember("abcdefg"))
+	for i := 0; i < 10; i++ {
+		x.Get("foo")
+	}
+	x.GetN("foo", 1)
+	l := x.Stats().Latency
+	checkNum(int(l.Get.Count), 10, t)
+	checkNum(int(l.GetN.Count), 1, t)
+	checkNum(int(l.LockWait.Count), 11, t)
+	checkNum(int(l.Search.Count), 11, t)
+
+	if l := New().Stats().Latency; l.Get.Count != 0 {
+		t.Errorf("got %d, expected no latency recorded by default", l.Get.Count)
+	}
+}
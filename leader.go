@@ -0,0 +1,56 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrNotLeader is returned for a change to the members or overrides of a hash
+// created WithLeader while the process does not hold the lease.
+var ErrNotLeader = errors.New("not the leader")
+
+// WithLeader makes every change to the members or overrides of the hash
+// depend on isLeader, so only one of several proxies sharing a topology can
+// edit it.  Changes made while isLeader returns false are refused: the
+// methods returning an error return ErrNotLeader and the others do nothing.
+// Refusals are counted in Stats.  Followers take the leader's topology
+// through Replicate, which is not gated.
+func WithLeader(isLeader func() bool) Option {
+	return func(c *Consistent) {
+		c.isLeader = isLeader
+	}
+}
+
+// allowMutation reports whether the topology may be changed, counting a
+// refusal if not.  Changes to a closed hash are refused without counting.
+// need c.lock() before calling
+func (c *Consistent) allowMutation() bool {
+	if c.closed {
+		return false
+	}
+	if c.isLeader == nil || c.isLeader() {
+		return true
+	}
+	c.stats.Refused++
+	return false
+}
+
+// refusal returns the error for a change allowMutation refused.
+// need c.lock() before calling
+func (c *Consistent) refusal() error {
+	if c.closed {
+		return ErrClosed
+	}
+	return ErrNotLeader
+}
+
+// Replicate is Restore for followers: it applies a snapshot taken from the
+// leader whether or not this process holds the lease.
+func (c *Consistent) Replicate(s Snapshot, lookup func(name string) (lineProtocol.WriteCloser, error)) error {
+	return c.restore(s, lookup, true)
+}
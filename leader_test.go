@@ -0,0 +1,42 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLeaderGatesMutations(t *testing.T) {
+	var leader atomic.Bool
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New(WithLeader(leader.Load))
+	x.Add(a)
+	checkNum(len(x.Members()), 0, t)
+	if err := x.AssignRange(0, 10, a); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("got %v, expected ErrNotLeader", err)
+	}
+	if err := x.SetCtx(context.Background(), nil); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("got %v, expected ErrNotLeader", err)
+	}
+
+	leader.Store(true)
+	x.Add(a)
+	x.Add(b)
+	checkNum(len(x.Members()), 2, t)
+
+	// a follower takes the leader's topology only through Replicate
+	y := New(WithLeader(func() bool { return false }))
+	if err := y.Restore(x.Snapshot(), lookupIn(a, b)); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("got %v, expected ErrNotLeader", err)
+	}
+	if err := y.Replicate(x.Snapshot(), lookupIn(a, b)); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(y.Members()), 2, t)
+	checkNum(int(y.Stats().Refused), 1, t)
+}
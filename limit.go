@@ -0,0 +1,116 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"math"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrQueueFull is the error returned by Write when a member has no write slot
+// free, its queue is full and the QueuePolicy is QueueError or no replica can
+// take the write.
+var ErrQueueFull = errors.New("write queue full")
+
+// QueuePolicy says what Write does when a member's write queue is full.
+type QueuePolicy int
+
+const (
+	// QueueBlock waits for a slot regardless of the queue length.
+	QueueBlock QueuePolicy = iota
+	// QueueSpill sends the write to the first of the key's other replicas,
+	// in GetN order, that has a free slot or room in its queue.
+	QueueSpill
+	// QueueError fails the write with ErrQueueFull.
+	QueueError
+)
+
+// WithConcurrencyLimit bounds the writes in flight through Write to limit per
+// member, with up to queue more waiting for a slot.  Writes beyond that are
+// handled according to policy, so one slow backend cannot tie up every
+// goroutine of the proxy.
+func WithConcurrencyLimit(limit, queue int, policy QueuePolicy) Option {
+	return func(c *Consistent) {
+		c.concurrency = limit
+		c.queue = int64(queue)
+		c.queuePolicy = policy
+	}
+}
+
+func noRelease() {}
+
+// acquire takes a write slot on e, or on one of key's other replicas if e's
+// queue is full and the policy allows it.  It returns the member to write to
+// and a func that gives the slot back.
+func (c *Consistent) acquire(key string, e lineProtocol.WriteCloser) (lineProtocol.WriteCloser, func(), error) {
+	c.rlock()
+	st, ok := c.state[e]
+	c.runlock()
+	if !ok || st.sem == nil {
+		return e, noRelease, nil
+	}
+	if st.tryAcquire() || st.wait(c.queue) {
+		return e, st.release, nil
+	}
+	switch c.queuePolicy {
+	case QueueBlock:
+		st.sem <- struct{}{}
+		return e, st.release, nil
+	case QueueSpill:
+		replicas, err := c.GetN(key, math.MaxInt32)
+		if err != nil {
+			return nil, nil, err
+		}
+		type candidate struct {
+			element lineProtocol.WriteCloser
+			st      *memberState
+		}
+		var candidates []candidate
+		c.rlock()
+		for _, r := range replicas {
+			if rst, ok := c.state[r]; ok && r != e && !rst.down && rst.sem != nil {
+				candidates = append(candidates, candidate{r, rst})
+			}
+		}
+		c.runlock()
+		for _, r := range candidates {
+			if r.st.tryAcquire() {
+				return r.element, r.st.release, nil
+			}
+		}
+		for _, r := range candidates {
+			if r.st.wait(c.queue) {
+				return r.element, r.st.release, nil
+			}
+		}
+	}
+	c.rlock()
+	defer c.runlock()
+	return nil, nil, c.opError("write", key, e, ErrQueueFull)
+}
+
+func (st *memberState) tryAcquire() bool {
+	select {
+	case st.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait blocks for a slot if fewer than queue writes are already waiting.
+func (st *memberState) wait(queue int64) bool {
+	if st.waiting.Add(1) > queue {
+		st.waiting.Add(-1)
+		return false
+	}
+	st.sem <- struct{}{}
+	st.waiting.Add(-1)
+	return true
+}
+
+func (st *memberState) release() { <-st.sem }
@@ -0,0 +1,79 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+// blockingMember holds every Write until release is closed.
+type blockingMember struct {
+	*member
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newBlockingMember(name string) *blockingMember {
+	return &blockingMember{member: newMember(name), entered: make(chan struct{}, 16), release: make(chan struct{})}
+}
+
+func (b *blockingMember) Write(p []byte) (int, error) {
+	b.entered <- struct{}{}
+	<-b.release
+	return b.member.Write(p)
+}
+
+func TestConcurrencyLimitError(t *testing.T) {
+	a := newBlockingMember("abcdefg")
+	x := New(WithConcurrencyLimit(1, 0, QueueError))
+	x.Add(a)
+	done := make(chan error)
+	go func() {
+		_, err := x.Write("ggg", []byte("x"))
+		done <- err
+	}()
+	<-a.entered
+	if _, err := x.Write("ggg", []byte("y")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("got %v, expected queue full error", err)
+	}
+	close(a.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrencyLimitSpill(t *testing.T) {
+	a, b := newBlockingMember("abcdefg"), newMember("hijklmn")
+	x := New(WithConcurrencyLimit(1, 0, QueueSpill))
+	x.Add(a)
+	x.Add(b)
+	key := ""
+	for _, k := range []string{"ggg", "hhh", "iiiii", "jjj", "kkk"} {
+		if owner, _ := x.Get(k); owner == a {
+			key = k
+			break
+		}
+	}
+	if key == "" {
+		t.Fatal("no test key lands on abcdefg")
+	}
+	done := make(chan error)
+	go func() {
+		_, err := x.Write(key, []byte("x"))
+		done <- err
+	}()
+	<-a.entered
+	if _, err := x.Write(key, []byte("y")); err != nil {
+		t.Errorf("expected spill to replica, got %v", err)
+	}
+	if b.String() != "y" {
+		t.Errorf("expected hijklmn to take the spilled write, got %q", b.String())
+	}
+	close(a.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
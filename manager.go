@@ -0,0 +1,211 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"hash/crc32"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DefaultStripes is the number of lock stripes used by NewManager when
+// stripes <= 0.
+const DefaultStripes = 32
+
+// Manager holds a separate Consistent for each tenant.
+//
+// Tenants are spread over a fixed number of stripes, each guarded by its own
+// lock, and every tenant ring keeps its own lock as well, so membership
+// updates for different tenants never contend on one mutex.
+//
+// With thousands of mostly idle tenants, ReleaseIdle bounds memory by
+// dropping the points of rings that have not been used for a while, keeping
+// only their member lists.  A released ring materializes its points again,
+// unchanged, the first time it is used.
+type Manager struct {
+	// Clock tells the time of last use and drives StartReleasing, and is
+	// given to the tenant rings.  nil is RealClock.  Set it before the first
+	// call.
+	Clock Clock
+
+	stripes []stripe
+}
+
+type stripe struct {
+	sync.RWMutex
+	rings map[string]*tenantRing
+}
+
+type tenantRing struct {
+	c    *Consistent
+	used atomic.Int64 // unix nanoseconds of the last use through the Manager
+}
+
+func (t *tenantRing) touch(clk Clock) *Consistent {
+	t.used.Store(clockOr(clk).Now().UnixNano())
+	return t.c
+}
+
+// NewManager creates a Manager with the given number of lock stripes.
+func NewManager(stripes int) *Manager {
+	if stripes <= 0 {
+		stripes = DefaultStripes
+	}
+	m := &Manager{stripes: make([]stripe, stripes)}
+	for i := range m.stripes {
+		m.stripes[i].rings = make(map[string]*tenantRing)
+	}
+	return m
+}
+
+func (m *Manager) stripe(tenant string) *stripe {
+	return &m.stripes[crc32.ChecksumIEEE([]byte(tenant))%uint32(len(m.stripes))]
+}
+
+// Ring returns the ring for tenant, creating an empty one if needed.
+func (m *Manager) Ring(tenant string) *Consistent {
+	s := m.stripe(tenant)
+	s.RLock()
+	t, ok := s.rings[tenant]
+	s.RUnlock()
+	if ok {
+		return t.touch(m.Clock)
+	}
+	s.Lock()
+	defer s.Unlock()
+	if t, ok = s.rings[tenant]; !ok {
+		t = &tenantRing{c: New(WithClock(m.Clock))}
+		s.rings[tenant] = t
+	}
+	return t.touch(m.Clock)
+}
+
+// Lookup returns the ring for tenant if it exists.
+func (m *Manager) Lookup(tenant string) (*Consistent, bool) {
+	s := m.stripe(tenant)
+	s.RLock()
+	defer s.RUnlock()
+	t, ok := s.rings[tenant]
+	if !ok {
+		return nil, false
+	}
+	return t.touch(m.Clock), true
+}
+
+// Drop removes the ring for tenant.
+func (m *Manager) Drop(tenant string) {
+	s := m.stripe(tenant)
+	s.Lock()
+	defer s.Unlock()
+	delete(s.rings, tenant)
+}
+
+// Tenants returns the sorted names of all tenants with a ring.
+func (m *Manager) Tenants() []string {
+	var t []string
+	for i := range m.stripes {
+		s := &m.stripes[i]
+		s.RLock()
+		for k := range s.rings {
+			t = append(t, k)
+		}
+		s.RUnlock()
+	}
+	sort.Strings(t)
+	return t
+}
+
+// Add inserts element in the ring for tenant.
+func (m *Manager) Add(tenant string, element lineProtocol.WriteCloser) {
+	m.Ring(tenant).Add(element)
+}
+
+// Remove removes element from the ring for tenant.
+func (m *Manager) Remove(tenant string, element lineProtocol.WriteCloser) {
+	if c, ok := m.Lookup(tenant); ok {
+		c.Remove(element)
+	}
+}
+
+// Set sets all the elements in the ring for tenant.
+func (m *Manager) Set(tenant string, elements []lineProtocol.WriteCloser) {
+	m.Ring(tenant).Set(elements)
+}
+
+// Get returns the element of tenant's ring closest to where name hashes.
+// A tenant without a ring behaves like an empty circle.
+func (m *Manager) Get(tenant, name string) (lineProtocol.WriteCloser, error) {
+	c, ok := m.Lookup(tenant)
+	if !ok {
+		return nil, &Error{Op: "get", Key: name, Err: ErrEmptyCircle}
+	}
+	return c.Get(name)
+}
+
+// GetN returns the N closest distinct elements of tenant's ring to name.
+func (m *Manager) GetN(tenant, name string, n int) ([]lineProtocol.WriteCloser, error) {
+	c, ok := m.Lookup(tenant)
+	if !ok {
+		return nil, &Error{Op: "getn", Key: name, Err: ErrEmptyCircle}
+	}
+	return c.GetN(name, n)
+}
+
+// Stats returns the counters of every tenant ring, keyed by tenant.  Rings
+// released by ReleaseIdle are left out rather than materialized.
+func (m *Manager) Stats() map[string]Stats {
+	s := make(map[string]Stats)
+	for i := range m.stripes {
+		st := &m.stripes[i]
+		st.RLock()
+		for k, t := range st.rings {
+			if !t.c.dormant.Load() {
+				s[k] = t.c.Stats()
+			}
+		}
+		st.RUnlock()
+	}
+	return s
+}
+
+// ReleaseIdle releases the points of every tenant ring not used through the
+// Manager for ttl, and returns how many it released.  A ring that is
+// rebalancing, ramping a member up or closed is left alone.
+func (m *Manager) ReleaseIdle(ttl time.Duration) int {
+	cut := clockOr(m.Clock).Now().Add(-ttl).UnixNano()
+	n := 0
+	for i := range m.stripes {
+		s := &m.stripes[i]
+		s.RLock()
+		for _, t := range s.rings {
+			if t.used.Load() <= cut && !t.c.dormant.Load() && t.c.release() {
+				n++
+			}
+		}
+		s.RUnlock()
+	}
+	return n
+}
+
+// StartReleasing runs ReleaseIdle(ttl) every ttl/2 until ctx is done.
+func (m *Manager) StartReleasing(ctx context.Context, ttl time.Duration) {
+	go func() {
+		t := clockOr(m.Clock).NewTicker(ttl / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.Chan():
+				m.ReleaseIdle(ttl)
+			}
+		}
+	}()
+}
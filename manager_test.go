@@ -0,0 +1,101 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestManagerTenants(t *testing.T) {
+	m := NewManager(4)
+	a, b := newMember("a"), newMember("b")
+	m.Add("t1", a)
+	m.Add("t2", b)
+	if got, err := m.Get("t1", "key"); err != nil || got != a {
+		t.Errorf("t1: got %v, %v, expected a", got, err)
+	}
+	if got, err := m.Get("t2", "key"); err != nil || got != b {
+		t.Errorf("t2: got %v, %v, expected b", got, err)
+	}
+	if _, err := m.Get("t3", "key"); !errors.Is(err, ErrEmptyCircle) {
+		t.Errorf("expected empty circle error, got %v", err)
+	}
+	m.Drop("t1")
+	if tenants := m.Tenants(); len(tenants) != 1 || tenants[0] != "t2" {
+		t.Errorf("got tenants %v, expected [t2]", tenants)
+	}
+}
+
+func TestManagerConcurrentTenants(t *testing.T) {
+	m := NewManager(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(tenant string) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				e := newMember(strconv.Itoa(j))
+				m.Add(tenant, e)
+				m.Get(tenant, "key")
+				m.Remove(tenant, e)
+			}
+		}("tenant" + strconv.Itoa(i))
+	}
+	wg.Wait()
+	checkNum(len(m.Tenants()), 16, t)
+}
+
+func TestManagerReleaseIdle(t *testing.T) {
+	m := NewManager(4)
+	for i := 0; i < 3; i++ {
+		tenant := fmt.Sprintf("t%d", i)
+		m.Set(tenant, []lineProtocol.WriteCloser{newMember("a"), newMember("b"), newMember("c")})
+	}
+	c, _ := m.Lookup("t0")
+	c.AddTokens(newMember("pinned"), []uint32{c.HashRanges(c.Members()[0])[0].End, 12345})
+	c.SetWeight(c.Members()[0], 2)
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("key%d", i)
+		e, _ := m.Get("t0", k)
+		before[k] = e.Name()
+	}
+	epoch := c.Epoch()
+
+	time.Sleep(time.Millisecond)
+	if n := m.ReleaseIdle(0); n != 3 {
+		t.Fatalf("released %d rings, want 3", n)
+	}
+	if len(m.Stats()) != 0 {
+		t.Error("Stats materialized released rings")
+	}
+	if c.circle != nil || len(c.vnodes) >= 4 {
+		t.Errorf("t0 kept %d points and %d vnode lists", len(c.circle), len(c.vnodes))
+	}
+	for k, name := range before {
+		if e, _ := m.Get("t0", k); e.Name() != name {
+			t.Fatalf("%s moved from %s to %s", k, name, e.Name())
+		}
+	}
+	if c.Epoch() != epoch {
+		t.Error("materializing changed the epoch")
+	}
+	if len(m.Stats()) != 1 {
+		t.Errorf("got stats for %d rings, want the one in use", len(m.Stats()))
+	}
+
+	// a ring released under a caller holding it still works
+	r := m.Ring("t1")
+	m.ReleaseIdle(-time.Hour)
+	r.Add(newMember("d"))
+	checkNum(len(r.Members()), 4, t)
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Identifier is implemented by members with a stable ID distinct from their
+// name.  A member's points are derived from its ID, so a backend that is
+// renamed or moves to a new address keeps every key as long as its ID stays
+// the same.  Snapshots, token tables and hints also record members by ID.
+type Identifier interface {
+	ID() string
+}
+
+// MemberID returns the ID of element, or its name if it has no ID.
+func MemberID(element lineProtocol.WriteCloser) string {
+	if i, ok := element.(Identifier); ok {
+		if id := i.ID(); id != "" {
+			return id
+		}
+	}
+	return element.Name()
+}
+
+// Identify returns element with the stable ID id.  The result forwards Ping
+// and Encoding to element if it implements Pinger or Encoder.
+func Identify(id string, element lineProtocol.WriteCloser) lineProtocol.WriteCloser {
+	return &identified{WriteCloser: element, id: id}
+}
+
+type identified struct {
+	lineProtocol.WriteCloser
+	id string
+}
+
+func (i *identified) ID() string { return i.id }
+
+func (i *identified) Unwrap() lineProtocol.WriteCloser { return i.WriteCloser }
+
+func (i *identified) Ping(ctx context.Context) error {
+	if p, ok := i.WriteCloser.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (i *identified) Encoding() string {
+	if e, ok := i.WriteCloser.(Encoder); ok {
+		return e.Encoding()
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestMemberID(t *testing.T) {
+	old := Identify("node-2", newMember("10.0.0.2:8086"))
+	members := []lineProtocol.WriteCloser{
+		Identify("node-1", newMember("10.0.0.1:8086")),
+		old,
+		newMember("10.0.0.3:8086"),
+	}
+	x := New()
+	x.Set(members)
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("key%d", i)
+		e, _ := x.Get(k)
+		before[k] = MemberID(e)
+	}
+	s := x.Snapshot()
+
+	moved := Identify("node-2", newMember("10.0.9.9:8086"))
+	x.Remove(old)
+	x.Add(moved)
+	for k, id := range before {
+		if e, _ := x.Get(k); MemberID(e) != id {
+			t.Fatalf("%s moved from %s to %s after re-addressing", k, id, MemberID(e))
+		}
+	}
+
+	y := New()
+	members[1] = moved
+	lookup := func(id string) (lineProtocol.WriteCloser, error) {
+		for _, m := range members {
+			if MemberID(m) == id {
+				return m, nil
+			}
+		}
+		return nil, ErrUnknownMember
+	}
+	if err := y.Restore(s, lookup); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := y.GetMember("node-2"); !ok || e != moved {
+		t.Error("expected the snapshot to restore node-2 at its new address")
+	}
+	if got := MemberID(members[2]); got != "10.0.0.3:8086" {
+		t.Errorf("got %q, expected a member without ID to use its name", got)
+	}
+}
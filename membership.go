@@ -0,0 +1,137 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Tag orders the updates made to one member of a Membership.  Clock is a
+// Lamport clock; Replica breaks ties between replicas that updated the same
+// member concurrently.
+type Tag struct {
+	Clock   uint64 `json:"clock"`
+	Replica string `json:"replica"`
+}
+
+// after reports whether t is ordered after u.
+func (t Tag) after(u Tag) bool {
+	if t.Clock != u.Clock {
+		return t.Clock > u.Clock
+	}
+	return t.Replica > u.Replica
+}
+
+// MembershipEntry is the latest update seen for one member.
+type MembershipEntry struct {
+	Tag     Tag  `json:"tag"`
+	Present bool `json:"present"`
+}
+
+// MembershipState is the serializable state of a Membership, keyed by member
+// name.
+type MembershipState map[string]MembershipEntry
+
+// Membership is a set of member names that several controllers can update
+// concurrently.  It is a state-based CRDT: each member keeps its own
+// last-writer-wins register, so updates to different members never overwrite
+// one another, and merging the states of all replicas in any order and any
+// number of times gives every replica the same set.
+type Membership struct {
+	mu      sync.Mutex
+	replica string
+	clock   uint64
+	entries MembershipState
+}
+
+// NewMembership creates an empty Membership for the controller named replica.
+// Every controller must use a distinct name.
+func NewMembership(replica string) *Membership {
+	return &Membership{replica: replica, entries: make(MembershipState)}
+}
+
+// Add records that name is a member.
+func (m *Membership) Add(name string) {
+	m.update(name, true)
+}
+
+// Remove records that name is no longer a member.
+func (m *Membership) Remove(name string) {
+	m.update(name, false)
+}
+
+func (m *Membership) update(name string, present bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock++
+	m.entries[name] = MembershipEntry{Tag: Tag{Clock: m.clock, Replica: m.replica}, Present: present}
+}
+
+// Merge folds the state of another replica into m and reports whether the set
+// of members changed.
+func (m *Membership) Merge(s MembershipState) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := false
+	for name, e := range s {
+		if e.Tag.Clock > m.clock {
+			m.clock = e.Tag.Clock
+		}
+		cur, ok := m.entries[name]
+		if ok && !e.Tag.after(cur.Tag) {
+			continue
+		}
+		if cur.Present != e.Present {
+			changed = true
+		}
+		m.entries[name] = e
+	}
+	return changed
+}
+
+// State returns a copy of the state of m, to be sent to other replicas.
+func (m *Membership) State() MembershipState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := make(MembershipState, len(m.entries))
+	for k, v := range m.entries {
+		s[k] = v
+	}
+	return s
+}
+
+// Members returns the names currently in the set, sorted.
+func (m *Membership) Members() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for k, v := range m.entries {
+		if v.Present {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply makes the members of c match m, using lookup to turn names into
+// writers.  If lookup fails for any name, or c refuses the change as SetCtx
+// does, c is left unchanged and the error is returned.
+func (m *Membership) Apply(c *Consistent, lookup func(name string) (lineProtocol.WriteCloser, error)) error {
+	names := m.Members()
+	elements := make([]lineProtocol.WriteCloser, 0, len(names))
+	for _, name := range names {
+		e, err := lookup(name)
+		if err != nil {
+			return err
+		}
+		elements = append(elements, e)
+	}
+	return c.SetCtx(context.Background(), elements)
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMembershipConcurrentUpdates(t *testing.T) {
+	x, y := NewMembership("x"), NewMembership("y")
+	x.Add("abcdefg")
+	y.Merge(x.State())
+
+	// concurrent changes to different members both survive
+	x.Add("hijklmn")
+	y.Remove("abcdefg")
+	y.Add("opqrstu")
+	sx, sy := x.State(), y.State()
+	x.Merge(sy)
+	y.Merge(sx)
+	y.Merge(sx)
+
+	want := []string{"hijklmn", "opqrstu"}
+	if got := x.Members(); !reflect.DeepEqual(got, want) {
+		t.Errorf("x has %v, expected %v", got, want)
+	}
+	if got := y.Members(); !reflect.DeepEqual(got, want) {
+		t.Errorf("y has %v, expected %v", got, want)
+	}
+}
+
+func TestMembershipConflict(t *testing.T) {
+	x, y := NewMembership("x"), NewMembership("y")
+	x.Add("abcdefg")
+	y.Remove("abcdefg")
+	x.Merge(y.State())
+	y.Merge(x.State())
+	if !reflect.DeepEqual(x.Members(), y.Members()) {
+		t.Errorf("got %v and %v, expected replicas to agree", x.Members(), y.Members())
+	}
+
+	// an update made after seeing the other wins
+	x.Add("abcdefg")
+	if !y.Merge(x.State()) {
+		t.Error("expected merge to change y")
+	}
+	checkNum(len(y.Members()), 1, t)
+}
+
+func TestMembershipApply(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	m := NewMembership("x")
+	m.Add("abcdefg")
+	m.Add("hijklmn")
+	x := New()
+	if err := m.Apply(x, lookupIn(a, b)); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.Members()), 2, t)
+	m.Remove("hijklmn")
+	if err := m.Apply(x, lookupIn(a, b)); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.Members()), 1, t)
+}
+
+func TestMembershipApplyMinMembers(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	m := NewMembership("x")
+	m.Add("abcdefg")
+	x := New(WithMinMembers(2))
+	x.Add(a)
+	x.Add(b)
+	if err := m.Apply(x, lookupIn(a, b)); !errors.Is(err, ErrMinMembers) {
+		t.Fatalf("expected ErrMinMembers, got %v", err)
+	}
+	checkNum(len(x.Members()), 2, t)
+}
@@ -0,0 +1,15 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+//go:build !race
+
+package consistent
+
+// useGuard does nothing outside race builds; see race.go.
+type useGuard struct{}
+
+func (g *useGuard) lock()    {}
+func (g *useGuard) unlock()  {}
+func (g *useGuard) rlock()   {}
+func (g *useGuard) runlock() {}
@@ -0,0 +1,27 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Observer is told about every routing decision made by Get, GetTwo, GetN and
+// Locate, with the primary member chosen (nil on error) and how long the call
+// took.  OnGet runs on the caller's goroutine after the ring lock is released,
+// so it should be cheap.
+type Observer interface {
+	OnGet(key string, member lineProtocol.WriteCloser, d time.Duration)
+}
+
+// WithObserver sets the Observer of the hash.  Without one, routing calls do
+// not read the clock or allocate on its behalf.
+func WithObserver(o Observer) Option {
+	return func(c *Consistent) {
+		c.observer = o
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+type countingObserver struct {
+	keys    []string
+	members []lineProtocol.WriteCloser
+}
+
+func (o *countingObserver) OnGet(key string, member lineProtocol.WriteCloser, d time.Duration) {
+	o.keys = append(o.keys, key)
+	o.members = append(o.members, member)
+}
+
+func TestObserver(t *testing.T) {
+	o := new(countingObserver)
+	x := New(WithObserver(o))
+	x.Get("empty")
+	a := newMember("abcdefg")
+	x.Add(a)
+	x.Get("ggg")
+	x.GetN("hhh", 2)
+	x.GetTwo("iiiii")
+	checkNum(len(o.keys), 4, t)
+	if o.members[0] != nil || o.members[1] != a || o.members[2] != a || o.keys[3] != "iiiii" {
+		t.Errorf("got keys %v members %v", o.keys, o.members)
+	}
+}
+
+func TestGetWithoutObserverAllocations(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	hashing := testing.AllocsPerRun(100, func() { x.hashKey("ggg") })
+	if n := testing.AllocsPerRun(100, func() { x.Get("ggg") }); n > hashing {
+		t.Errorf("got %v allocations per Get, expected no more than hashing's %v", n, hashing)
+	}
+}
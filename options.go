@@ -0,0 +1,40 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// Option configures a Consistent created by New.
+type Option func(*Consistent)
+
+// WithoutLocking disables the internal mutex.  Use it only when every call on
+// the Consistent is already serialized by the caller, for example when a single
+// routing goroutine owns the ring.
+//
+// Binaries built with -race panic on concurrent use of an unlocked ring instead
+// of silently corrupting it.
+func WithoutLocking() Option {
+	return func(c *Consistent) {
+		c.unlocked = true
+	}
+}
+
+// WithValidator makes SetCtx call v for every element it is about to add and
+// abandon the whole update if v returns an error.
+func WithValidator(v func(lineProtocol.WriteCloser) error) Option {
+	return func(c *Consistent) {
+		c.validate = v
+	}
+}
+
+// WithMinMembers makes Remove, Set and SetCtx refuse any change that would
+// shrink the hash below n members, and MarkDown and CheckHealth refuse to leave
+// fewer than n members up, so a misbehaving health checker or discovery source
+// cannot empty it.  Refusals are counted in Stats.
+func WithMinMembers(n int) Option {
+	return func(c *Consistent) {
+		c.minMembers = n
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestWithoutLocking(t *testing.T) {
+	x := New(WithoutLocking())
+	a := newMember("abcdefg")
+	x.Add(a)
+	got, err := x.Get("key")
+	if err != nil || got != a {
+		t.Errorf("got %v, %v, expected abcdefg", got, err)
+	}
+	x.Remove(a)
+	checkNum(len(x.circle), 0, t)
+}
+
+func TestWithMinMembers(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New(WithMinMembers(2))
+	x.Add(a)
+	x.Remove(a)
+	checkNum(len(x.Members()), 1, t)
+	x.Add(b)
+	x.Remove(a)
+	checkNum(len(x.Members()), 2, t)
+	x.Set(nil)
+	checkNum(len(x.Members()), 2, t)
+	err := x.SetCtx(context.Background(), []lineProtocol.WriteCloser{c})
+	var e *Error
+	if !errors.Is(err, ErrMinMembers) || !errors.As(err, &e) || e.Op != "set" {
+		t.Errorf("got %v, expected a set min members error", err)
+	}
+	x.Set([]lineProtocol.WriteCloser{b, c})
+	checkNum(len(x.Members()), 2, t)
+	if s := x.Stats(); s.Refused != 4 {
+		t.Errorf("got %d refusals, expected 4", s.Refused)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// ScaleOut is the recommendation an OverloadDetector makes.
+type ScaleOut struct {
+	Members    int     // members with a capacity
+	Overloaded int     // members at or above the overload factor
+	LoadFactor float64 // total load over total capacity
+	Add        int     // members to add to bring LoadFactor down to the target
+}
+
+// OverloadPolicy configures an OverloadDetector.
+type OverloadPolicy struct {
+	Target   float64 // load factor to plan for, 0.7 if 0
+	Overload float64 // load factor at which a member counts as overloaded, 0.9 if 0
+	Quorum   float64 // share of members that must be overloaded, 0.5 if 0
+	Rounds   int     // consecutive overloaded checks before recommending, 3 if 0
+}
+
+// OverloadDetector watches the load of members with a capacity (see
+// SetCapacity) and, when most of them have been overloaded for several checks
+// in a row, calls a callback with the number of members to add, for example
+// to drive an autoscaler.
+type OverloadDetector struct {
+	c      *Consistent
+	p      OverloadPolicy
+	fn     func(ScaleOut)
+	streak int
+}
+
+// NewOverloadDetector creates an OverloadDetector for c calling fn.
+func NewOverloadDetector(c *Consistent, p OverloadPolicy, fn func(ScaleOut)) *OverloadDetector {
+	if p.Target == 0 {
+		p.Target = 0.7
+	}
+	if p.Overload == 0 {
+		p.Overload = 0.9
+	}
+	if p.Quorum == 0 {
+		p.Quorum = 0.5
+	}
+	if p.Rounds == 0 {
+		p.Rounds = 3
+	}
+	return &OverloadDetector{c: c, p: p, fn: fn}
+}
+
+// Check runs one check, calling the callback if overload is sustained, and
+// returns the current state.  It is not safe for concurrent use.
+func (d *OverloadDetector) Check() ScaleOut {
+	var (
+		s           ScaleOut
+		load, limit float64
+	)
+	d.c.rlock()
+	for k := range d.c.members {
+		cp, ok := d.c.capacities[k]
+		if !ok || cp.limit <= 0 {
+			continue
+		}
+		l := float64(cp.load.Load())
+		s.Members++
+		load += l
+		limit += float64(cp.limit)
+		if l/float64(cp.limit) >= d.p.Overload {
+			s.Overloaded++
+		}
+	}
+	d.c.runlock()
+	if s.Members == 0 {
+		d.streak = 0
+		return s
+	}
+	s.LoadFactor = load / limit
+	perMember := limit / float64(s.Members)
+	if need := int(math.Ceil(load / (d.p.Target * perMember))); need > s.Members {
+		s.Add = need - s.Members
+	}
+	if float64(s.Overloaded)/float64(s.Members) < d.p.Quorum {
+		d.streak = 0
+		return s
+	}
+	if d.streak++; d.streak >= d.p.Rounds {
+		d.streak = 0
+		d.fn(s)
+	}
+	return s
+}
+
+// Start runs Check every interval until ctx is done.
+func (d *OverloadDetector) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := d.c.clock.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.Chan():
+				d.Check()
+			}
+		}
+	}()
+}
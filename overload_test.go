@@ -0,0 +1,35 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "testing"
+
+func TestOverloadDetector(t *testing.T) {
+	x := New()
+	ms := []*member{newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu"), newMember("vwxyz")}
+	for _, m := range ms {
+		x.Add(m)
+		x.SetCapacity(m, 100)
+	}
+	var got []ScaleOut
+	d := NewOverloadDetector(x, OverloadPolicy{Rounds: 2}, func(s ScaleOut) { got = append(got, s) })
+
+	x.AddLoad(ms[0], 95)
+	d.Check()
+	d.Check()
+	checkNum(len(got), 0, t)
+
+	for _, m := range ms[1:] {
+		x.AddLoad(m, 95)
+	}
+	d.Check()
+	checkNum(len(got), 0, t)
+	d.Check()
+	checkNum(len(got), 1, t)
+	// 380 units at 70 per member need 6 members
+	if got[0].Add != 2 || got[0].Overloaded != 4 {
+		t.Errorf("got %+v, expected 4 overloaded and 2 to add", got[0])
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrUnknownMember is the error returned when an operation names an element
+// that has not been added to the hash.
+var ErrUnknownMember = errors.New("unknown member")
+
+// ErrInvalidRange is the error returned by AssignRange when start > end.
+var ErrInvalidRange = errors.New("invalid hash range")
+
+// ErrOverlappingRange is the error returned by AssignRange when the range
+// overlaps an existing override.
+var ErrOverlappingRange = errors.New("overlapping hash range")
+
+// Override pins every key hashing into [Start, End] to Member, regardless of
+// where the circle would place it.
+type Override struct {
+	Start  uint32
+	End    uint32
+	Member lineProtocol.WriteCloser
+}
+
+// AssignRange makes element the owner of all keys hashing into [start, end].
+// Overrides take precedence over the circle in Get, GetTwo and GetN and are
+// dropped when their member is removed.  A range that wraps past 0 has to be
+// assigned as two ranges.
+func (c *Consistent) AssignRange(start, end uint32, element lineProtocol.WriteCloser) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("assignrange", "", element, c.refusal())
+	}
+	if start > end {
+		return c.opError("assignrange", "", element, ErrInvalidRange)
+	}
+	if _, ok := c.members[element]; !ok {
+		return c.opError("assignrange", "", element, ErrUnknownMember)
+	}
+	i := sort.Search(len(c.overrides), func(x int) bool { return c.overrides[x].Start > end })
+	if i > 0 && c.overrides[i-1].End >= start {
+		return c.opError("assignrange", "", element, ErrOverlappingRange)
+	}
+	c.overrides = append(c.overrides, Override{})
+	copy(c.overrides[i+1:], c.overrides[i:])
+	c.overrides[i] = Override{Start: start, End: end, Member: element}
+	return nil
+}
+
+// Overrides returns the current overrides ordered by Start.
+func (c *Consistent) Overrides() []Override {
+	c.rlock()
+	defer c.runlock()
+	return append([]Override(nil), c.overrides...)
+}
+
+// ClearOverrides removes all overrides.
+func (c *Consistent) ClearOverrides() {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return
+	}
+	c.overrides = nil
+}
+
+// need c.rlock() before calling
+func (c *Consistent) override(key uint32) (lineProtocol.WriteCloser, bool) {
+	i := sort.Search(len(c.overrides), func(x int) bool { return c.overrides[x].Start > key })
+	if i == 0 || c.overrides[i-1].End < key {
+		return nil, false
+	}
+	return c.overrides[i-1].Member, true
+}
+
+// need c.lock() before calling
+func (c *Consistent) removeOverrides(element lineProtocol.WriteCloser) {
+	kept := c.overrides[:0]
+	for _, o := range c.overrides {
+		if o.Member != element {
+			kept = append(kept, o)
+		}
+	}
+	c.overrides = kept
+}
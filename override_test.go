@@ -0,0 +1,58 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAssignRange(t *testing.T) {
+	x := New()
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x.Add(a)
+	x.Add(b)
+	key := x.hashKey("ggg")
+	owner, _ := x.Get("ggg")
+	other := a
+	if owner == a {
+		other = b
+	}
+	if err := x.AssignRange(key, key, other); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := x.Get("ggg"); got != other {
+		t.Errorf("got %v, expected override %v", got, other)
+	}
+	if got, _ := x.GetN("ggg", 2); got[0] != other || got[1] != owner {
+		t.Errorf("got %v, expected [%v %v]", got, other, owner)
+	}
+	if err := x.AssignRange(key-1, key+1, owner); !errors.Is(err, ErrOverlappingRange) {
+		t.Errorf("expected overlapping range error, got %v", err)
+	}
+	if err := x.AssignRange(2, 1, owner); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expected invalid range error, got %v", err)
+	}
+	if err := x.AssignRange(0, 1, newMember("nope")); !errors.Is(err, ErrUnknownMember) {
+		t.Errorf("expected unknown member error, got %v", err)
+	}
+	checkNum(len(x.Overrides()), 1, t)
+	x.ClearOverrides()
+	if got, _ := x.Get("ggg"); got != owner {
+		t.Errorf("got %v, expected %v after clearing overrides", got, owner)
+	}
+}
+
+func TestRemoveDropsOverrides(t *testing.T) {
+	x := New()
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x.Add(a)
+	x.Add(b)
+	if err := x.AssignRange(0, 1000, a); err != nil {
+		t.Fatal(err)
+	}
+	x.Remove(a)
+	checkNum(len(x.Overrides()), 0, t)
+}
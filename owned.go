@@ -0,0 +1,55 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// OwnedBy returns a predicate reporting whether Get currently routes a key to
+// element, for a backend filtering a key listing after a topology change.
+// Each call sees the hash as it is then; unlike Get it updates no counters,
+// so it is cheap to run over many keys.
+func (c *Consistent) OwnedBy(element lineProtocol.WriteCloser) func(key string) bool {
+	return func(key string) bool {
+		c.rlock()
+		defer c.runlock()
+		return c.owner(key) == element
+	}
+}
+
+// FilterOwned returns the keys Get routes to element, in their original
+// order, all judged against the same state of the hash.
+func (c *Consistent) FilterOwned(element lineProtocol.WriteCloser, keys []string) []string {
+	c.rlock()
+	defer c.runlock()
+	var owned []string
+	for _, k := range keys {
+		if c.owner(k) == element {
+			owned = append(owned, k)
+		}
+	}
+	return owned
+}
+
+// owner returns the member Get routes key to, or nil if Get would fail or
+// delegate to another ring.
+// need c.rlock() before calling
+func (c *Consistent) owner(key string) lineProtocol.WriteCloser {
+	if len(c.rules) > 0 {
+		if r, ok := c.rule(key); ok {
+			if r.Action == Redirect {
+				return r.Member
+			}
+			return nil
+		}
+	}
+	if len(c.circle) == 0 {
+		return nil
+	}
+	e, _, err := c.get(c.keyHash(key))
+	if err != nil {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFilterOwned(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	var keys []string
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("key%d", i))
+	}
+	owns := x.OwnedBy(a)
+	got := x.FilterOwned(a, keys)
+	var want []string
+	for _, k := range keys {
+		e, _ := x.Get(k)
+		if e == a {
+			want = append(want, k)
+		}
+		if owns(k) != (e == a) {
+			t.Errorf("%s: OwnedBy disagrees with Get", k)
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, expected %v", got, want)
+	}
+	x.MarkDown(a)
+	if n := len(x.FilterOwned(a, keys)); n != 0 {
+		t.Errorf("got %d keys, expected none for a member marked down", n)
+	}
+	checkNum(len(x.FilterOwned(b, keys)), len(keys), t)
+}
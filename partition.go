@@ -0,0 +1,90 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// partitionBatch is the number of keys PartitionKeys resolves per read lock.
+const partitionBatch = 1024
+
+// PartitionKeys reads newline-delimited keys from r and calls fn with every
+// key and the member Get routes it to, in input order, holding only a batch
+// of keys in memory at a time, so listings of any size can be partitioned
+// offline.  Empty lines are skipped.  member is nil for keys Get would fail
+// for or that a rule delegates to another ring.  Keys are resolved without
+// touching the routing counters, and fn runs without the ring lock held.
+//
+// It stops at the first error from r or fn and returns it with the number
+// of keys passed to fn.
+func (c *Consistent) PartitionKeys(r io.Reader, fn func(key string, member lineProtocol.WriteCloser) error) (int64, error) {
+	br := bufio.NewReader(r)
+	keys := make([]string, 0, partitionBatch)
+	members := make([]lineProtocol.WriteCloser, partitionBatch)
+	var n int64
+	flush := func() error {
+		c.rlock()
+		for i, k := range keys {
+			members[i] = c.owner(k)
+		}
+		c.runlock()
+		for i, k := range keys {
+			if err := fn(k, members[i]); err != nil {
+				return err
+			}
+			n++
+		}
+		keys = keys[:0]
+		return nil
+	}
+	for {
+		line, rerr := br.ReadBytes('\n')
+		if key := bytes.TrimRight(line, "\r\n"); len(key) > 0 {
+			keys = append(keys, string(key))
+			if len(keys) == partitionBatch {
+				if err := flush(); err != nil {
+					return n, err
+				}
+			}
+		}
+		if rerr != nil {
+			if err := flush(); err != nil {
+				return n, err
+			}
+			if rerr == io.EOF {
+				rerr = nil
+			}
+			return n, rerr
+		}
+	}
+}
+
+// PartitionKeysTo is PartitionKeys writing a line
+//
+//	<key>\t<member name>
+//
+// to w for every key, with "-" as the name of keys without a member.
+func (c *Consistent) PartitionKeysTo(r io.Reader, w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	n, err := c.PartitionKeys(r, func(key string, member lineProtocol.WriteCloser) error {
+		name := "-"
+		if member != nil {
+			name = member.Name()
+		}
+		bw.WriteString(key)
+		bw.WriteByte('\t')
+		bw.WriteString(name)
+		return bw.WriteByte('\n')
+	})
+	if ferr := bw.Flush(); err == nil {
+		err = ferr
+	}
+	return n, err
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestPartitionKeys(t *testing.T) {
+	x := New()
+	x.Set([]lineProtocol.WriteCloser{newMember("a"), newMember("b"), newMember("c")})
+	var in strings.Builder
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&in, "cpu,host=h%d\r\n", i)
+		if i%100 == 0 {
+			in.WriteString("\n")
+		}
+	}
+	in.WriteString("last")
+
+	var out strings.Builder
+	n, err := x.PartitionKeysTo(strings.NewReader(in.String()), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(int(n), 3001, t)
+	sc := bufio.NewScanner(strings.NewReader(out.String()))
+	lines := 0
+	for sc.Scan() {
+		key, name, _ := strings.Cut(sc.Text(), "\t")
+		if e, _ := x.Get(key); e.Name() != name {
+			t.Fatalf("%s assigned to %s, Get says %s", key, name, e.Name())
+		}
+		lines++
+	}
+	checkNum(lines, 3001, t)
+
+	stop := errors.New("stop")
+	n, err = x.PartitionKeys(strings.NewReader(in.String()), func(key string, _ lineProtocol.WriteCloser) error {
+		if key == "cpu,host=h1500" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 1500 {
+		t.Errorf("got %d keys, %v; want 1500, stop", n, err)
+	}
+
+	empty := New()
+	empty.PartitionKeys(strings.NewReader("k\n"), func(_ string, m lineProtocol.WriteCloser) error {
+		if m != nil {
+			t.Error("expected no member on an empty ring")
+		}
+		return nil
+	})
+	if _, err := x.PartitionKeys(io.LimitReader(errReader{}, 1), func(string, lineProtocol.WriteCloser) error { return nil }); err == nil {
+		t.Error("expected the read error")
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("broken") }
@@ -0,0 +1,130 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrInvalidPlacement is the error returned by ImportPlacement for ranges
+// that do not cover the hash space exactly once, in order.
+var ErrInvalidPlacement = errors.New("ranges do not cover the hash space")
+
+// Placement is the owner of every key hash of a hash, as compact ranges
+// covering the whole hash space in order, with overrides and exclusion
+// resolved.  It is a break-glass record for disaster recovery: a ring that
+// imports it routes every key exactly as the exporting ring did, whatever
+// the members are called or weigh now.
+type Placement struct {
+	Epoch  uint64           `json:"epoch"`
+	Ranges []PlacementRange `json:"ranges"`
+}
+
+// PlacementRange is a range of a Placement.  The range is inclusive.
+type PlacementRange struct {
+	Start  uint32 `json:"start"`
+	End    uint32 `json:"end"`
+	Member string `json:"member"` // MemberID of the owner
+}
+
+// ExportPlacement returns the placement of the hash.  Health and capacity
+// are runtime state and not part of it.
+func (c *Consistent) ExportPlacement() Placement {
+	c.rlock()
+	defer c.runlock()
+	p := Placement{Epoch: c.epoch}
+	if len(c.sortedHashes) == 0 {
+		return p
+	}
+	starts := append([]uint32{0}, c.sortedHashes...)
+	for _, o := range c.overrides {
+		starts = append(starts, o.Start)
+		if o.End < math.MaxUint32 {
+			starts = append(starts, o.End+1)
+		}
+	}
+	slices.Sort(starts)
+	starts = slices.Compact(starts)
+	for i, s := range starts {
+		end := uint32(math.MaxUint32)
+		if i+1 < len(starts) {
+			end = starts[i+1] - 1
+		}
+		m := MemberID(c.placedOwner(s))
+		if n := len(p.Ranges); n > 0 && p.Ranges[n-1].Member == m {
+			p.Ranges[n-1].End = end
+			continue
+		}
+		p.Ranges = append(p.Ranges, PlacementRange{Start: s, End: end, Member: m})
+	}
+	return p
+}
+
+// placedOwner returns the member key routes to ignoring health and
+// capacity.
+// need c.rlock() before calling
+func (c *Consistent) placedOwner(key uint32) lineProtocol.WriteCloser {
+	if e, ok := c.override(key); ok {
+		return e
+	}
+	i := c.search(key)
+	owner := c.circle[c.sortedHashes[i]]
+	for n := 0; n < len(c.sortedHashes); n++ {
+		if e := c.circle[c.sortedHashes[(i+n)%len(c.sortedHashes)]]; !c.isExcluded(e) {
+			return e
+		}
+	}
+	return owner
+}
+
+// ImportPlacement replaces the state of the hash with p, using lookup to
+// turn the member IDs in p into writers.  Every range becomes an explicit
+// token of its member, so keys land exactly where p says as long as the hash
+// uses the hasher and key routing options of the exporting one.  Weights,
+// overrides, aliases and exclusion are dropped.  Like Restore, it leaves the
+// hash unchanged on error.
+func (c *Consistent) ImportPlacement(p Placement, lookup func(name string) (lineProtocol.WriteCloser, error)) error {
+	if len(p.Ranges) == 0 || p.Ranges[0].Start != 0 || p.Ranges[len(p.Ranges)-1].End != math.MaxUint32 {
+		return &Error{Op: "import", Err: ErrInvalidPlacement}
+	}
+	for i, r := range p.Ranges {
+		if r.Start > r.End || i > 0 && r.Start != p.Ranges[i-1].End+1 {
+			return &Error{Op: "import", Err: ErrInvalidPlacement}
+		}
+	}
+	writers := make(map[string]lineProtocol.WriteCloser)
+	index := make(map[lineProtocol.WriteCloser]string)
+	s := Snapshot{Tokens: make(map[string][]uint32)}
+	for _, r := range p.Ranges {
+		e, ok := writers[r.Member]
+		if !ok {
+			var err error
+			if e, err = lookup(r.Member); err != nil {
+				return &Error{Op: "import", Member: r.Member, Err: err}
+			}
+			writers[r.Member] = e
+		}
+		// Restore looks members up by name; one named by its index here
+		// merges IDs resolving to the same writer.
+		id, ok := index[e]
+		if !ok {
+			id = strconv.Itoa(len(index))
+			index[e] = id
+			s.Members = append(s.Members, id)
+		}
+		// The keys of [Start, End] are those ahead of the point after End.
+		s.Tokens[id] = append(s.Tokens[id], r.End+1)
+	}
+	byID := make(map[string]lineProtocol.WriteCloser, len(index))
+	for e, id := range index {
+		byID[id] = e
+	}
+	return c.Restore(s, func(id string) (lineProtocol.WriteCloser, error) { return byID[id], nil })
+}
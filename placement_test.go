@@ -0,0 +1,72 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestPlacement(t *testing.T) {
+	a, b, c := newMember("a"), newMember("b"), newMember("c")
+	x := New()
+	x.Set([]lineProtocol.WriteCloser{a, b, c})
+	x.SetWeight(b, 3)
+	x.Exclude(c)
+	if err := x.AssignRange(1000, 1<<31, a); err != nil {
+		t.Fatal(err)
+	}
+	p := x.ExportPlacement()
+	if p.Ranges[0].Start != 0 || p.Ranges[len(p.Ranges)-1].End != math.MaxUint32 {
+		t.Fatalf("ranges do not cover the hash space: %+v", p.Ranges)
+	}
+	for i := 1; i < len(p.Ranges); i++ {
+		if p.Ranges[i].Start != p.Ranges[i-1].End+1 || p.Ranges[i].Member == p.Ranges[i-1].Member {
+			t.Fatalf("ranges %+v and %+v", p.Ranges[i-1], p.Ranges[i])
+		}
+	}
+
+	// the members come back under new names
+	renamed := map[string]*member{"a": newMember("a2"), "b": newMember("b2"), "c": newMember("c2")}
+	y := New()
+	y.Add(newMember("stale"))
+	err := y.ImportPlacement(p, func(id string) (lineProtocol.WriteCloser, error) {
+		if m, ok := renamed[id]; ok {
+			return m, nil
+		}
+		return nil, ErrUnknownMember
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5000; i++ {
+		k := fmt.Sprint(i)
+		want, _ := x.Get(k)
+		got, err := y.Get(k)
+		if err != nil || got != renamed[want.Name()] {
+			t.Fatalf("%s: got %v, %v, want %s", k, got, err, renamed[want.Name()].name)
+		}
+	}
+	for _, h := range []uint32{0, 999, 1000, 1 << 31, 1<<31 + 1, math.MaxUint32} {
+		x.RLock()
+		want := x.placedOwner(h)
+		x.RUnlock()
+		y.RLock()
+		got := y.circle[y.sortedHashes[y.search(h)]]
+		y.RUnlock()
+		if got != renamed[want.Name()] {
+			t.Errorf("hash %d: got %v, want %v", h, got, want)
+		}
+	}
+
+	p.Ranges[1].Start++
+	if err := y.ImportPlacement(p, nil); !errors.Is(err, ErrInvalidPlacement) {
+		t.Errorf("gap in ranges: %v", err)
+	}
+}
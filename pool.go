@@ -0,0 +1,96 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Pool is a WriteCloser spreading writes over up to size connections to one
+// member, so concurrent writes routed to the same backend do not serialize on
+// a single stream.  Connections are created with dial as needed; one whose
+// write fails is closed and replaced on a later write.  Each Write goes to a
+// single connection in full.
+type Pool struct {
+	name   string
+	dial   DialFunc
+	slots  chan struct{} // one token per connection in use
+	mu     sync.Mutex
+	idle   []lineProtocol.WriteCloser
+	closed bool
+}
+
+// NewPool creates a Pool called name with at most size connections.
+func NewPool(name string, size int, dial DialFunc) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{name: name, dial: dial, slots: make(chan struct{}, size)}
+}
+
+// Name returns the name given to NewPool.
+func (p *Pool) Name() string { return p.name }
+
+// Write writes b on an idle connection, dialing a new one if none is idle.
+// It waits while size writes are already in progress.
+func (p *Pool) Write(b []byte) (int, error) {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+	w, err := p.take()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	if err != nil {
+		w.Close()
+		return n, err
+	}
+	p.give(w)
+	return n, nil
+}
+
+func (p *Pool) take() (lineProtocol.WriteCloser, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if n := len(p.idle); n > 0 {
+		w := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return w, nil
+	}
+	p.mu.Unlock()
+	return p.dial()
+}
+
+func (p *Pool) give(w lineProtocol.WriteCloser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		w.Close()
+		return
+	}
+	p.idle = append(p.idle, w)
+}
+
+// Close closes idle connections and makes later writes fail with ErrClosed.
+// Connections busy in a Write are closed when it finishes.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var err error
+	for _, w := range p.idle {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	p.idle = nil
+	return err
+}
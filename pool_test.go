@@ -0,0 +1,57 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestPool(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		conns []*member
+	)
+	p := NewPool("abcdefg", 4, func() (lineProtocol.WriteCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		m := newMember("conn")
+		conns = append(conns, m)
+		return m, nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Write([]byte("x")); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(conns) > 4 {
+		t.Errorf("got %d connections, expected at most 4", len(conns))
+	}
+	total := 0
+	for _, c := range conns {
+		total += len(c.String())
+	}
+	checkNum(total, 100, t)
+
+	conns[0].err = errors.New("broken pipe")
+	p.Close()
+	for _, c := range conns {
+		if !c.closed {
+			t.Errorf("expected idle connections closed")
+		}
+	}
+	if _, err := p.Write([]byte("x")); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, expected closed error", err)
+	}
+}
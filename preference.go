@@ -0,0 +1,48 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// Preference is one entry of a preference list.
+type Preference struct {
+	Rank   int // 0 for the primary, 1 for the first secondary and so on
+	Member lineProtocol.WriteCloser
+	Point  uint32 // circle point the member was reached at
+	Up     bool   // the member is not marked down
+}
+
+// PreferenceList returns the members GetN returns for key, in the same order,
+// with their rank.  Routing rules apply as they do to GetN; a redirected key
+// has only the one entry, with no point.  Replication code can rely on the
+// ranks being stable: any two proxies with the same topology give every key
+// the same primary, secondary and so on.
+func (c *Consistent) PreferenceList(key string, n int) ([]Preference, error) {
+	c.rlock()
+	defer c.runlock()
+	if c.closed {
+		return nil, c.opError("preference", key, nil, ErrClosed)
+	}
+	if len(c.rules) > 0 {
+		if r, ok := c.rule(key); ok {
+			switch r.Action {
+			case Redirect:
+				return []Preference{{Member: r.Member, Up: !c.isDown(r.Member)}}, nil
+			case Delegate:
+				return r.Ring.PreferenceList(key, n)
+			default:
+				return nil, c.opError("preference", key, nil, ErrDropped)
+			}
+		}
+	}
+	if len(c.circle) == 0 {
+		return nil, c.opError("preference", key, nil, ErrEmptyCircle)
+	}
+	var res []Preference
+	c.walkReplicas(c.keyHash(key), n, func(elem lineProtocol.WriteCloser, point uint32) {
+		res = append(res, Preference{Rank: len(res), Member: elem, Point: point, Up: !c.isDown(elem)})
+	})
+	return res, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestPreferenceListMatchesGetN(t *testing.T) {
+	x := New()
+	for _, name := range []string{"abcdefg", "hijklmn", "opqrstu", "vwxyz"} {
+		x.Add(newMember(name))
+	}
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		members, _ := x.GetN(key, 3)
+		prefs, err := x.PreferenceList(key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkNum(len(prefs), 3, t)
+		for r, p := range prefs {
+			if p.Rank != r || p.Member != members[r] || !p.Up {
+				t.Fatalf("got %+v at rank %d, expected %v up", p, r, members[r])
+			}
+		}
+	}
+}
+
+func TestPreferenceListRules(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	archive := New()
+	archive.Add(b)
+	archive.Add(c)
+	x.SetRules([]Rule{
+		{Name: "pin", Match: Exact("pinned"), Action: Redirect, Member: b},
+		{Name: "archive", Match: Prefix("old"), Action: Delegate, Ring: archive},
+		{Name: "scratch", Match: Prefix("tmp"), Action: Drop},
+	})
+	for _, key := range []string{"pinned", "old1", "old2", "other"} {
+		members, _ := x.GetN(key, 2)
+		prefs, err := x.PreferenceList(key, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkNum(len(prefs), len(members), t)
+		for r, p := range prefs {
+			if p.Rank != r || p.Member != members[r] {
+				t.Fatalf("%s: got %+v at rank %d, expected %v", key, p, r, members[r])
+			}
+		}
+	}
+	if _, err := x.PreferenceList("tmp1", 2); !errors.Is(err, ErrDropped) {
+		t.Errorf("got %v, expected ErrDropped", err)
+	}
+}
+
+func TestGetNZero(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	x.Add(newMember("hijklmn"))
+	for i := 0; i < 100; i++ {
+		res, err := x.GetN(strconv.Itoa(i), 0)
+		if err != nil || len(res) != 0 {
+			t.Fatalf("got %v, %v, expected nothing", res, err)
+		}
+	}
+}
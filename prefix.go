@@ -0,0 +1,27 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "strings"
+
+// WithPrefixRouting makes the hash route every key by its prefix up to the
+// first delim only, so keys sharing a prefix always land together.  With
+// delim "," all series of one line protocol measurement are co-located.  Keys
+// without delim are routed whole.  Rules still match the whole key.
+func WithPrefixRouting(delim string) Option {
+	return func(c *Consistent) {
+		c.prefixDelim = delim
+	}
+}
+
+// keyHash returns the hash a key is routed by.
+func (c *Consistent) keyHash(key string) uint32 {
+	if c.prefixDelim != "" {
+		if i := strings.Index(key, c.prefixDelim); i >= 0 {
+			key = key[:i]
+		}
+	}
+	return c.hashKey(key)
+}
@@ -0,0 +1,31 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPrefixRouting(t *testing.T) {
+	x := New(WithPrefixRouting(","))
+	for i := 0; i < 8; i++ {
+		x.Add(newMember("member" + strconv.Itoa(i)))
+	}
+	for i := 0; i < 20; i++ {
+		m := "m" + strconv.Itoa(i)
+		want, _ := x.Get(m)
+		for _, key := range []string{m + ",host=a", m + ",host=b,dc=1", m + ","} {
+			if got, _ := x.Get(key); got != want {
+				t.Errorf("got %v for %q, expected %v like %q", got, key, want, m)
+			}
+		}
+		got, _ := x.GetN(m+",host=c", 2)
+		all, _ := x.GetN(m, 2)
+		if got[0] != all[0] || got[1] != all[1] {
+			t.Errorf("got replicas %v, expected %v", got, all)
+		}
+	}
+}
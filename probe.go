@@ -0,0 +1,192 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DefaultProbeMeasurement is the measurement of probe writes when NewProber
+// is given "".
+const DefaultProbeMeasurement = "consistent_probe"
+
+// probeTries bounds the keys tried to find one routing to a member.
+const probeTries = 1 << 16
+
+// ErrNoProbeKey is the error recorded for a member no probe key routes to,
+// such as one excluded from placement.
+var ErrNoProbeKey = errors.New("no probe key routes to member")
+
+// ProbeResult is what a Prober saw of one member.
+type ProbeResult struct {
+	Member   lineProtocol.WriteCloser
+	Key      string        // probe key routing to Member
+	Probes   int64         // probes sent since NewProber
+	Failures int64         // probes that failed
+	Latency  time.Duration // of the last probe's write
+	Err      error         // of the last probe, nil if it succeeded
+	At       time.Time     // of the last probe
+}
+
+// Prober continuously checks that routing and writing work for every
+// member by writing a synthetic point through Write with, for each member, a
+// key that routes to it.  Probes are line protocol points of their own
+// measurement, tagged with the member, so backends can drop them:
+//
+//	consistent_probe,member=<name> seq=<n>i <unix nanoseconds>
+type Prober struct {
+	c           *Consistent
+	measurement string
+
+	mu      sync.Mutex
+	seq     int64
+	epoch   uint64
+	keys    map[lineProtocol.WriteCloser]string
+	results map[lineProtocol.WriteCloser]*ProbeResult
+}
+
+// NewProber creates a Prober for c writing probes to measurement.
+func NewProber(c *Consistent, measurement string) *Prober {
+	if measurement == "" {
+		measurement = DefaultProbeMeasurement
+	}
+	return &Prober{
+		c:           c,
+		measurement: measurement,
+		keys:        make(map[lineProtocol.WriteCloser]string),
+		results:     make(map[lineProtocol.WriteCloser]*ProbeResult),
+	}
+}
+
+// Probe probes every member once and returns the results, sorted by name.
+// Members marked down are not written to and count as failed.  It stops
+// early if ctx is done.
+func (p *Prober) Probe(ctx context.Context) []ProbeResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	members := p.c.Members()
+	if epoch := p.c.Epoch(); epoch != p.epoch {
+		p.epoch = epoch
+		p.findKeys(members)
+	}
+	for _, m := range members {
+		if ctx.Err() != nil {
+			break
+		}
+		r, ok := p.results[m]
+		if !ok {
+			r = &ProbeResult{Member: m}
+			p.results[m] = r
+		}
+		r.Key = p.keys[m]
+		p.probe(r)
+	}
+	return p.snapshot()
+}
+
+// findKeys finds a key routing to each of members among the probe keys.
+// need p.mu.Lock() before calling
+func (p *Prober) findKeys(members []lineProtocol.WriteCloser) {
+	clear(p.keys)
+	for e := range p.results {
+		if !sliceContainsMember(members, e) {
+			delete(p.results, e)
+		}
+	}
+	// resolving with owner rather than Get keeps the probe keys out of the
+	// routed counters, hot keys and observers
+	p.c.rlock()
+	defer p.c.runlock()
+	if p.c.closed || len(p.c.circle) == 0 {
+		return
+	}
+	for i := 0; i < probeTries && len(p.keys) < len(members); i++ {
+		k := p.measurement + "-" + strconv.Itoa(i)
+		e := p.c.owner(k)
+		if e == nil {
+			continue
+		}
+		if _, ok := p.keys[e]; !ok {
+			p.keys[e] = k
+		}
+	}
+}
+
+// need p.mu.Lock() before calling
+func (p *Prober) probe(r *ProbeResult) {
+	r.Probes++
+	r.At = p.c.clock.Now()
+	r.Latency = 0
+	switch {
+	case !p.c.Healthy(r.Member):
+		r.Err = ErrMemberDown
+	case r.Key == "":
+		r.Err = ErrNoProbeKey
+	default:
+		p.seq++
+		start := time.Now()
+		_, r.Err = p.c.Write(r.Key, p.line(r.Member))
+		r.Latency = time.Since(start)
+	}
+	if r.Err != nil {
+		r.Failures++
+	}
+}
+
+func (p *Prober) line(e lineProtocol.WriteCloser) []byte {
+	var b strings.Builder
+	b.WriteString(p.measurement)
+	b.WriteString(",member=")
+	b.WriteString(probeTagEscaper.Replace(e.Name()))
+	b.WriteString(" seq=")
+	b.WriteString(strconv.FormatInt(p.seq, 10))
+	b.WriteString("i ")
+	b.WriteString(strconv.FormatInt(p.c.clock.Now().UnixNano(), 10))
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+var probeTagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// Results returns the latest results of every member, sorted by name.
+func (p *Prober) Results() []ProbeResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.snapshot()
+}
+
+// need p.mu.Lock() before calling
+func (p *Prober) snapshot() []ProbeResult {
+	res := make([]ProbeResult, 0, len(p.results))
+	for _, r := range p.results {
+		res = append(res, *r)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Member.Name() < res[j].Member.Name() })
+	return res
+}
+
+// Start runs Probe every interval until ctx is done.
+func (p *Prober) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := p.c.clock.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.Chan():
+				p.Probe(ctx)
+			}
+		}
+	}()
+}
@@ -0,0 +1,73 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestProber(t *testing.T) {
+	a, b, c := newMember("a"), newMember("b b"), newMember("c")
+	x := New()
+	x.Set([]lineProtocol.WriteCloser{a, b, c})
+	p := NewProber(x, "")
+
+	res := p.Probe(context.Background())
+	if len(res) != 3 {
+		t.Fatalf("%d results", len(res))
+	}
+	for _, r := range res {
+		if r.Err != nil || r.Probes != 1 || r.Failures != 0 || r.Key == "" {
+			t.Errorf("%s: %+v", r.Member.Name(), r)
+		}
+		if e, _ := x.Get(r.Key); e != r.Member {
+			t.Errorf("probe key %s routes to %v, not %v", r.Key, e, r.Member)
+		}
+	}
+	if got := b.String(); !strings.HasPrefix(got, `consistent_probe,member=b\ b seq=`) {
+		t.Errorf("b got %q", got)
+	}
+
+	c.mu.Lock()
+	c.err = errors.New("broken")
+	c.mu.Unlock()
+	x.MarkDown(a)
+	p.Probe(context.Background())
+	for _, r := range p.Results() {
+		switch r.Member {
+		case a:
+			if !errors.Is(r.Err, ErrMemberDown) || r.Failures != 1 {
+				t.Errorf("a: %+v", r)
+			}
+		case b:
+			if r.Err != nil || r.Probes != 2 {
+				t.Errorf("b: %+v", r)
+			}
+		case c:
+			if r.Err == nil || r.Failures != 1 {
+				t.Errorf("c: %+v", r)
+			}
+		}
+	}
+	checkNum(strings.Count(a.String(), "\n"), 1, t)
+}
+
+func TestProberKeepsOutOfHotKeys(t *testing.T) {
+	x := New(WithHotKeys(100, time.Hour))
+	x.Set([]lineProtocol.WriteCloser{newMember("a"), newMember("b"), newMember("c")})
+	NewProber(x, "").Probe(context.Background())
+	for _, k := range x.TopKeys(100) {
+		if k.Count != 1 {
+			t.Errorf("probe key %s routed %d times, expected only its write", k.Key, k.Count)
+		}
+	}
+	checkNum(len(x.TopKeys(100)), 3, t)
+}
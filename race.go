@@ -0,0 +1,37 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+//go:build race
+
+package consistent
+
+import "sync/atomic"
+
+// useGuard detects concurrent use of a Consistent created WithoutLocking.
+// state is -1 while a writer is inside, otherwise the number of readers.
+type useGuard struct {
+	state int32
+}
+
+const misuse = "consistent: concurrent use of a ring created WithoutLocking"
+
+func (g *useGuard) lock() {
+	if !atomic.CompareAndSwapInt32(&g.state, 0, -1) {
+		panic(misuse)
+	}
+}
+
+func (g *useGuard) unlock() {
+	atomic.StoreInt32(&g.state, 0)
+}
+
+func (g *useGuard) rlock() {
+	if atomic.AddInt32(&g.state, 1) <= 0 {
+		panic(misuse)
+	}
+}
+
+func (g *useGuard) runlock() {
+	atomic.AddInt32(&g.state, -1)
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// rampSteps is the number of increments AddWithRamp takes to reach full weight.
+const rampSteps = 10
+
+type ramp struct {
+	element lineProtocol.WriteCloser // moved by UpdateEndpoint
+	hashes  []uint32
+	step    int
+	timer   Timer
+}
+
+// AddWithRamp inserts element with a tenth of its vnodes and grows it to its
+// full set in equal steps over d, so a cold member is not handed its whole
+// keyspace at once.  Removing the element cancels the ramp.  Ramps run on
+// timers and so must not be used on a ring created WithoutLocking.
+func (c *Consistent) AddWithRamp(element lineProtocol.WriteCloser, d time.Duration) {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() || c.members[element] {
+		return
+	}
+	if d <= 0 {
+		c.add(element)
+		return
+	}
+	hashes, collided := c.freshHashes(element)
+	c.stats.Collisions += int64(collided)
+	r := &ramp{element: element, hashes: hashes, step: 1}
+	c.place(element, r.hashes[:r.size()])
+	if c.ramps == nil {
+		c.ramps = make(map[lineProtocol.WriteCloser]*ramp)
+	}
+	c.ramps[element] = r
+	interval := d / rampSteps
+	var grow func()
+	grow = func() {
+		c.lock()
+		defer c.unlock()
+		e := r.element
+		if c.ramps[e] != r {
+			return
+		}
+		r.step++
+		// points taken since AddWithRamp are probed past, not overwritten
+		fresh := r.hashes[len(c.vnodes[e]):r.size()]
+		c.stats.Collisions += int64(c.resolve(e, fresh, len(c.vnodes[e]), c.circle))
+		for _, h := range fresh {
+			c.circle[h] = e
+		}
+		c.vnodes[e] = r.hashes[:r.size()]
+		if r.step >= rampSteps {
+			delete(c.ramps, e)
+		} else {
+			r.timer = c.clock.AfterFunc(interval, grow)
+		}
+		c.updateSortedHashes()
+	}
+	r.timer = c.clock.AfterFunc(interval, grow)
+}
+
+// size returns the number of vnodes active at the current step.
+func (r *ramp) size() int {
+	n := (len(r.hashes)*r.step + rampSteps - 1) / rampSteps
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// need c.lock() before calling
+func (c *Consistent) stopRamp(element lineProtocol.WriteCloser) {
+	if r, ok := c.ramps[element]; ok {
+		r.timer.Stop()
+		delete(c.ramps, element)
+	}
+}
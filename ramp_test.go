@@ -0,0 +1,65 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddWithRamp(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	x.AddWithRamp(newMember("hijklmn"), 50*time.Millisecond)
+	checkNum(len(x.circle), 22, t)
+	if s := x.Snapshot(); len(s.Tokens) != 0 {
+		t.Errorf("expected ramping member to be recorded at full weight, got %v", s.Tokens)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for x.Stats().Vnodes < 40 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	x.RLock()
+	defer x.RUnlock()
+	checkNum(len(x.circle), 40, t)
+	checkNum(len(x.vnodes[x.circle[x.sortedHashes[0]]]), 20, t)
+	checkNum(len(x.explicit), 0, t)
+	checkNum(len(x.ramps), 0, t)
+}
+
+func TestRemoveStopsRamp(t *testing.T) {
+	x := New()
+	e := newMember("hijklmn")
+	x.AddWithRamp(e, time.Hour)
+	x.Remove(e)
+	checkNum(len(x.circle), 0, t)
+	checkNum(len(x.ramps), 0, t)
+}
+
+func TestRampResolvesTakenPoints(t *testing.T) {
+	clk := newFakeClock()
+	x := New(WithClock(clk))
+	e := newMember("hijklmn")
+	x.AddWithRamp(e, 10*time.Second)
+	// occupy one of the points a later step would activate
+	x.RLock()
+	h := x.ramps[e].hashes[19]
+	x.RUnlock()
+	o := newMember("other")
+	if err := x.AddTokens(o, []uint32{h}); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(10 * time.Second)
+	x.RLock()
+	owner := x.circle[h]
+	x.RUnlock()
+	if owner != o {
+		t.Errorf("ramp took over a point of %v", owner)
+	}
+	checkNum(int(x.Stats().Collisions), 1, t)
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"hash/crc32"
+	"sort"
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrInvalidBoundary is the error returned when a boundary of a RangeTable
+// would leave a range empty or fall outside the table.
+var ErrInvalidBoundary = errors.New("invalid range boundary")
+
+// ErrRangesIncomplete is the error returned by SetRanges when the ranges do
+// not cover every bucket exactly once.
+var ErrRangesIncomplete = errors.New("ranges must cover every bucket once")
+
+// BucketRange assigns the buckets [Start, End] of a RangeTable to Member.
+type BucketRange struct {
+	Start  int
+	End    int
+	Member lineProtocol.WriteCloser
+}
+
+// RangeTable is a two-level placement: keys hash to one of a fixed number of
+// buckets, and contiguous ranges of buckets are assigned to members.  Hashing
+// spreads skewed key spaces evenly over the buckets, while the range
+// boundaries can be moved one bucket at a time to shift load between
+// neighbouring members precisely.
+type RangeTable struct {
+	buckets int
+	starts  []int // first bucket of each range, starts[0] == 0
+	owners  []lineProtocol.WriteCloser
+	sync.RWMutex
+}
+
+// NewRangeTable creates a RangeTable of the given number of buckets with no
+// ranges; Get fails with ErrEmptyCircle until SetRanges is called.
+func NewRangeTable(buckets int) *RangeTable {
+	if buckets < 1 {
+		buckets = 1
+	}
+	return &RangeTable{buckets: buckets}
+}
+
+// Bucket returns the bucket key hashes to.
+func (t *RangeTable) Bucket(key string) int {
+	return int(crc32.ChecksumIEEE([]byte(key)) % uint32(t.buckets))
+}
+
+// SetRanges replaces the assignment of the table.  The ranges must cover
+// every bucket exactly once, in any order.
+func (t *RangeTable) SetRanges(ranges []BucketRange) error {
+	r := append([]BucketRange(nil), ranges...)
+	sort.Slice(r, func(i, j int) bool { return r[i].Start < r[j].Start })
+	next := 0
+	for _, b := range r {
+		if b.Start != next || b.End < b.Start || b.Member == nil {
+			return ErrRangesIncomplete
+		}
+		next = b.End + 1
+	}
+	if next != t.buckets {
+		return ErrRangesIncomplete
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.starts, t.owners = t.starts[:0], t.owners[:0]
+	for _, b := range r {
+		t.starts = append(t.starts, b.Start)
+		t.owners = append(t.owners, b.Member)
+	}
+	return nil
+}
+
+// Ranges returns the current assignment ordered by Start.
+func (t *RangeTable) Ranges() []BucketRange {
+	t.RLock()
+	defer t.RUnlock()
+	res := make([]BucketRange, len(t.starts))
+	for i, s := range t.starts {
+		end := t.buckets - 1
+		if i+1 < len(t.starts) {
+			end = t.starts[i+1] - 1
+		}
+		res[i] = BucketRange{Start: s, End: end, Member: t.owners[i]}
+	}
+	return res
+}
+
+// MoveBoundary moves the start of the i-th range, ordered by Start, to bucket
+// start, growing or shrinking it at the expense of the range before it.  Only
+// the keys of the buckets between the old and new boundary move.
+func (t *RangeTable) MoveBoundary(i, start int) error {
+	t.Lock()
+	defer t.Unlock()
+	if i <= 0 || i >= len(t.starts) {
+		return ErrInvalidBoundary
+	}
+	end := t.buckets
+	if i+1 < len(t.starts) {
+		end = t.starts[i+1]
+	}
+	if start <= t.starts[i-1] || start >= end {
+		return ErrInvalidBoundary
+	}
+	t.starts[i] = start
+	return nil
+}
+
+// Split cuts the range holding bucket at bucket and gives the buckets from
+// there to the end of the range to element.
+func (t *RangeTable) Split(bucket int, element lineProtocol.WriteCloser) error {
+	t.Lock()
+	defer t.Unlock()
+	if bucket <= 0 || bucket >= t.buckets || len(t.starts) == 0 {
+		return ErrInvalidBoundary
+	}
+	i := sort.SearchInts(t.starts, bucket+1) - 1
+	if t.starts[i] == bucket {
+		return ErrInvalidBoundary
+	}
+	t.starts = append(t.starts[:i+1], append([]int{bucket}, t.starts[i+1:]...)...)
+	t.owners = append(t.owners[:i+1], append([]lineProtocol.WriteCloser{element}, t.owners[i+1:]...)...)
+	return nil
+}
+
+// Get returns the member owning the bucket of name.
+func (t *RangeTable) Get(name string) (lineProtocol.WriteCloser, error) {
+	b := t.Bucket(name)
+	t.RLock()
+	defer t.RUnlock()
+	if len(t.starts) == 0 {
+		return nil, &Error{Op: "get", Key: name, Err: ErrEmptyCircle}
+	}
+	return t.owners[sort.SearchInts(t.starts, b+1)-1], nil
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestRangeTable(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	r := NewRangeTable(100)
+	if _, err := r.Get("foo"); !errors.Is(err, ErrEmptyCircle) {
+		t.Errorf("got %v, expected ErrEmptyCircle", err)
+	}
+	if err := r.SetRanges([]BucketRange{{0, 49, a}, {51, 99, b}}); !errors.Is(err, ErrRangesIncomplete) {
+		t.Errorf("got %v, expected ErrRangesIncomplete", err)
+	}
+	if err := r.SetRanges([]BucketRange{{50, 99, b}, {0, 49, a}}); err != nil {
+		t.Fatal(err)
+	}
+
+	owners := make(map[string]*member)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		e, err := r.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := a
+		if r.Bucket(key) >= 50 {
+			want = b
+		}
+		if e != want {
+			t.Fatalf("got %v for bucket %d, expected %v", e, r.Bucket(key), want)
+		}
+		owners[key] = e.(*member)
+	}
+
+	// moving the boundary only moves the keys between the old and new one
+	if err := r.MoveBoundary(1, 40); err != nil {
+		t.Fatal(err)
+	}
+	for key, old := range owners {
+		e, _ := r.Get(key)
+		if bk := r.Bucket(key); (bk < 40 || bk >= 50) && e != old {
+			t.Errorf("key in bucket %d moved from %v to %v", bk, old, e)
+		}
+	}
+	if err := r.MoveBoundary(1, 0); !errors.Is(err, ErrInvalidBoundary) {
+		t.Errorf("got %v, expected ErrInvalidBoundary", err)
+	}
+
+	if err := r.Split(90, c); err != nil {
+		t.Fatal(err)
+	}
+	got := r.Ranges()
+	want := []BucketRange{{0, 39, a}, {40, 89, b}, {90, 99, c}}
+	checkNum(len(got), len(want), t)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got range %+v, expected %+v", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// DefaultReadReplicas is the number of replicas GetForRead spreads reads
+// over unless WithReadReplicas says otherwise.
+const DefaultReadReplicas = 2
+
+// WithReadReplicas sets how many replicas of a key, the first n members
+// GetN returns, GetForRead spreads reads over.
+func WithReadReplicas(n int) Option {
+	return func(c *Consistent) {
+		if n < 1 {
+			n = 1
+		}
+		c.readReplicas = n
+	}
+}
+
+// GetForRead returns a member holding a copy of key to read it from.  Reads
+// rotate round-robin over the replicas of the key that are not marked down,
+// so repeated reads of a hot series do not all land on its primary.  Keys
+// matching a rule, and keys whose replicas are all down, are routed by Get.
+func (c *Consistent) GetForRead(key string) (lineProtocol.WriteCloser, error) {
+	n := c.readReplicas
+	if n == 0 {
+		n = DefaultReadReplicas
+	}
+	c.rlock()
+	if c.closed || len(c.circle) == 0 {
+		c.runlock()
+		return c.Get(key)
+	}
+	if len(c.rules) > 0 {
+		if _, ok := c.rule(key); ok {
+			c.runlock()
+			return c.Get(key)
+		}
+	}
+	var buf [8]lineProtocol.WriteCloser
+	up := buf[:0]
+	c.walkReplicas(c.keyHash(key), n, func(elem lineProtocol.WriteCloser, _ uint32) {
+		if !c.isDown(elem) {
+			up = append(up, elem)
+		}
+	})
+	c.runlock()
+	if len(up) == 0 {
+		return c.Get(key)
+	}
+	return up[c.reads.Add(1)%uint64(len(up))], nil
+}
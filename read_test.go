@@ -0,0 +1,42 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestGetForRead(t *testing.T) {
+	x := New(WithReadReplicas(3))
+	x.Set([]lineProtocol.WriteCloser{newMember("a"), newMember("b"), newMember("c"), newMember("d")})
+	replicas, _ := x.GetN("hot", 3)
+	counts := make(map[lineProtocol.WriteCloser]int)
+	for i := 0; i < 300; i++ {
+		e, err := x.GetForRead("hot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[e]++
+	}
+	for _, r := range replicas {
+		checkNum(counts[r], 100, t)
+	}
+
+	x.MarkDown(replicas[0])
+	for i := 0; i < 10; i++ {
+		if e, _ := x.GetForRead("hot"); e == replicas[0] {
+			t.Fatal("read from a member marked down")
+		}
+	}
+	for _, r := range replicas {
+		x.MarkDown(r)
+	}
+	want, _ := x.Get("hot")
+	if e, _ := x.GetForRead("hot"); e != want {
+		t.Errorf("all replicas down: got %v, want Get's %v", e, want)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// LoadReporter is implemented by members that can report their actual load,
+// in any unit as long as every member uses the same one: bytes on disk,
+// series held and so on.
+type LoadReporter interface {
+	Load(ctx context.Context) (float64, error)
+}
+
+// RebalancePolicy bounds the weight changes a Rebalancer makes.
+type RebalancePolicy struct {
+	Tolerance float64 // relative deviation from the mean load left alone, 0.1 if 0
+	MaxStep   float64 // largest weight change per round, 0.25 if 0
+	MinWeight float64 // lowest weight set, 0.1 if 0
+	MaxWeight float64 // highest weight set, 4 if 0
+}
+
+// WeightChange is a weight adjustment suggested or applied by a Rebalancer.
+type WeightChange struct {
+	Member lineProtocol.WriteCloser
+	From   float64
+	To     float64
+}
+
+// Rebalancer evens out the actual load of the members of a hash, which
+// uniform hashing does not when some keys are much heavier than others.  It
+// moves the weight of every member whose reported load is off the mean in
+// proportion to the deviation, a bounded step at a time.
+type Rebalancer struct {
+	c     *Consistent
+	p     RebalancePolicy
+	mu    sync.Mutex
+	loads map[lineProtocol.WriteCloser]float64
+}
+
+// NewRebalancer creates a Rebalancer for c.
+func NewRebalancer(c *Consistent, p RebalancePolicy) *Rebalancer {
+	if p.Tolerance == 0 {
+		p.Tolerance = 0.1
+	}
+	if p.MaxStep == 0 {
+		p.MaxStep = 0.25
+	}
+	if p.MinWeight == 0 {
+		p.MinWeight = 0.1
+	}
+	if p.MaxWeight == 0 {
+		p.MaxWeight = 4
+	}
+	return &Rebalancer{c: c, p: p, loads: make(map[lineProtocol.WriteCloser]float64)}
+}
+
+// Report records the load of element, for agents reporting on behalf of
+// members.
+func (r *Rebalancer) Report(element lineProtocol.WriteCloser, load float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loads[element] = load
+}
+
+// Collect asks every member implementing LoadReporter for its load.  Members
+// failing to answer keep their last report.
+func (r *Rebalancer) Collect(ctx context.Context) {
+	for _, e := range r.c.Members() {
+		if lr, ok := e.(LoadReporter); ok {
+			if load, err := lr.Load(ctx); err == nil {
+				r.Report(e, load)
+			}
+		}
+	}
+}
+
+// Suggest returns the weight changes the next round would make, sorted by
+// member name.  Members without a report are left alone.
+func (r *Rebalancer) Suggest() []WeightChange {
+	members := r.c.Members()
+	r.mu.Lock()
+	loads := make(map[lineProtocol.WriteCloser]float64, len(members))
+	var total float64
+	for _, e := range members {
+		if l, ok := r.loads[e]; ok {
+			loads[e] = l
+			total += l
+		}
+	}
+	r.mu.Unlock()
+	if len(loads) == 0 || total <= 0 {
+		return nil
+	}
+	mean := total / float64(len(loads))
+
+	var res []WeightChange
+	for e, load := range loads {
+		if math.Abs(load-mean)/mean <= r.p.Tolerance {
+			continue
+		}
+		w := r.c.Weight(e)
+		to := w + r.p.MaxStep
+		if load > 0 {
+			to = w * mean / load
+		}
+		to = math.Max(w-r.p.MaxStep, math.Min(w+r.p.MaxStep, to))
+		to = math.Max(r.p.MinWeight, math.Min(r.p.MaxWeight, to))
+		if to != w {
+			res = append(res, WeightChange{Member: e, From: w, To: to})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Member.Name() < res[j].Member.Name() })
+	return res
+}
+
+// Apply makes the changes Suggest returns and returns those that succeeded.
+// Reports are kept, so members should report again before the next round.
+func (r *Rebalancer) Apply() []WeightChange {
+	var done []WeightChange
+	for _, ch := range r.Suggest() {
+		if r.c.SetWeight(ch.Member, ch.To) == nil {
+			done = append(done, ch)
+		}
+	}
+	return done
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"testing"
+)
+
+type loadMember struct {
+	*member
+	load float64
+}
+
+func (m *loadMember) Load(ctx context.Context) (float64, error) { return m.load, nil }
+
+func TestRebalancer(t *testing.T) {
+	a := &loadMember{newMember("abcdefg"), 300}
+	b := &loadMember{newMember("hijklmn"), 100}
+	c := newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	x.Add(c)
+	r := NewRebalancer(x, RebalancePolicy{})
+	r.Collect(context.Background())
+	r.Report(c, 200)
+
+	got := r.Suggest()
+	checkNum(len(got), 2, t)
+	if got[0].Member != a || got[0].To != 0.75 {
+		t.Errorf("got %+v, expected a to drop by one step to 0.75", got[0])
+	}
+	if got[1].Member != b || got[1].To != 1.25 {
+		t.Errorf("got %+v, expected b to rise by one step to 1.25", got[1])
+	}
+	checkNum(len(r.Apply()), 2, t)
+	if x.Weight(a) != 0.75 || x.Weight(c) != 1 {
+		t.Errorf("got weights %v and %v, expected 0.75 and 1", x.Weight(a), x.Weight(c))
+	}
+}
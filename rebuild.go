@@ -0,0 +1,131 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"slices"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// WithBackgroundRebuild makes Add, Remove and Set on a circle of at least
+// minPoints points build the new sorted points while holding only the read
+// lock, then take the write lock just to install them, the way SetCtx does.
+// Gets keep running during the expensive part of a change instead of waiting
+// for the whole circle to be rebuilt.  A change that races with another is
+// rebuilt from scratch.  Set goes through SetCtx and so applies
+// WithValidator.  It has no effect on rings created WithoutLocking.
+func WithBackgroundRebuild(minPoints int) Option {
+	return func(c *Consistent) {
+		c.bgRebuild = minPoints
+	}
+}
+
+// largeRing reports whether changes are built in the background.
+func (c *Consistent) largeRing() bool {
+	if c.unlocked {
+		return false
+	}
+	c.rlock()
+	defer c.runlock()
+	return len(c.sortedHashes) >= c.bgRebuild
+}
+
+// addInBackground adds element as described at WithBackgroundRebuild.  It
+// returns false, doing nothing, if the circle is too small for that.
+func (c *Consistent) addInBackground(element lineProtocol.WriteCloser) bool {
+	if c.unlocked {
+		return false
+	}
+	for {
+		start := time.Now()
+		c.rlock()
+		if len(c.sortedHashes) < c.bgRebuild {
+			c.runlock()
+			return false
+		}
+		if c.members[element] {
+			c.runlock()
+			return true
+		}
+		epoch := c.epoch
+		hashes, collided := c.freshHashes(element)
+		merged := append(append(make(uints, 0, len(c.sortedHashes)+len(hashes)), c.sortedHashes...), hashes...)
+		c.runlock()
+		slices.Sort(merged)
+		merged = slices.Compact(merged)
+
+		c.lock()
+		if c.epoch != epoch {
+			c.unlock()
+			continue
+		}
+		if c.allowMutation() {
+			c.stats.Collisions += int64(collided)
+			c.placeSorted(element, hashes, merged, time.Since(start))
+		}
+		c.unlock()
+		return true
+	}
+}
+
+// removeInBackground removes element as described at WithBackgroundRebuild.
+// It returns false, doing nothing, if the circle is too small for that.
+func (c *Consistent) removeInBackground(element lineProtocol.WriteCloser) bool {
+	if c.unlocked {
+		return false
+	}
+	for {
+		start := time.Now()
+		c.rlock()
+		if len(c.sortedHashes) < c.bgRebuild {
+			c.runlock()
+			return false
+		}
+		if !c.members[element] {
+			c.runlock()
+			return true
+		}
+		epoch := c.epoch
+		gone := make(map[uint32]bool, len(c.vnodes[element]))
+		for _, h := range c.vnodes[element] {
+			if c.circle[h] == element {
+				gone[h] = true
+			}
+		}
+		kept := make(uints, 0, len(c.sortedHashes))
+		for _, h := range c.sortedHashes {
+			if !gone[h] {
+				kept = append(kept, h)
+			}
+		}
+		c.runlock()
+
+		c.lock()
+		if c.epoch != epoch {
+			c.unlock()
+			continue
+		}
+		if c.allowMutation() && c.allowShrink(len(c.members)-1) {
+			c.removeSorted(element, kept, time.Since(start))
+		}
+		c.unlock()
+		return true
+	}
+}
+
+// setSorted installs sorted as the sorted points of the circle, built in d,
+// or recomputes them if sorted is nil.
+// need c.lock() before calling
+func (c *Consistent) setSorted(sorted uints, d time.Duration) {
+	if sorted == nil {
+		c.updateSortedHashes()
+		return
+	}
+	c.sortedHashes = sorted
+	c.linkRuns()
+	c.rebuilt(d)
+}
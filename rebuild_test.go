@@ -0,0 +1,68 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestBackgroundRebuild(t *testing.T) {
+	x, y := New(WithBackgroundRebuild(1)), New()
+	var members []lineProtocol.WriteCloser
+	for i := 0; i < 20; i++ {
+		members = append(members, newMember(fmt.Sprintf("member-%d", i)))
+	}
+	x.Add(members[0])
+	y.Add(members[0])
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if _, err := x.Get("foo"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}
+	}()
+	for _, m := range members[1:] {
+		x.Add(m)
+		y.Add(m)
+	}
+	for _, m := range members[:5] {
+		x.Remove(m)
+		y.Remove(m)
+	}
+	close(stop)
+	wg.Wait()
+
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("key%d", i)
+		a, _ := x.Get(k)
+		b, _ := y.Get(k)
+		if a != b {
+			t.Fatalf("%s: got %s, expected %s as without background rebuilds", k, a.Name(), b.Name())
+		}
+	}
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+	x.Set(members[:3])
+	checkNum(len(x.Members()), 3, t)
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
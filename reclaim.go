@@ -0,0 +1,123 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// reclaimer defers closing the writers the hash owns once they are removed,
+// until every write that started while they were members has returned.
+// Writes made through Write, WriteReplicas and ScatterStream register the
+// epoch they started in; a writer retired at epoch R may only be reached by
+// writes that started before R, so it is closed once none of those remain.
+type reclaimer struct {
+	mu      sync.Mutex
+	active  map[uint64]int // writes in flight by the epoch they started in
+	retired []retiredWriter
+}
+
+type retiredWriter struct {
+	w     lineProtocol.WriteCloser
+	epoch uint64 // first epoch without w
+}
+
+// enter registers a write starting now and returns the epoch to pass to
+// exit when it returns.
+func (c *Consistent) enter() uint64 {
+	c.rlock()
+	defer c.runlock()
+	r := &c.reclaim
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active == nil {
+		r.active = make(map[uint64]int)
+	}
+	r.active[c.epoch]++
+	return c.epoch
+}
+
+// exit ends a write registered by enter, closing the writers no other write
+// can reach anymore.
+func (c *Consistent) exit(epoch uint64) {
+	r := &c.reclaim
+	r.mu.Lock()
+	if r.active[epoch]--; r.active[epoch] <= 0 {
+		delete(r.active, epoch)
+	}
+	ready := r.ready()
+	r.mu.Unlock()
+	closeWriters(ready)
+}
+
+// retire hands w, just removed from the hash, over to be closed and returns
+// the writers that can be closed now, w among them if no write in flight
+// can reach it.  The caller closes them after releasing the lock.
+// need c.lock() before calling
+func (c *Consistent) retire(w lineProtocol.WriteCloser) []lineProtocol.WriteCloser {
+	r := &c.reclaim
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retired = append(r.retired, retiredWriter{w: w, epoch: c.epoch})
+	return r.ready()
+}
+
+// ready removes and returns the retired writers that no write in flight
+// started early enough to reach.
+// need r.mu.Lock() before calling
+func (r *reclaimer) ready() []lineProtocol.WriteCloser {
+	if len(r.retired) == 0 {
+		return nil
+	}
+	oldest, busy := uint64(0), false
+	for e := range r.active {
+		if !busy || e < oldest {
+			oldest, busy = e, true
+		}
+	}
+	var ready []lineProtocol.WriteCloser
+	kept := r.retired[:0]
+	for _, rw := range r.retired {
+		if !busy || oldest >= rw.epoch {
+			ready = append(ready, rw.w)
+		} else {
+			kept = append(kept, rw)
+		}
+	}
+	r.retired = kept
+	return ready
+}
+
+// drain returns every retired writer, for Close once no write is in flight.
+func (r *reclaimer) drain() []lineProtocol.WriteCloser {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ws := make([]lineProtocol.WriteCloser, 0, len(r.retired))
+	for _, rw := range r.retired {
+		ws = append(ws, rw.w)
+	}
+	r.retired = nil
+	return ws
+}
+
+// pending returns the number of retired writers not closed yet.
+func (r *reclaimer) pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.retired)
+}
+
+func closeWriters(ws []lineProtocol.WriteCloser) error {
+	var errs []error
+	for _, w := range ws {
+		if err := w.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
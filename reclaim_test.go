@@ -0,0 +1,105 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestReclaim(t *testing.T) {
+	made := make(map[string]*member)
+	f := WriterFactoryFunc(func(addr string) (lineProtocol.WriteCloser, error) {
+		m := newMember(addr)
+		made[addr] = m
+		return m, nil
+	})
+	x := New(WithWriterFactory(f))
+	x.SetAddrs([]string{"a", "b", "c"})
+
+	early := x.enter()
+	x.RemoveAddr("a")
+	if made["a"].closed || x.Stats().Reclaiming != 1 {
+		t.Fatal("closed a member a write in flight may still reach")
+	}
+	late := x.enter()
+	x.RemoveAddr("b")
+	x.exit(early)
+	if !made["a"].closed {
+		t.Error("expected a to close once the early write returned")
+	}
+	if made["b"].closed {
+		t.Error("closed b while a write that started before its removal is in flight")
+	}
+	x.exit(late)
+	if !made["b"].closed || x.Stats().Reclaiming != 0 {
+		t.Error("expected b to close once the last write returned")
+	}
+
+	x.RemoveAddr("c")
+	if !made["c"].closed {
+		t.Error("expected a member no write can reach to close at once")
+	}
+}
+
+// strictMember fails writes made after it was closed.
+type strictMember struct {
+	name   string
+	closed atomic.Bool
+	late   *atomic.Int64
+}
+
+func (m *strictMember) Name() string { return m.name }
+
+func (m *strictMember) Write(p []byte) (int, error) {
+	if m.closed.Load() {
+		m.late.Add(1)
+	}
+	return len(p), nil
+}
+
+func (m *strictMember) Close() error {
+	m.closed.Store(true)
+	return nil
+}
+
+func TestReclaimConcurrent(t *testing.T) {
+	var late atomic.Int64
+	f := WriterFactoryFunc(func(addr string) (lineProtocol.WriteCloser, error) {
+		return &strictMember{name: addr, late: &late}, nil
+	})
+	x := New(WithWriterFactory(f))
+	x.SetAddrs([]string{"a", "b"})
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				x.Write(fmt.Sprintf("%d-%d", g, i), []byte("x"))
+			}
+		}(g)
+	}
+	for i := 0; i < 200; i++ {
+		x.SetAddrs([]string{"a", fmt.Sprint("n", i)})
+	}
+	close(stop)
+	wg.Wait()
+	if n := late.Load(); n != 0 {
+		t.Errorf("%d writes reached a closed writer", n)
+	}
+	x.Close()
+}
@@ -0,0 +1,150 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DialFunc creates a new connection to a member.
+type DialFunc func() (lineProtocol.WriteCloser, error)
+
+// Reconnecting is a WriteCloser that re-dials its underlying writer when a
+// write fails, so a transient connection reset does not force the member out
+// of the hash and its keys onto other members.
+//
+// A failed write is retried once on a fresh connection.  If that fails too,
+// writes fail fast with ErrMemberDown until the next dial attempt, which is
+// backed off exponentially from MinBackoff to MaxBackoff, as told by Clock,
+// nil meaning RealClock.  Set these fields before the first Write.
+type Reconnecting struct {
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Clock      Clock
+
+	name    string
+	dial    DialFunc
+	mu      sync.Mutex
+	w       lineProtocol.WriteCloser
+	backoff time.Duration
+	retryAt time.Time
+	closed  bool
+}
+
+// NewReconnecting creates a Reconnecting writer called name that connects
+// with dial on first use.  name places the member in the hash and stays the
+// same across reconnects.
+func NewReconnecting(name string, dial DialFunc) *Reconnecting {
+	return &Reconnecting{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+		name:       name,
+		dial:       dial,
+	}
+}
+
+// Name returns the name given to NewReconnecting.
+func (r *Reconnecting) Name() string { return r.name }
+
+// Write writes p to the current connection, reconnecting as needed.
+func (r *Reconnecting) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, ErrClosed
+	}
+	if r.w != nil {
+		n, err := r.w.Write(p)
+		if err == nil {
+			return n, nil
+		}
+		r.w.Close()
+		r.w = nil
+		r.retryAt = time.Time{}
+	}
+	if err := r.connect(); err != nil {
+		return 0, err
+	}
+	n, err := r.w.Write(p)
+	if err != nil {
+		r.w.Close()
+		r.w = nil
+		r.fail()
+	}
+	return n, err
+}
+
+// need r.mu.Lock() before calling
+func (r *Reconnecting) connect() error {
+	if clockOr(r.Clock).Now().Before(r.retryAt) {
+		return ErrMemberDown
+	}
+	w, err := r.dial()
+	if err != nil {
+		r.fail()
+		return err
+	}
+	r.w = w
+	r.backoff = 0
+	return nil
+}
+
+// fail schedules the next dial attempt.
+// need r.mu.Lock() before calling
+func (r *Reconnecting) fail() {
+	if r.backoff == 0 {
+		r.backoff = r.MinBackoff
+	} else if r.backoff *= 2; r.backoff > r.MaxBackoff {
+		r.backoff = r.MaxBackoff
+	}
+	r.retryAt = clockOr(r.Clock).Now().Add(r.backoff)
+}
+
+// Close closes the current connection.  Later writes return ErrClosed.
+func (r *Reconnecting) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.w == nil {
+		return nil
+	}
+	err := r.w.Close()
+	r.w = nil
+	return err
+}
+
+// Redial closes the current connection so the next Write dials a fresh one
+// right away, without waiting out any backoff.  The writer keeps its name,
+// and so its place in the hash.
+func (r *Reconnecting) Redial() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retryAt, r.backoff = time.Time{}, 0
+	if r.w == nil {
+		return nil
+	}
+	err := r.w.Close()
+	r.w = nil
+	return err
+}
+
+// Connect dials now if the writer has no connection, without waiting out
+// any backoff, so a caller can find out whether the member is reachable
+// before the first Write.
+func (r *Reconnecting) Connect() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if r.w != nil {
+		return nil
+	}
+	r.retryAt = time.Time{}
+	return r.connect()
+}
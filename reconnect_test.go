@@ -0,0 +1,53 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestReconnecting(t *testing.T) {
+	var (
+		conns   []*member
+		dialErr error
+	)
+	r := NewReconnecting("abcdefg", func() (lineProtocol.WriteCloser, error) {
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		m := newMember("conn")
+		conns = append(conns, m)
+		return m, nil
+	})
+	r.MinBackoff = time.Hour
+	if _, err := r.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	conns[0].err = errors.New("connection reset")
+	if _, err := r.Write([]byte("b")); err != nil {
+		t.Fatalf("expected transparent reconnect, got %v", err)
+	}
+	checkNum(len(conns), 2, t)
+	if !conns[0].closed || conns[1].String() != "b" {
+		t.Errorf("expected old connection closed and write retried on the new one")
+	}
+
+	conns[1].err = errors.New("connection reset")
+	dialErr = errors.New("connection refused")
+	if _, err := r.Write([]byte("c")); err != dialErr {
+		t.Errorf("got %v, expected dial error", err)
+	}
+	if _, err := r.Write([]byte("d")); !errors.Is(err, ErrMemberDown) {
+		t.Errorf("got %v, expected fast failure while backing off", err)
+	}
+	r.Close()
+	if _, err := r.Write([]byte("e")); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, expected closed error", err)
+	}
+}
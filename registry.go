@@ -0,0 +1,104 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrRingExists is the error returned by Register and NewNamed for a name
+// already taken in the registry.
+var ErrRingExists = errors.New("ring already registered")
+
+// registry holds the rings registered by name in this process.
+var registry = struct {
+	sync.RWMutex
+	rings map[string]*Consistent
+}{rings: make(map[string]*Consistent)}
+
+// WithName names the hash.  The name labels its Stats, so metrics from
+// several rings in one process can be told apart; it does not register the
+// hash.
+func WithName(name string) Option {
+	return func(c *Consistent) { c.name = name }
+}
+
+// Name returns the name set WithName or by NewNamed, or "".
+func (c *Consistent) Name() string { return c.name }
+
+// NewNamed creates a hash named name with opts and registers it, so any
+// layer of the process can find it with Lookup instead of being passed it.
+func NewNamed(name string, opts ...Option) (*Consistent, error) {
+	c := New(append(opts, WithName(name))...)
+	if err := Register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Register adds c to the registry under its name.  It fails with
+// ErrRingExists if another hash holds the name.  Close unregisters the hash.
+func Register(c *Consistent) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if prev, ok := registry.rings[c.name]; ok && prev != c {
+		return &Error{Op: "register", Key: c.name, Err: ErrRingExists}
+	}
+	registry.rings[c.name] = c
+	return nil
+}
+
+// Lookup returns the hash registered under name.
+func Lookup(name string) (*Consistent, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	c, ok := registry.rings[name]
+	return c, ok
+}
+
+// Unregister removes the hash registered under name, if any.
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.rings, name)
+}
+
+// unregister removes c from the registry if it is registered.
+func unregister(c *Consistent) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.rings[c.name] == c {
+		delete(registry.rings, c.name)
+	}
+}
+
+// Rings returns the sorted names of the registered rings.
+func Rings() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.rings))
+	for k := range registry.rings {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegistryStats returns the Stats of every registered ring, keyed by name.
+func RegistryStats() map[string]Stats {
+	registry.RLock()
+	rings := make([]*Consistent, 0, len(registry.rings))
+	for _, c := range registry.rings {
+		rings = append(rings, c)
+	}
+	registry.RUnlock()
+	s := make(map[string]Stats, len(rings))
+	for _, c := range rings {
+		s[c.name] = c.Stats()
+	}
+	return s
+}
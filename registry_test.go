@@ -0,0 +1,43 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	w, err := NewNamed("metrics-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("metrics-write")
+	w.Add(newMember("a"))
+	if _, err := NewNamed("metrics-write"); !errors.Is(err, ErrRingExists) {
+		t.Errorf("duplicate name: got %v", err)
+	}
+	r := New(WithName("metrics-read"))
+	if _, ok := Lookup("metrics-read"); ok {
+		t.Error("WithName alone registered the ring")
+	}
+	if err := Register(r); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := Lookup("metrics-write"); !ok || got != w {
+		t.Error("Lookup did not find the registered ring")
+	}
+	stats := RegistryStats()
+	if s := stats["metrics-write"]; s.Ring != "metrics-write" || s.Members != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+	if len(Rings()) < 2 {
+		t.Errorf("Rings() = %v", Rings())
+	}
+	r.Close()
+	if _, ok := Lookup("metrics-read"); ok {
+		t.Error("expected Close to unregister the ring")
+	}
+}
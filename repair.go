@@ -0,0 +1,157 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Syncer compares the keys hashing into r on two replicas and repairs any
+// difference.  It is supplied by the user; the Repairer only decides what to
+// compare.
+type Syncer func(ctx context.Context, r HashRange, primary, replica lineProtocol.WriteCloser) error
+
+// RepairProgress describes the work of a Repairer.
+type RepairProgress struct {
+	Running   bool
+	Runs      int64     // completed passes
+	Tasks     int       // (range, replica pair) tasks in the current or last pass
+	Done      int       // tasks finished in the current or last pass
+	Errors    int64     // tasks that failed, over all passes
+	LastStart time.Time // start of the current or last pass
+	LastEnd   time.Time // end of the last completed pass
+}
+
+// Repairer runs anti-entropy repair over a Consistent: every arc of the
+// circle is compared between its primary and each of its other replicas, in
+// GetN order.  Adjacent arcs with the same replicas are compared as one range.
+type Repairer struct {
+	c        *Consistent
+	replicas int
+	sync     Syncer
+	mu       sync.Mutex
+	progress RepairProgress
+}
+
+// NewRepairer creates a Repairer for c with the given replication factor.  Its
+// progress is included in c.Stats().
+func NewRepairer(c *Consistent, replicas int, s Syncer) *Repairer {
+	r := &Repairer{c: c, replicas: replicas, sync: s}
+	c.lock()
+	c.repairer = r
+	c.unlock()
+	return r
+}
+
+type repairTask struct {
+	r                HashRange
+	primary, replica lineProtocol.WriteCloser
+}
+
+// tasks lists what a pass has to compare.
+func (r *Repairer) tasks() []repairTask {
+	c := r.c
+	c.rlock()
+	defer c.runlock()
+	var (
+		tasks []repairTask
+		cur   HashRange
+		set   []lineProtocol.WriteCloser
+	)
+	flush := func() {
+		for _, e := range set[1:] {
+			tasks = append(tasks, repairTask{cur, set[0], e})
+		}
+	}
+	n := len(c.sortedHashes)
+	for i, h := range c.sortedHashes {
+		prev := c.sortedHashes[(i+n-1)%n]
+		replicas := c.getN(prev, r.replicas)
+		if i > 0 && sameMembers(replicas, set) {
+			cur.End = h - 1
+			continue
+		}
+		if i > 0 {
+			flush()
+		}
+		cur, set = HashRange{Start: prev, End: h - 1}, replicas
+	}
+	if len(set) > 1 {
+		flush()
+	}
+	return tasks
+}
+
+func sameMembers(a, b []lineProtocol.WriteCloser) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Run makes one repair pass, returning early if ctx is done.
+func (r *Repairer) Run(ctx context.Context) error {
+	tasks := r.tasks()
+	r.mu.Lock()
+	r.progress.Running = true
+	r.progress.Tasks = len(tasks)
+	r.progress.Done = 0
+	r.progress.LastStart = r.c.clock.Now()
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.progress.Running = false
+		r.mu.Unlock()
+	}()
+	for _, t := range tasks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := r.sync(ctx, t.r, t.primary, t.replica)
+		r.mu.Lock()
+		r.progress.Done++
+		if err != nil {
+			r.progress.Errors++
+		}
+		r.mu.Unlock()
+	}
+	r.mu.Lock()
+	r.progress.Runs++
+	r.progress.LastEnd = r.c.clock.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// Start runs a pass every interval until ctx is done.
+func (r *Repairer) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := r.c.clock.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.Chan():
+				r.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Progress returns a copy of the repair progress.
+func (r *Repairer) Progress() RepairProgress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress
+}
@@ -0,0 +1,45 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestRepairer(t *testing.T) {
+	x := New()
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x.Add(a)
+	x.Add(b)
+	x.Add(c)
+	var (
+		covered uint64
+		failed  = errors.New("replica unreachable")
+	)
+	r := NewRepairer(x, 2, func(ctx context.Context, hr HashRange, primary, replica lineProtocol.WriteCloser) error {
+		if primary == replica {
+			t.Errorf("range %+v compared %v with itself", hr, primary)
+		}
+		covered += uint64(hr.End-hr.Start) + 1
+		if replica == c {
+			return failed
+		}
+		return nil
+	})
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if covered != 1<<32 {
+		t.Errorf("repair covered %d hashes, expected the whole circle once", covered)
+	}
+	p := x.Stats().Repair
+	if p.Runs != 1 || p.Done != p.Tasks || p.Running || p.Errors == 0 {
+		t.Errorf("got progress %+v", p)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// AddWithReplicas inserts element with n points on the circle instead of
+// NumberOfReplicas, for members on hardware that should take more or fewer
+// keys than the default.  The count stays with element until it is removed,
+// through SetReplicas, SetWeight, Snapshot and Restore.
+func (c *Consistent) AddWithReplicas(element lineProtocol.WriteCloser, n int) {
+	if n < 1 {
+		n = 1
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() || c.members[element] {
+		return
+	}
+	if c.replicas == nil {
+		c.replicas = make(map[lineProtocol.WriteCloser]int)
+	}
+	c.replicas[element] = n
+	c.add(element)
+}
+
+// SetReplicas changes NumberOfReplicas of a populated hash.  Every member
+// without a count of its own and placed by name gains or loses only the
+// points beyond the old count, so about |n-old|/max(n, old) of the keys move
+// instead of nearly all of them as with rebuilding the ring.  Ramps in
+// progress finish at once.
+func (c *Consistent) SetReplicas(n int) error {
+	if n < 1 {
+		n = 1
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("setreplicas", "", nil, c.refusal())
+	}
+	if n == c.NumberOfReplicas {
+		return nil
+	}
+	c.NumberOfReplicas = n
+	changed := false
+	for k := range c.members {
+		if _, ok := c.replicas[k]; ok || c.explicit[k] {
+			continue
+		}
+		c.stopRamp(k)
+		if c.reweigh(k) {
+			changed = true
+		}
+	}
+	if changed {
+		c.updateSortedHashes()
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSetReplicas(t *testing.T) {
+	x := New()
+	for i := 0; i < 5; i++ {
+		x.Add(newMember("member" + strconv.Itoa(i)))
+	}
+	before := make(map[string]string)
+	for i := 0; i < 10000; i++ {
+		e, _ := x.Get(strconv.Itoa(i))
+		before[strconv.Itoa(i)] = e.Name()
+	}
+	if err := x.SetReplicas(25); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.sortedHashes), 125, t)
+	if err := x.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for k, old := range before {
+		if e, _ := x.Get(k); e.Name() != old {
+			moved++
+		}
+	}
+	// a fifth of the points are new, so roughly a fifth of the keys move
+	if moved > 3000 {
+		t.Errorf("%d of 10000 keys moved going from 20 to 25 replicas", moved)
+	}
+
+	fresh := New()
+	fresh.NumberOfReplicas = 25
+	for i := 0; i < 5; i++ {
+		fresh.Add(newMember("member" + strconv.Itoa(i)))
+	}
+	for i := 0; i < 1000; i++ {
+		a, _ := x.Get(strconv.Itoa(i))
+		b, _ := fresh.Get(strconv.Itoa(i))
+		if a.Name() != b.Name() {
+			t.Fatalf("key %d on %v, expected %v like a ring built with 25 replicas", i, a, b)
+		}
+	}
+}
+
+func TestAddWithReplicas(t *testing.T) {
+	old, big := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.AddWithReplicas(old, 10)
+	x.AddWithReplicas(big, 40)
+	checkNum(len(x.vnodes[old]), 10, t)
+	checkNum(len(x.vnodes[big]), 40, t)
+
+	// the global default does not touch members with their own count
+	x.SetReplicas(30)
+	checkNum(len(x.vnodes[old]), 10, t)
+
+	y := New()
+	if err := y.Restore(x.Snapshot(), lookupIn(old, big)); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(y.vnodes[big]), 40, t)
+
+	x.Remove(old)
+	x.Add(old)
+	checkNum(len(x.vnodes[old]), 30, t)
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// routedCounts tracks how many Gets landed on each member over a sliding
+// window.  The counters live in memberState; this only keeps the window.
+type routedCounts struct {
+	window time.Duration
+	start  atomic.Int64 // unix nanoseconds the current window started
+}
+
+// WithRoutedCounts makes Get count the keys it resolves to each member over a
+// sliding window of the given length, reported by RoutedKeys.
+func WithRoutedCounts(window time.Duration) Option {
+	return func(c *Consistent) {
+		c.routed = &routedCounts{window: window}
+	}
+}
+
+// RoutedKeys is the observed routing load of one member next to the share of
+// the hash space it owns.  A member whose Share is well above its Ownership is
+// receiving skewed keys.
+type RoutedKeys struct {
+	Member    lineProtocol.WriteCloser
+	Keys      float64 // keys routed to the member over the last window
+	Share     float64 // fraction of all routed keys
+	Ownership float64 // fraction of the hash space the member owns
+}
+
+// need c.rlock() before calling
+func (c *Consistent) countRouted(element lineProtocol.WriteCloser) {
+	c.rotateRouted(c.clock.Now().UnixNano())
+	if st, ok := c.state[element]; ok {
+		st.routed[0].Add(1)
+	}
+}
+
+// rotateRouted starts a new window if the current one is over.  Only the
+// caller winning the swap of start rotates the counters.
+// need c.rlock() before calling
+func (c *Consistent) rotateRouted(now int64) {
+	r := c.routed
+	start := r.start.Load()
+	if now-start < int64(r.window) || !r.start.CompareAndSwap(start, now) {
+		return
+	}
+	stale := now-start >= 2*int64(r.window)
+	for _, st := range c.state {
+		n := st.routed[0].Swap(0)
+		if stale {
+			n = 0
+		}
+		st.routed[1].Store(n)
+	}
+}
+
+// RoutedKeys returns the routing load of every member, sorted by name, or nil
+// if the hash was not created WithRoutedCounts.  Keys weighs the previous
+// window by how much of it still overlaps the sliding window.
+func (c *Consistent) RoutedKeys() []RoutedKeys {
+	if c.routed == nil {
+		return nil
+	}
+	c.rlock()
+	defer c.runlock()
+	now := c.clock.Now().UnixNano()
+	c.rotateRouted(now)
+	elapsed := float64(now-c.routed.start.Load()) / float64(c.routed.window)
+	if elapsed > 1 {
+		elapsed = 1
+	}
+
+	owned := make(map[lineProtocol.WriteCloser]uint64, len(c.members))
+	for i, h := range c.sortedHashes {
+		prev := c.sortedHashes[(i+len(c.sortedHashes)-1)%len(c.sortedHashes)]
+		owned[c.circle[h]] += uint64(h - prev)
+	}
+	if len(c.sortedHashes) == 1 {
+		owned[c.circle[c.sortedHashes[0]]] = 1 << 32
+	}
+
+	res := make([]RoutedKeys, 0, len(c.members))
+	var total float64
+	for k := range c.members {
+		st := c.state[k]
+		n := float64(st.routed[0].Load()) + float64(st.routed[1].Load())*(1-elapsed)
+		total += n
+		res = append(res, RoutedKeys{Member: k, Keys: n, Ownership: float64(owned[k]) / (1 << 32)})
+	}
+	for i := range res {
+		if total > 0 {
+			res[i].Share = res[i].Keys / total
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Member.Name() < res[j].Member.Name() })
+	return res
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRoutedKeys(t *testing.T) {
+	x := New(WithRoutedCounts(time.Hour))
+	x.Add(newMember("abcdefg"))
+	x.Add(newMember("hijklmn"))
+	for i := 0; i < 1000; i++ {
+		x.Get(strconv.Itoa(i))
+	}
+	r := x.RoutedKeys()
+	checkNum(len(r), 2, t)
+	var keys, share, owned float64
+	for _, m := range r {
+		keys += m.Keys
+		share += m.Share
+		owned += m.Ownership
+	}
+	checkNum(int(keys), 1000, t)
+	if math.Abs(share-1) > 1e-9 || math.Abs(owned-1) > 1e-9 {
+		t.Errorf("got shares summing to %v and ownership to %v, expected 1", share, owned)
+	}
+
+	if New().RoutedKeys() != nil {
+		t.Error("expected no counts by default")
+	}
+}
+
+func TestRoutedKeysWindow(t *testing.T) {
+	x := New(WithRoutedCounts(20 * time.Millisecond))
+	x.Add(newMember("abcdefg"))
+	for i := 0; i < 100; i++ {
+		x.Get(strconv.Itoa(i))
+	}
+	time.Sleep(50 * time.Millisecond)
+	if r := x.RoutedKeys(); r[0].Keys != 0 {
+		t.Errorf("got %v keys, expected old windows to decay away", r[0].Keys)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// Algorithm selects the placement scheme behind a Router.
+type Algorithm int
+
+const (
+	// Ring places members on a hash circle with NumberOfReplicas vnodes each.
+	Ring Algorithm = iota
+	// AnchorHash uses Anchor; the capacity passed to NewRouter bounds the
+	// number of members.
+	AnchorHash
+	// DxHashAlgorithm uses DxHash; the capacity passed to NewRouter is the
+	// initial bucket count.
+	DxHashAlgorithm
+)
+
+// Router is the surface shared by every placement algorithm, so they can be
+// swapped or benchmarked against each other behind the same calls.
+type Router interface {
+	Add(element lineProtocol.WriteCloser) error
+	Remove(element lineProtocol.WriteCloser)
+	Members() []lineProtocol.WriteCloser
+	Get(name string) (lineProtocol.WriteCloser, error)
+	GetN(name string, n int) ([]lineProtocol.WriteCloser, error)
+}
+
+// NewRouter creates an empty Router using alg.  capacity is ignored by Ring.
+func NewRouter(alg Algorithm, capacity int) Router {
+	switch alg {
+	case AnchorHash:
+		return NewAnchor(capacity)
+	case DxHashAlgorithm:
+		return NewDxHash(capacity)
+	}
+	return ringRouter{New()}
+}
+
+// ringRouter adapts Consistent to Router.
+type ringRouter struct {
+	*Consistent
+}
+
+func (r ringRouter) Add(element lineProtocol.WriteCloser) error {
+	r.Consistent.Add(element)
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"hash/crc32"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// RoutingTableVersion is the newest format version of RoutingTable.  Version
+// 2 added Weights and Excluded.
+const RoutingTableVersion = 2
+
+// ErrUnsupportedVersion is the error returned when no format version both
+// sides support exists.
+var ErrUnsupportedVersion = errors.New("unsupported format version")
+
+// RoutingTable is the placement state of a hash in a compact form thin
+// clients in other languages can load to route keys exactly like Get on a
+// healthy hash.  To route a key, cut it at the first PrefixDelim if set, hash
+// it with CRC-32 (IEEE) when Hash is "crc32", and take the member of the
+// override range containing the hash, if any, or else of the first point in
+// Hashes greater than the hash, wrapping around to the first point.  From
+// version 2, a member listed in Excluded never takes a key: the key goes to
+// the owner of the next point whose member is not excluded.
+// testdata/routing_vectors.json holds a table with keys and the members they
+// must route to, for checking other implementations.
+//
+// Health, capacity, exclusion and rules are runtime state and not part of the
+// table.
+type RoutingTable struct {
+	Version     int             `json:"version"`
+	Epoch       uint64          `json:"epoch"`
+	Hash        string          `json:"hash"` // "crc32", or "" for a custom Hasher
+	PrefixDelim string          `json:"prefix_delim,omitempty"`
+	Members     []string        `json:"members"` // sorted member names
+	Hashes      []uint32        `json:"hashes"`  // sorted points on the circle
+	Owners      []uint32        `json:"owners"`  // Owners[i] indexes Members for Hashes[i]
+	Overrides   []RangeOverride `json:"overrides,omitempty"`
+	Weights     []float64       `json:"weights,omitempty"`  // v2: Weights[i] is the weight of Members[i]
+	Excluded    []uint32        `json:"excluded,omitempty"` // v2: indexes of members excluded from placement
+}
+
+// RangeOverride is an Override in a RoutingTable.  The range is inclusive.
+type RangeOverride struct {
+	Start  uint32 `json:"start"`
+	End    uint32 `json:"end"`
+	Member uint32 `json:"member"` // index into Members
+}
+
+// ExportRoutingTable returns the routing table of the hash in the newest
+// version.  Use Downgrade for clients that only support older ones.
+func (c *Consistent) ExportRoutingTable() RoutingTable {
+	c.rlock()
+	defer c.runlock()
+	t := RoutingTable{
+		Version:     RoutingTableVersion,
+		Epoch:       c.epoch,
+		PrefixDelim: c.prefixDelim,
+		Members:     make([]string, 0, len(c.members)),
+		Hashes:      append([]uint32(nil), c.sortedHashes...),
+		Owners:      make([]uint32, len(c.sortedHashes)),
+	}
+	if c.hasher == nil {
+		t.Hash = "crc32"
+	}
+	for k := range c.members {
+		t.Members = append(t.Members, k.Name())
+	}
+	sort.Strings(t.Members)
+	index := make(map[string]uint32, len(t.Members))
+	for i, m := range t.Members {
+		index[m] = uint32(i)
+	}
+	t.Weights = make([]float64, len(t.Members))
+	for k := range c.members {
+		i := index[k.Name()]
+		t.Weights[i] = 1
+		if w, ok := c.weights[k]; ok {
+			t.Weights[i] = w
+		}
+		if c.isExcluded(k) {
+			t.Excluded = append(t.Excluded, i)
+		}
+	}
+	slices.Sort(t.Excluded)
+	for i, h := range c.sortedHashes {
+		t.Owners[i] = index[c.circle[h].Name()]
+	}
+	for _, o := range c.overrides {
+		t.Overrides = append(t.Overrides, RangeOverride{Start: o.Start, End: o.End, Member: index[o.Member.Name()]})
+	}
+	return t
+}
+
+// NegotiateVersion returns the routing table version to serve a client
+// supporting versions up to newest.
+func NegotiateVersion(newest int) (int, error) {
+	if newest < 1 {
+		return 0, ErrUnsupportedVersion
+	}
+	return min(newest, RoutingTableVersion), nil
+}
+
+// Downgrade returns t in the given older version, dropping the fields that
+// version lacks.  A version 1 table cannot express exclusion, so clients on it
+// route excluded members' keys to them.
+func (t RoutingTable) Downgrade(version int) (RoutingTable, error) {
+	if version < 1 || version > t.Version {
+		return RoutingTable{}, ErrUnsupportedVersion
+	}
+	if version < 2 {
+		t.Weights, t.Excluded = nil, nil
+	}
+	t.Version = version
+	return t, nil
+}
+
+// Get returns the name of the member key routes to, as a client loading t
+// would compute it.  h must be the hasher of the exported hash, or nil for
+// CRC-32.
+func (t RoutingTable) Get(key string, h Hasher) (string, error) {
+	if len(t.Hashes) == 0 {
+		return "", ErrEmptyCircle
+	}
+	if t.PrefixDelim != "" {
+		if i := strings.Index(key, t.PrefixDelim); i >= 0 {
+			key = key[:i]
+		}
+	}
+	var x uint32
+	if h != nil {
+		x = h([]byte(key))
+	} else {
+		x = crc32.ChecksumIEEE([]byte(key))
+	}
+	excluded := func(m uint32) bool { return slices.Contains(t.Excluded, m) }
+	i := sort.Search(len(t.Overrides), func(i int) bool { return t.Overrides[i].Start > x })
+	if i > 0 && t.Overrides[i-1].End >= x && !excluded(t.Overrides[i-1].Member) {
+		return t.Members[t.Overrides[i-1].Member], nil
+	}
+	i = sort.Search(len(t.Hashes), func(i int) bool { return t.Hashes[i] > x })
+	for n := 0; n < len(t.Hashes); n++ {
+		if m := t.Owners[(i+n)%len(t.Hashes)]; !excluded(m) {
+			return t.Members[m], nil
+		}
+	}
+	return "", ErrMemberDown
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+)
+
+var updateVectors = flag.Bool("update-vectors", false, "rewrite testdata/routing_vectors.json")
+
+// routingVectors is the layout of testdata/routing_vectors.json.
+type routingVectors struct {
+	Table RoutingTable      `json:"table"`
+	Keys  map[string]string `json:"keys"` // key to the member it routes to
+}
+
+func vectorRing() *Consistent {
+	x := New(WithPrefixRouting(","))
+	x.NumberOfReplicas = 4
+	for _, n := range []string{"10.0.0.1:8086", "10.0.0.2:8086", "10.0.0.3:8086"} {
+		x.Add(newMember(n))
+	}
+	m, _ := x.GetMember("10.0.0.3:8086")
+	x.AssignRange(1<<31, 1<<31+1<<28, m)
+	return x
+}
+
+func TestRoutingVectors(t *testing.T) {
+	x := vectorRing()
+	if *updateVectors {
+		v := routingVectors{Table: x.ExportRoutingTable(), Keys: make(map[string]string)}
+		v.Table.Epoch = 0
+		for i := 0; i < 64; i++ {
+			k := fmt.Sprintf("cpu%d,host=server%d value=1", i%8, i)
+			e, _ := x.Get(k)
+			v.Keys[k] = e.Name()
+		}
+		b, _ := json.MarshalIndent(v, "", "  ")
+		if err := os.WriteFile("testdata/routing_vectors.json", append(b, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile("testdata/routing_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v routingVectors
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range v.Keys {
+		if got, _ := v.Table.Get(k, nil); got != want {
+			t.Errorf("%s: table routes to %s, expected %s", k, got, want)
+		}
+		if e, _ := x.Get(k); e.Name() != want {
+			t.Errorf("%s: hash routes to %s, expected %s", k, e.Name(), want)
+		}
+	}
+	checkNum(v.Table.Version, RoutingTableVersion, t)
+}
+
+func TestRoutingTableDowngrade(t *testing.T) {
+	x := New()
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x.Add(a)
+	x.Add(b)
+	x.Exclude(a)
+	table := x.ExportRoutingTable()
+	v1, err := table.Downgrade(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.Version != 1 || v1.Excluded != nil || v1.Weights != nil {
+		t.Errorf("got %+v, expected the version 2 fields dropped", v1)
+	}
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprintf("key%d", i)
+		if got, _ := table.Get(k, nil); got != b.name {
+			t.Fatalf("%s: got %s, expected the excluded member to be skipped", k, got)
+		}
+	}
+	if _, err := table.Downgrade(3); err != ErrUnsupportedVersion {
+		t.Errorf("got %v, expected ErrUnsupportedVersion", err)
+	}
+	if v, _ := NegotiateVersion(99); v != RoutingTableVersion {
+		t.Errorf("got %d, expected %d", v, RoutingTableVersion)
+	}
+	if _, err := NegotiateVersion(0); err != ErrUnsupportedVersion {
+		t.Errorf("got %v, expected ErrUnsupportedVersion", err)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrDropped is the error returned for a key matched by a Drop rule.
+var ErrDropped = errors.New("dropped by rule")
+
+// ErrInvalidRule is the error returned by SetRules for a rule without a
+// Matcher, or a Delegate rule without a Ring other than the hash itself.
+var ErrInvalidRule = errors.New("invalid rule")
+
+// Matcher selects the keys a Rule applies to.
+type Matcher interface {
+	Match(key string) bool
+}
+
+type matchFunc func(key string) bool
+
+func (f matchFunc) Match(key string) bool { return f(key) }
+
+// Exact matches key only.
+func Exact(key string) Matcher {
+	return matchFunc(func(k string) bool { return k == key })
+}
+
+// Prefix matches the keys starting with prefix.
+func Prefix(prefix string) Matcher {
+	return matchFunc(func(k string) bool { return strings.HasPrefix(k, prefix) })
+}
+
+// Regexp matches the keys matching the regular expression expr.
+func Regexp(expr string) (Matcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return matchFunc(re.MatchString), nil
+}
+
+// HasTag matches line protocol series keys carrying the tag name with a value
+// accepted by pred.
+func HasTag(name string, pred func(value string) bool) Matcher {
+	return matchFunc(func(k string) bool {
+		v, ok := tagValue(k, name)
+		return ok && pred(v)
+	})
+}
+
+// tagValue returns the value of the tag name in the series key k, of the form
+// measurement,tag=value,tag=value, honouring backslash escapes.
+func tagValue(k, name string) (string, bool) {
+	i := indexUnescaped(k, ',')
+	for i >= 0 {
+		k = k[i+1:]
+		end := indexUnescaped(k, ',')
+		pair := k
+		if end >= 0 {
+			pair = k[:end]
+		}
+		if sp := indexUnescaped(pair, ' '); sp >= 0 {
+			pair, end = pair[:sp], -1
+		}
+		if eq := indexUnescaped(pair, '='); eq >= 0 && pair[:eq] == name {
+			return pair[eq+1:], true
+		}
+		i = end
+	}
+	return "", false
+}
+
+func indexUnescaped(s string, b byte) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case b:
+			return i
+		}
+	}
+	return -1
+}
+
+// RuleAction is what a Rule does with the keys it matches.
+type RuleAction int
+
+const (
+	// Redirect routes matched keys to Rule.Member.
+	Redirect RuleAction = iota
+	// Delegate routes matched keys through Rule.Ring.
+	Delegate
+	// Drop fails matched keys with ErrDropped.
+	Drop
+)
+
+// Rule redirects the keys it matches before they are hashed.  A Redirect rule
+// whose Member is not in the hash is skipped.
+type Rule struct {
+	Name   string
+	Match  Matcher
+	Action RuleAction
+	Member lineProtocol.WriteCloser // for Redirect
+	Ring   *Consistent              // for Delegate; must not route back to this hash
+}
+
+// SetRules replaces the routing rules of the hash.  Get and GetN try the
+// rules in order and the first one matching a key decides its route; keys
+// matching none are hashed as usual.  Invalid rules fail with ErrInvalidRule
+// and leave the rules unchanged.
+func (c *Consistent) SetRules(rules []Rule) error {
+	for _, r := range rules {
+		if r.Match == nil || r.Action == Delegate && (r.Ring == nil || r.Ring == c) {
+			return &Error{Op: "setrules", Err: fmt.Errorf("%w %q", ErrInvalidRule, r.Name)}
+		}
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("setrules", "", nil, c.refusal())
+	}
+	c.rules = append([]Rule(nil), rules...)
+	return nil
+}
+
+// Rules returns the current routing rules.
+func (c *Consistent) Rules() []Rule {
+	c.rlock()
+	defer c.runlock()
+	return append([]Rule(nil), c.rules...)
+}
+
+// rule returns the first rule applying to key.
+// need c.rlock() before calling
+func (c *Consistent) rule(key string) (*Rule, bool) {
+	for i := range c.rules {
+		r := &c.rules[i]
+		if r.Action == Redirect && !c.members[r.Member] {
+			continue
+		}
+		if r.Match.Match(key) {
+			return r, true
+		}
+	}
+	return nil, false
+}
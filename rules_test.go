@@ -0,0 +1,93 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTagValue(t *testing.T) {
+	tests := []struct {
+		key, name, value string
+		ok               bool
+	}{
+		{"cpu,host=a,region=eu", "region", "eu", true},
+		{"cpu,host=a,region=eu", "host", "a", true},
+		{"cpu,host=a value=1", "value", "", false},
+		{`c\,pu,ho\=st=x\,y,dc=1`, `ho\=st`, `x\,y`, true},
+		{"cpu", "host", "", false},
+	}
+	for _, tt := range tests {
+		v, ok := tagValue(tt.key, tt.name)
+		if v != tt.value || ok != tt.ok {
+			t.Errorf("tagValue(%q, %q) = %q, %v, expected %q, %v", tt.key, tt.name, v, ok, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	archive := New()
+	archive.Add(c)
+	re, err := Regexp(`^tmp_`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = x.SetRules([]Rule{
+		{Name: "pin", Match: Exact("cpu,host=a"), Action: Redirect, Member: b},
+		{Name: "gone", Match: Exact("cpu,host=z"), Action: Redirect, Member: c},
+		{Name: "archive", Match: HasTag("tier", func(v string) bool { return v == "cold" }), Action: Delegate, Ring: archive},
+		{Name: "scratch", Match: re, Action: Drop},
+		{Name: "all-cpu", Match: Prefix("cpu"), Action: Redirect, Member: a},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]*member{
+		"cpu,host=a":           b,
+		"cpu,host=z":           a,
+		"mem,tier=cold,host=a": c,
+	} {
+		got, err := x.Get(key)
+		if err != nil || got != want {
+			t.Errorf("got %v, %v for %q, expected %v", got, err, key, want)
+		}
+	}
+	if _, err := x.Get("tmp_x"); !errors.Is(err, ErrDropped) {
+		t.Errorf("got %v, expected ErrDropped", err)
+	}
+	if got, _ := x.GetN("cpu,host=a", 2); len(got) != 1 || got[0] != b {
+		t.Errorf("got %v, expected [%v]", got, b)
+	}
+	if got, _, _ := x.GetTwo("cpu,host=a"); got != b {
+		t.Errorf("got GetTwo primary %v, expected %v", got, b)
+	}
+	if _, _, err := x.GetTwo("tmp_x"); !errors.Is(err, ErrDropped) {
+		t.Errorf("got %v, expected ErrDropped from GetTwo", err)
+	}
+	checkNum(len(x.Rules()), 5, t)
+}
+
+func TestSetRulesInvalid(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	for _, r := range []Rule{
+		{Name: "nomatch", Action: Drop},
+		{Name: "noring", Match: Prefix("a"), Action: Delegate},
+		{Name: "self", Match: Prefix("a"), Action: Delegate, Ring: x},
+	} {
+		if err := x.SetRules([]Rule{r}); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("%s: got %v, expected ErrInvalidRule", r.Name, err)
+		}
+	}
+	checkNum(len(x.Rules()), 0, t)
+	if _, err := x.Get("abc"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+// linkRuns rebuilds c.runEnd, which maps every index of c.sortedHashes to the
+// index of the next point owned by a different member, or to itself if one
+// member owns the whole circle.  It lets GetTwo and GetN skip a member's
+// consecutive points in one step; with heavy weights a member can hold
+// thousands of them in a row.
+// need c.lock() before calling
+func (c *Consistent) linkRuns() {
+	n := len(c.sortedHashes)
+	if cap(c.runEnd) < n {
+		c.runEnd = make([]int32, n)
+	}
+	c.runEnd = c.runEnd[:n]
+	// find a run boundary and work backwards from it around the circle
+	k := -1
+	for i := 0; i < n; i++ {
+		if c.circle[c.sortedHashes[i]] != c.circle[c.sortedHashes[(i+1)%n]] {
+			k = i
+			break
+		}
+	}
+	if k < 0 {
+		for i := range c.runEnd {
+			c.runEnd[i] = int32(i)
+		}
+		return
+	}
+	for d := 0; d < n; d++ {
+		j := (k - d + n) % n
+		next := (j + 1) % n
+		if c.circle[c.sortedHashes[j]] != c.circle[c.sortedHashes[next]] {
+			c.runEnd[j] = int32(next)
+		} else {
+			c.runEnd[j] = c.runEnd[next]
+		}
+	}
+}
+
+// walkRuns calls fn with the index after start and then with the first index
+// of every following run of points owned by one member, once around the
+// circle, while fn returns true.  Indexes skipped have the same owner as the
+// last one fn saw.
+// need c.rlock() before calling
+func (c *Consistent) walkRuns(start int, fn func(i int) bool) {
+	n := len(c.sortedHashes)
+	if n < 2 {
+		return
+	}
+	for i, dist := (start+1)%n, 1; dist < n; {
+		if !fn(i) {
+			return
+		}
+		next := int(c.runEnd[i])
+		step := (next - i + n) % n
+		if step == 0 {
+			return
+		}
+		i, dist = next, dist+step
+	}
+}
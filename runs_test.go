@@ -0,0 +1,49 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestRunsMatchLinearScan(t *testing.T) {
+	x := New()
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x.Add(a)
+	x.Add(b)
+	x.Add(c)
+	x.SetWeight(a, 50)
+	for i := 0; i < 2000; i++ {
+		k := fmt.Sprintf("key%d", i)
+		got, err := x.GetN(k, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the preference order by scanning every point
+		x.rlock()
+		start := x.search(x.keyHash(k))
+		var want []string
+		for j := 0; j < len(x.sortedHashes) && len(want) < 3; j++ {
+			e := x.circle[x.sortedHashes[(start+j)%len(x.sortedHashes)]]
+			if !slices.Contains(want, e.Name()) {
+				want = append(want, e.Name())
+			}
+		}
+		x.runlock()
+		var gotNames []string
+		for _, e := range got {
+			gotNames = append(gotNames, e.Name())
+		}
+		if fmt.Sprint(gotNames) != fmt.Sprint(want) {
+			t.Fatalf("%s: got %v, expected %v", k, gotNames, want)
+		}
+		first, second, _ := x.GetTwo(k)
+		if first.Name() != want[0] || second.Name() != want[1] {
+			t.Fatalf("%s: GetTwo got %s %s, expected %v", k, first.Name(), second.Name(), want[:2])
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DefaultScatterBuffer is the per-member buffer ScatterStream uses when given a
+// size <= 0.
+const DefaultScatterBuffer = 64 << 10
+
+// ScatterResult is what ScatterStream sent to one member.
+type ScatterResult struct {
+	Member lineProtocol.WriteCloser
+	Lines  int   // lines written
+	Bytes  int64 // bytes written
+	Err    error // first write error; the member's later lines are dropped
+}
+
+// ScatterStream reads line protocol from r and writes each line to the member
+// Get routes its series key to, the line up to its first unescaped space.
+// Lines are gathered in a buffer of bufSize bytes per member and written in
+// whole lines when it fills and at the end, so memory stays bounded by the
+// buffers and the longest line however large the batch.  A line longer than
+// the buffer is written on its own.  Empty lines and comments are skipped.
+//
+// It returns the results per member sorted by name, the number of lines that
+// could not be routed or were dropped after a write error, and the first
+// error reading r.
+func (c *Consistent) ScatterStream(r io.Reader, bufSize int) ([]ScatterResult, int, error) {
+	defer c.exit(c.enter())
+	if bufSize <= 0 {
+		bufSize = DefaultScatterBuffer
+	}
+	type sink struct {
+		res ScatterResult
+		buf []byte
+		n   int // lines in buf
+	}
+	sinks := make(map[lineProtocol.WriteCloser]*sink)
+	dropped := 0
+	flush := func(s *sink, p []byte, lines int) {
+		if len(p) == 0 {
+			return
+		}
+		if s.res.Err != nil {
+			dropped += lines
+			return
+		}
+		start := time.Now()
+		_, err := c.write(s.res.Member, p)
+		c.recordWrite(s.res.Member, err, time.Since(start))
+		if err != nil {
+			s.res.Err = err
+			dropped += lines
+			return
+		}
+		s.res.Lines += lines
+		s.res.Bytes += int64(len(p))
+	}
+
+	br := bufio.NewReaderSize(r, bufSize)
+	var long []byte
+	var rerr error
+	for rerr == nil {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			long = append(long, line...)
+			continue
+		}
+		if err != nil && err != io.EOF {
+			rerr = err
+			break
+		}
+		if long != nil {
+			line = append(long, line...)
+			long = nil
+		}
+		if err == io.EOF {
+			rerr = io.EOF
+			if len(line) == 0 {
+				break
+			}
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+		}
+		body := bytes.TrimSpace(line)
+		if len(body) == 0 || body[0] == '#' {
+			continue
+		}
+		key := string(body)
+		if i := indexUnescaped(key, ' '); i >= 0 {
+			key = key[:i]
+		}
+		e, gerr := c.Get(key)
+		if gerr != nil {
+			dropped++
+			continue
+		}
+		s, ok := sinks[e]
+		if !ok {
+			s = &sink{res: ScatterResult{Member: e}}
+			sinks[e] = s
+		}
+		if len(s.buf)+len(line) > bufSize {
+			flush(s, s.buf, s.n)
+			s.buf, s.n = s.buf[:0], 0
+		}
+		if len(line) > bufSize {
+			flush(s, line, 1)
+			continue
+		}
+		s.buf = append(s.buf, line...)
+		s.n++
+	}
+	res := make([]ScatterResult, 0, len(sinks))
+	for _, s := range sinks {
+		flush(s, s.buf, s.n)
+		res = append(res, s.res)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Member.Name() < res[j].Member.Name() })
+	if errors.Is(rerr, io.EOF) {
+		rerr = nil
+	}
+	return res, dropped, rerr
+}
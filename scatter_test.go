@@ -0,0 +1,60 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScatterStream(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	var in strings.Builder
+	want := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("cpu,host=server%d", i%20)
+		fmt.Fprintf(&in, "%s value=%d %d\n", key, i, i)
+		e, _ := x.Get(key)
+		want[e.Name()]++
+	}
+	in.WriteString("\n# a comment\nmem,host=x value=1") // no final newline
+
+	res, dropped, err := x.ScatterStream(strings.NewReader(in.String()), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(dropped, 0, t)
+	e, _ := x.Get("mem,host=x")
+	want[e.Name()]++
+	total := 0
+	for _, r := range res {
+		checkNum(r.Lines, want[r.Member.Name()], t)
+		m := r.Member.(*member)
+		if got := strings.Count(m.String(), "\n"); got != r.Lines {
+			t.Errorf("%s got %d lines, expected %d", m.name, got, r.Lines)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(m.String()), "\n") {
+			if got, _ := x.Get(line[:strings.IndexByte(line, ' ')]); got != m {
+				t.Errorf("%q went to %s", line, m.name)
+			}
+		}
+		total += r.Lines
+	}
+	checkNum(total, 201, t)
+
+	b.err = errors.New("broken pipe")
+	res, dropped, _ = x.ScatterStream(strings.NewReader(in.String()), 0)
+	for _, r := range res {
+		if r.Member == b && r.Err == nil {
+			t.Error("expected the write error to be reported")
+		}
+	}
+	checkNum(dropped, want[b.name], t)
+}
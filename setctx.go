@@ -0,0 +1,185 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrNilMember is the error returned when a nil element is passed as a member.
+var ErrNilMember = errors.New("nil member")
+
+// ErrMinMembers is the error returned when a change would leave fewer members
+// than WithMinMembers allows.
+var ErrMinMembers = errors.New("too few members")
+
+// ringState is a complete circle built off to the side by SetCtx.
+type ringState struct {
+	circle     map[uint32]lineProtocol.WriteCloser
+	members    map[lineProtocol.WriteCloser]bool
+	vnodes     map[lineProtocol.WriteCloser][]uint32
+	explicit   map[lineProtocol.WriteCloser]bool
+	sorted     uints
+	removed    []lineProtocol.WriteCloser
+	collisions int // points of new members moved off taken ones
+}
+
+// SetCtx is like Set, but builds the new circle without blocking readers and
+// swaps it in all at once.  If ctx is done or the validator configured with
+// WithValidator rejects an element, the hash is left exactly as it was and the
+// error is returned.  ErrMinMembers is returned if the change would leave
+// fewer members than WithMinMembers allows.  Members kept from the current
+// circle keep their points.
+func (c *Consistent) SetCtx(ctx context.Context, elements []lineProtocol.WriteCloser) error {
+	for {
+		start := time.Now()
+		c.rlock()
+		epoch := c.epoch
+		r, err := c.build(ctx, elements)
+		c.runlock()
+		if err != nil {
+			return err
+		}
+		c.lock()
+		if !c.allowMutation() {
+			err := c.opError("set", "", nil, c.refusal())
+			c.unlock()
+			return err
+		}
+		if !c.allowShrink(len(r.members)) {
+			err := c.opError("set", "", nil, ErrMinMembers)
+			c.unlock()
+			return err
+		}
+		if c.epoch == epoch {
+			c.swap(r, time.Since(start))
+			c.unlock()
+			return nil
+		}
+		// someone changed the circle while we were building; start over
+		c.unlock()
+	}
+}
+
+// need c.rlock() before calling
+func (c *Consistent) build(ctx context.Context, elements []lineProtocol.WriteCloser) (*ringState, error) {
+	r := &ringState{
+		circle:   make(map[uint32]lineProtocol.WriteCloser, len(elements)*c.NumberOfReplicas),
+		members:  make(map[lineProtocol.WriteCloser]bool, len(elements)),
+		vnodes:   make(map[lineProtocol.WriteCloser][]uint32, len(elements)),
+		explicit: make(map[lineProtocol.WriteCloser]bool),
+	}
+	// members kept from the current circle go first, so they keep their
+	// points when a new member's collide with them
+	var fresh []lineProtocol.WriteCloser
+	for i, e := range elements {
+		if i%64 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if e == nil {
+			return nil, ErrNilMember
+		}
+		if r.members[e] {
+			continue
+		}
+		hashes, ok := c.vnodes[e]
+		if !ok {
+			if c.validate != nil {
+				if err := c.validate(e); err != nil {
+					return nil, err
+				}
+			}
+			fresh = append(fresh, e)
+			r.members[e] = true
+			continue
+		}
+		for _, h := range hashes {
+			r.circle[h] = e
+		}
+		r.members[e] = true
+		r.vnodes[e] = hashes
+		if c.explicit[e] {
+			r.explicit[e] = true
+		}
+	}
+	for _, e := range fresh {
+		hashes := c.derivedHashes(e)
+		r.collisions += c.resolve(e, hashes, 0, r.circle)
+		for _, h := range hashes {
+			r.circle[h] = e
+		}
+		r.vnodes[e] = hashes
+	}
+	for k := range c.members {
+		if !r.members[k] {
+			r.removed = append(r.removed, k)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.sorted = make(uints, 0, len(r.circle))
+	for h := range r.circle {
+		r.sorted = append(r.sorted, h)
+	}
+	slices.Sort(r.sorted)
+	return r, nil
+}
+
+// need c.lock() before calling
+func (c *Consistent) swap(r *ringState, d time.Duration) {
+	for _, k := range c.install(r) {
+		c.bus.emit(MemberAdded{Member: k, Generation: c.generation(k)})
+	}
+	for _, k := range r.removed {
+		c.bus.emit(MemberRemoved{Member: k, Generation: c.generation(k)})
+	}
+	c.rebuilt(d)
+}
+
+// install makes r the circle of the hash without emitting any event or
+// advancing the epoch, and returns the members it added.
+// need c.lock() before calling
+func (c *Consistent) install(r *ringState) []lineProtocol.WriteCloser {
+	for _, k := range r.removed {
+		c.stopRamp(k)
+		delete(c.capacities, k)
+		delete(c.weights, k)
+		delete(c.replicas, k)
+		c.removeAliases(k)
+		c.removeOverrides(k)
+	}
+	c.circle = r.circle
+	c.members = r.members
+	c.vnodes = r.vnodes
+	c.explicit = r.explicit
+	c.sortedHashes = r.sorted
+	c.linkRuns()
+	state := make(map[lineProtocol.WriteCloser]*memberState, len(r.members))
+	var added []lineProtocol.WriteCloser
+	for k := range r.members {
+		if st, ok := c.state[k]; ok {
+			state[k] = st
+		} else {
+			state[k] = c.newState()
+			c.unreserve(k)
+			c.joined(k)
+			added = append(added, k)
+		}
+	}
+	c.state = state
+	c.count = int64(len(r.members))
+	c.stats.Collisions += int64(r.collisions)
+	c.recordChurn(churnAdd, int64(len(added)))
+	c.recordChurn(churnRemove, int64(len(r.removed)))
+	return added
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestSetCtx(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	x.AssignRange(0, 10, a)
+	if err := x.SetCtx(context.Background(), []lineProtocol.WriteCloser{b, c}); err != nil {
+		t.Fatal(err)
+	}
+	y := New()
+	y.Set([]lineProtocol.WriteCloser{b, c})
+	checkNum(len(x.circle), 40, t)
+	checkNum(len(x.Members()), 2, t)
+	checkNum(len(x.Overrides()), 0, t)
+	for _, k := range []string{"ggg", "hhh", "iiiii"} {
+		want, _ := y.Get(k)
+		if got, _ := x.Get(k); got != want {
+			t.Errorf("%s: got %v, expected %v", k, got, want)
+		}
+	}
+}
+
+func TestSetCtxRollback(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	rejected := errors.New("rejected")
+	x := New(WithValidator(func(e lineProtocol.WriteCloser) error {
+		if e == b {
+			return rejected
+		}
+		return nil
+	}))
+	x.Add(a)
+	epoch := x.epoch
+	if err := x.SetCtx(context.Background(), []lineProtocol.WriteCloser{b}); err != rejected {
+		t.Errorf("got %v, expected validator error", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := x.SetCtx(ctx, []lineProtocol.WriteCloser{a, newMember("opqrstu")}); err != context.Canceled {
+		t.Errorf("got %v, expected context canceled", err)
+	}
+	if err := x.SetCtx(context.Background(), []lineProtocol.WriteCloser{nil}); !errors.Is(err, ErrNilMember) {
+		t.Errorf("got %v, expected nil member error", err)
+	}
+	if x.epoch != epoch {
+		t.Errorf("failed SetCtx changed the circle")
+	}
+	checkNum(len(x.circle), 20, t)
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sync/atomic"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ShadowStats counts how often the shadow ring set WithShadow agreed with the
+// hash.
+type ShadowStats struct {
+	Agree    int64 // both chose a member with the same name
+	Disagree int64 // they chose different members, or only one found a member
+}
+
+// Rate returns the share of keys on which the rings agreed, or 1 if nothing
+// was compared.
+func (s ShadowStats) Rate() float64 {
+	if n := s.Agree + s.Disagree; n > 0 {
+		return float64(s.Agree) / float64(n)
+	}
+	return 1
+}
+
+type shadow struct {
+	ring            *Consistent
+	agree, disagree atomic.Int64
+}
+
+// WithShadow makes Get also resolve every key on ring, for example one built
+// with a new hash function, and count whether the two agree, while still
+// routing by the hash itself.  Members are compared by name.  Use it to
+// measure how many keys a migration would move using production keys before
+// switching over.
+func WithShadow(ring *Consistent) Option {
+	return func(c *Consistent) {
+		c.shadow = &shadow{ring: ring}
+	}
+}
+
+func (c *Consistent) compareShadow(name string, e lineProtocol.WriteCloser, err error) {
+	s, serr := c.shadow.ring.Get(name)
+	switch {
+	case err != nil && serr != nil:
+		c.shadow.agree.Add(1)
+	case err != nil || serr != nil || e.Name() != s.Name():
+		c.shadow.disagree.Add(1)
+	default:
+		c.shadow.agree.Add(1)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestShadow(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	same := New()
+	same.Add(a)
+	same.Add(b)
+	x := New(WithShadow(same))
+	x.Add(a)
+	x.Add(b)
+	for i := 0; i < 100; i++ {
+		x.Get(strconv.Itoa(i))
+	}
+	if s := x.Stats().Shadow; s.Agree != 100 || s.Rate() != 1 {
+		t.Errorf("got %+v, expected full agreement", s)
+	}
+
+	// the shadow uses another hash function, some keys move
+	other := New(WithHasher128(MD5, FoldXor))
+	other.Add(a)
+	other.Add(b)
+	y := New(WithShadow(other))
+	y.Add(a)
+	y.Add(b)
+	for i := 0; i < 1000; i++ {
+		if e, err := y.Get(strconv.Itoa(i)); err != nil || e == nil {
+			t.Fatal(err)
+		}
+	}
+	s := y.Stats().Shadow
+	checkNum(int(s.Agree+s.Disagree), 1000, t)
+	if s.Disagree == 0 || s.Agree == 0 {
+		t.Errorf("got %+v, expected a mix of agreement and disagreement", s)
+	}
+}
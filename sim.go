@@ -0,0 +1,142 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// SimStep is one step of a Simulation: the membership changes to apply and
+// then the traffic to route.
+type SimStep struct {
+	Add     []string // members to add
+	Remove  []string // members to remove
+	Fail    []string // members to mark down
+	Recover []string // members to mark up again
+	Keys    int      // keys to draw from the key space, ignored with a Trace
+}
+
+// Simulation drives a Router through scripted membership churn and traffic
+// and reports how keys moved and how load was spread after every step.  Runs
+// with the same Seed, steps and Router are identical.
+//
+// Routers that cannot mark members down, unlike Ring routers, have failed
+// members removed and recovered ones added back.
+type Simulation struct {
+	Seed     int64
+	Router   func() Router // creates the empty Router to simulate
+	Steps    []SimStep
+	KeySpace int      // distinct generated keys, drawn with a Zipf distribution
+	Trace    []string // real keys to route at every step instead of generated ones
+}
+
+// SimReport describes the state of the Router after one step.
+type SimReport struct {
+	Step      int
+	Members   int
+	Routed    int            // keys routed
+	Failed    int            // keys that found no member
+	Remapped  int            // distinct keys whose member changed since they were last routed
+	Load      map[string]int // keys routed to each member
+	Imbalance float64        // highest load over mean load, 1 when perfectly even
+}
+
+// simMember is the writer standing in for every simulated member.
+type simMember string
+
+func (m simMember) Name() string                { return string(m) }
+func (m simMember) Write(p []byte) (int, error) { return len(p), nil }
+func (m simMember) Close() error                { return nil }
+
+type downMarker interface {
+	MarkDown(element lineProtocol.WriteCloser)
+	MarkUp(element lineProtocol.WriteCloser) error
+}
+
+// Run runs the simulation and returns a report per step.
+func (s Simulation) Run() []SimReport {
+	rng := rand.New(rand.NewSource(s.Seed))
+	space := s.KeySpace
+	if space < 1 {
+		space = 1
+	}
+	zipf := rand.NewZipf(rng, 1.1, 1, uint64(space-1))
+	r := s.Router()
+	marker, canMark := r.(downMarker)
+	members := make(map[string]simMember)
+	owners := make(map[string]string)
+
+	reports := make([]SimReport, 0, len(s.Steps))
+	for i, step := range s.Steps {
+		for _, name := range step.Add {
+			members[name] = simMember(name)
+			r.Add(members[name])
+		}
+		for _, name := range step.Remove {
+			r.Remove(members[name])
+			delete(members, name)
+		}
+		for _, name := range step.Fail {
+			if canMark {
+				marker.MarkDown(members[name])
+			} else {
+				r.Remove(members[name])
+			}
+		}
+		for _, name := range step.Recover {
+			if canMark {
+				marker.MarkUp(members[name])
+			} else {
+				r.Add(members[name])
+			}
+		}
+
+		keys := s.Trace
+		if keys == nil {
+			keys = make([]string, step.Keys)
+			for j := range keys {
+				keys[j] = fmt.Sprintf("key-%d", zipf.Uint64())
+			}
+		}
+		rep := SimReport{Step: i, Members: len(r.Members()), Load: make(map[string]int)}
+		moved := make(map[string]bool)
+		for _, k := range keys {
+			rep.Routed++
+			e, err := r.Get(k)
+			if err != nil {
+				rep.Failed++
+				continue
+			}
+			name := e.Name()
+			rep.Load[name]++
+			if prev, ok := owners[k]; ok && prev != name {
+				moved[k] = true
+			}
+			owners[k] = name
+		}
+		rep.Remapped = len(moved)
+		rep.Imbalance = imbalance(rep.Load, rep.Members)
+		reports = append(reports, rep)
+	}
+	return reports
+}
+
+func imbalance(load map[string]int, members int) float64 {
+	if members == 0 || len(load) == 0 {
+		return 0
+	}
+	counts := make([]int, 0, len(load))
+	total := 0
+	for _, n := range load {
+		counts = append(counts, n)
+		total += n
+	}
+	sort.Ints(counts)
+	return float64(counts[len(counts)-1]) / (float64(total) / float64(members))
+}
@@ -0,0 +1,51 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimulation(t *testing.T) {
+	s := Simulation{
+		Seed:     42,
+		Router:   func() Router { return NewRouter(Ring, 0) },
+		KeySpace: 1000,
+		Steps: []SimStep{
+			{Add: []string{"a", "b", "c"}, Keys: 5000},
+			{Keys: 5000},
+			{Fail: []string{"b"}, Keys: 5000},
+			{Recover: []string{"b"}, Keys: 5000},
+			{Remove: []string{"c"}, Keys: 5000},
+		},
+	}
+	r := s.Run()
+	checkNum(len(r), 5, t)
+	checkNum(r[0].Members, 3, t)
+	checkNum(r[1].Remapped, 0, t)
+	if r[2].Remapped == 0 || r[2].Load["b"] != 0 {
+		t.Errorf("got %d remapped and %d on b, expected b's keys to fail over", r[2].Remapped, r[2].Load["b"])
+	}
+	if r[3].Load["b"] == 0 {
+		t.Error("expected b to take keys again once recovered")
+	}
+	checkNum(r[4].Members, 2, t)
+	if !reflect.DeepEqual(r, s.Run()) {
+		t.Error("expected runs with the same seed to be identical")
+	}
+}
+
+func TestSimulationTrace(t *testing.T) {
+	s := Simulation{
+		Router: func() Router { return NewRouter(AnchorHash, 8) },
+		Trace:  []string{"cpu", "mem", "disk", "net"},
+		Steps:  []SimStep{{Add: []string{"a", "b"}}, {Fail: []string{"a"}}},
+	}
+	r := s.Run()
+	checkNum(r[0].Routed, 4, t)
+	checkNum(r[1].Members, 1, t)
+	checkNum(r[1].Load["b"], 4, t)
+}
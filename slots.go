@@ -0,0 +1,327 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// SlotCount is the number of slots in a SlotTable, as in Redis Cluster.
+const SlotCount = 16384
+
+// ErrUnassignedSlot is the error returned when a key hashes to a slot that has
+// no owner.
+var ErrUnassignedSlot = errors.New("unassigned slot")
+
+// ErrSlotMigrating is the error returned when changing the owner of a slot
+// that is being migrated.
+var ErrSlotMigrating = errors.New("slot is migrating")
+
+// ErrSlotNotMigrating is the error returned when completing or aborting the
+// migration of a slot that is not being migrated.
+var ErrSlotNotMigrating = errors.New("slot is not migrating")
+
+// ErrInvalidSlot is the error returned for slots >= SlotCount.
+var ErrInvalidSlot = errors.New("invalid slot")
+
+// Migration describes a slot moving between members.  From is in the
+// MIGRATING state and To in the IMPORTING state for the slot: From still owns
+// it, but keys it no longer holds should be looked up on To.
+type Migration struct {
+	Slot uint16
+	From lineProtocol.WriteCloser
+	To   lineProtocol.WriteCloser
+}
+
+// SlotInfo is the routing state of one key.
+type SlotInfo struct {
+	Slot      uint16
+	Owner     lineProtocol.WriteCloser
+	Importing lineProtocol.WriteCloser // non-nil while the slot migrates
+}
+
+// SlotTable is a Redis Cluster style placement: keys hash to one of SlotCount
+// slots and every slot is explicitly assigned to a member.  Slots move one at
+// a time through BeginMigration and CompleteMigration, which makes
+// rebalancing resumable and observable.
+type SlotTable struct {
+	owners     [SlotCount]lineProtocol.WriteCloser
+	migrations map[uint16]lineProtocol.WriteCloser
+	sync.RWMutex
+}
+
+// NewSlotTable creates a SlotTable with no slots assigned.
+func NewSlotTable() *SlotTable {
+	return &SlotTable{migrations: make(map[uint16]lineProtocol.WriteCloser)}
+}
+
+// KeySlot returns the slot of key: CRC16 (XMODEM) of the key modulo SlotCount.
+// As in Redis, if the key contains a non-empty {tag} only the tag is hashed,
+// so keys sharing a tag share a slot.
+func KeySlot(key string) uint16 {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			key = key[i+1 : i+1+j]
+		}
+	}
+	return crc16(key) % SlotCount
+}
+
+// Assign makes element the owner of slots [from, to].
+func (t *SlotTable) Assign(from, to uint16, element lineProtocol.WriteCloser) error {
+	t.Lock()
+	defer t.Unlock()
+	if from > to {
+		return ErrInvalidRange
+	}
+	if to >= SlotCount {
+		return ErrInvalidSlot
+	}
+	for s := from; s <= to; s++ {
+		if _, ok := t.migrations[s]; ok {
+			return ErrSlotMigrating
+		}
+	}
+	for s := int(from); s <= int(to); s++ {
+		t.owners[s] = element
+	}
+	return nil
+}
+
+// Owner returns the owner of slot, or nil if it is unassigned.
+func (t *SlotTable) Owner(slot uint16) lineProtocol.WriteCloser {
+	t.RLock()
+	defer t.RUnlock()
+	if slot >= SlotCount {
+		return nil
+	}
+	return t.owners[slot]
+}
+
+// Get returns the owner of the slot name hashes to.
+func (t *SlotTable) Get(name string) (lineProtocol.WriteCloser, error) {
+	info, err := t.Lookup(name)
+	return info.Owner, err
+}
+
+// Lookup returns the slot, owner and any importing member for name.
+func (t *SlotTable) Lookup(name string) (SlotInfo, error) {
+	t.RLock()
+	defer t.RUnlock()
+	info := SlotInfo{Slot: KeySlot(name)}
+	info.Owner = t.owners[info.Slot]
+	info.Importing = t.migrations[info.Slot]
+	if info.Owner == nil {
+		return info, ErrUnassignedSlot
+	}
+	return info, nil
+}
+
+// BeginMigration marks slot as migrating from its owner to element.
+func (t *SlotTable) BeginMigration(slot uint16, element lineProtocol.WriteCloser) error {
+	t.Lock()
+	defer t.Unlock()
+	if slot >= SlotCount {
+		return ErrInvalidSlot
+	}
+	if t.owners[slot] == nil {
+		return ErrUnassignedSlot
+	}
+	if _, ok := t.migrations[slot]; ok {
+		return ErrSlotMigrating
+	}
+	t.migrations[slot] = element
+	return nil
+}
+
+// CompleteMigration hands slot to the member it was migrating to.
+func (t *SlotTable) CompleteMigration(slot uint16) error {
+	t.Lock()
+	defer t.Unlock()
+	to, ok := t.migrations[slot]
+	if !ok {
+		return ErrSlotNotMigrating
+	}
+	t.owners[slot] = to
+	delete(t.migrations, slot)
+	return nil
+}
+
+// AbortMigration leaves slot with its current owner.
+func (t *SlotTable) AbortMigration(slot uint16) error {
+	t.Lock()
+	defer t.Unlock()
+	if _, ok := t.migrations[slot]; !ok {
+		return ErrSlotNotMigrating
+	}
+	delete(t.migrations, slot)
+	return nil
+}
+
+// Migrations returns the migrations in progress ordered by slot.
+func (t *SlotTable) Migrations() []Migration {
+	t.RLock()
+	defer t.RUnlock()
+	m := make([]Migration, 0, len(t.migrations))
+	for s, to := range t.migrations {
+		m = append(m, Migration{Slot: s, From: t.owners[s], To: to})
+	}
+	sort.Slice(m, func(i, j int) bool { return m[i].Slot < m[j].Slot })
+	return m
+}
+
+// Slots returns the slots owned by element in ascending order.
+func (t *SlotTable) Slots(element lineProtocol.WriteCloser) []uint16 {
+	t.RLock()
+	defer t.RUnlock()
+	var s []uint16
+	for i, o := range t.owners {
+		if o == element {
+			s = append(s, uint16(i))
+		}
+	}
+	return s
+}
+
+// Members returns the distinct owners of slots.
+func (t *SlotTable) Members() []lineProtocol.WriteCloser {
+	t.RLock()
+	defer t.RUnlock()
+	var m []lineProtocol.WriteCloser
+	for _, o := range t.owners {
+		if o != nil && !sliceContainsMember(m, o) {
+			m = append(m, o)
+		}
+	}
+	return m
+}
+
+var crc16Table = func() (t [256]uint16) {
+	for i := range t {
+		c := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x1021
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return
+}()
+
+// crc16 is CRC-16/XMODEM, the checksum Redis Cluster uses for key slots.
+func crc16(s string) uint16 {
+	var c uint16
+	for i := 0; i < len(s); i++ {
+		c = c<<8 ^ crc16Table[byte(c>>8)^s[i]]
+	}
+	return c
+}
+
+// SlotTableExport is the serializable form of a SlotTable: owners as
+// compacted ranges of slots, plus migrations in progress.  Members are
+// recorded by name.
+type SlotTableExport struct {
+	Ranges     []SlotRange       `json:"ranges"`
+	Migrations []MigrationExport `json:"migrations,omitempty"`
+}
+
+// SlotRange assigns slots [Start, End] to Member.
+type SlotRange struct {
+	Start  uint16 `json:"start"`
+	End    uint16 `json:"end"`
+	Member string `json:"member"`
+}
+
+// MigrationExport is the serializable form of a Migration.
+type MigrationExport struct {
+	Slot uint16 `json:"slot"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Export returns the full assignment table.
+func (t *SlotTable) Export() SlotTableExport {
+	t.RLock()
+	defer t.RUnlock()
+	var e SlotTableExport
+	for s := 0; s < SlotCount; {
+		o := t.owners[s]
+		end := s
+		for end+1 < SlotCount && t.owners[end+1] == o {
+			end++
+		}
+		if o != nil {
+			e.Ranges = append(e.Ranges, SlotRange{Start: uint16(s), End: uint16(end), Member: o.Name()})
+		}
+		s = end + 1
+	}
+	for s, to := range t.migrations {
+		e.Migrations = append(e.Migrations, MigrationExport{Slot: s, From: t.owners[s].Name(), To: to.Name()})
+	}
+	sort.Slice(e.Migrations, func(i, j int) bool { return e.Migrations[i].Slot < e.Migrations[j].Slot })
+	return e
+}
+
+// Import replaces the assignment table with e, using lookup to turn names into
+// writers.  Every writer is looked up once per name.  The table is left
+// unchanged if e is invalid or lookup fails.
+func (t *SlotTable) Import(e SlotTableExport, lookup func(name string) (lineProtocol.WriteCloser, error)) error {
+	byName := make(map[string]lineProtocol.WriteCloser)
+	resolve := func(name string) (lineProtocol.WriteCloser, error) {
+		if w, ok := byName[name]; ok {
+			return w, nil
+		}
+		w, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		byName[name] = w
+		return w, nil
+	}
+	var owners [SlotCount]lineProtocol.WriteCloser
+	for _, r := range e.Ranges {
+		if r.Start > r.End {
+			return ErrInvalidRange
+		}
+		if r.End >= SlotCount {
+			return ErrInvalidSlot
+		}
+		w, err := resolve(r.Member)
+		if err != nil {
+			return err
+		}
+		for s := int(r.Start); s <= int(r.End); s++ {
+			owners[s] = w
+		}
+	}
+	migrations := make(map[uint16]lineProtocol.WriteCloser, len(e.Migrations))
+	for _, m := range e.Migrations {
+		if m.Slot >= SlotCount {
+			return ErrInvalidSlot
+		}
+		if owners[m.Slot] == nil || owners[m.Slot].Name() != m.From {
+			return ErrUnknownMember
+		}
+		w, err := resolve(m.To)
+		if err != nil {
+			return err
+		}
+		migrations[m.Slot] = w
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.owners = owners
+	t.migrations = migrations
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestKeySlot(t *testing.T) {
+	// values from the Redis Cluster specification and CLUSTER KEYSLOT
+	if got := crc16("123456789"); got != 0x31c3 {
+		t.Errorf("crc16: got %#x, expected 0x31c3", got)
+	}
+	if got := KeySlot("foo"); got != 12182 {
+		t.Errorf("got slot %d for foo, expected 12182", got)
+	}
+	if KeySlot("{user1000}.following") != KeySlot("{user1000}.followers") {
+		t.Errorf("expected keys with the same hash tag to share a slot")
+	}
+	if KeySlot("foo{}{bar}") != crc16("foo{}{bar}")%SlotCount {
+		t.Errorf("expected an empty tag to hash the whole key")
+	}
+}
+
+func TestSlotMigration(t *testing.T) {
+	x := NewSlotTable()
+	a, b := newMember("a"), newMember("b")
+	if _, err := x.Get("foo"); !errors.Is(err, ErrUnassignedSlot) {
+		t.Errorf("expected unassigned slot error, got %v", err)
+	}
+	x.Assign(0, SlotCount/2-1, a)
+	x.Assign(SlotCount/2, SlotCount-1, b)
+	slot := KeySlot("foo")
+	if got, _ := x.Get("foo"); got != b {
+		t.Errorf("got %v, expected b", got)
+	}
+	if err := x.BeginMigration(slot, a); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := x.Lookup("foo"); info.Owner != b || info.Importing != a {
+		t.Errorf("got %+v during migration, expected owner b importing a", info)
+	}
+	if err := x.Assign(slot, slot, a); !errors.Is(err, ErrSlotMigrating) {
+		t.Errorf("expected slot migrating error, got %v", err)
+	}
+	if m := x.Migrations(); len(m) != 1 || m[0].From != b || m[0].To != a {
+		t.Errorf("got migrations %v", m)
+	}
+	if err := x.CompleteMigration(slot); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := x.Get("foo"); got != a {
+		t.Errorf("got %v after migration, expected a", got)
+	}
+	if err := x.AbortMigration(slot); !errors.Is(err, ErrSlotNotMigrating) {
+		t.Errorf("expected slot not migrating error, got %v", err)
+	}
+	checkNum(len(x.Slots(b)), SlotCount/2-1, t)
+}
+
+func TestSlotExportImport(t *testing.T) {
+	a, b := newMember("a"), newMember("b")
+	x := NewSlotTable()
+	x.Assign(0, 99, a)
+	x.Assign(100, SlotCount-1, b)
+	x.BeginMigration(5, b)
+	buf, err := json.Marshal(x.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e SlotTableExport
+	if err := json.Unmarshal(buf, &e); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(e.Ranges), 2, t)
+	y := NewSlotTable()
+	if err := y.Import(e, lookupIn(a, b)); err != nil {
+		t.Fatal(err)
+	}
+	if y.Owner(99) != a || y.Owner(100) != b {
+		t.Errorf("slot owners not restored")
+	}
+	if m := y.Migrations(); len(m) != 1 || m[0].Slot != 5 || m[0].To != b {
+		t.Errorf("got migrations %v, expected slot 5 to b", m)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Snapshot is a serializable description of a Consistent.  Members are
+// recorded by MemberID, their name unless they have a stable ID; Restore
+// resolves them back to writers, so members identified by ID can be restored
+// across address changes.  Tokens holds the circle positions of members that
+// were not placed by hashing their ID, and Weights and Replicas the weights
+// and point counts of members given their own.  Probes holds, by vnode index,
+// the points members took instead of ones another member held.  Excluded
+// lists the members excluded from primary placement and Aliases maps every
+// alias to the ID of its member.  Members still ramping up are recorded at
+// full weight.
+type Snapshot struct {
+	NumberOfReplicas int                       `json:"replicas"`
+	Members          []string                  `json:"members"`
+	Tokens           map[string][]uint32       `json:"tokens,omitempty"`
+	Weights          map[string]float64        `json:"weights,omitempty"`
+	Replicas         map[string]int            `json:"member_replicas,omitempty"`
+	Overrides        []OverrideSnapshot        `json:"overrides,omitempty"`
+	Excluded         []string                  `json:"excluded,omitempty"`
+	Aliases          map[string]string         `json:"aliases,omitempty"`
+	Probes           map[string]map[int]uint32 `json:"probes,omitempty"`
+}
+
+// OverrideSnapshot is the serializable form of an Override.
+type OverrideSnapshot struct {
+	Start  uint32 `json:"start"`
+	End    uint32 `json:"end"`
+	Member string `json:"member"`
+}
+
+// Snapshot returns the current state of the hash.  Members are sorted by ID.
+func (c *Consistent) Snapshot() Snapshot {
+	c.rlock()
+	defer c.runlock()
+	return c.snapshot()
+}
+
+// need c.rlock() before calling
+func (c *Consistent) snapshot() Snapshot {
+	s := Snapshot{NumberOfReplicas: c.NumberOfReplicas}
+	for k := range c.members {
+		s.Members = append(s.Members, MemberID(k))
+		if c.explicit[k] {
+			if s.Tokens == nil {
+				s.Tokens = make(map[string][]uint32)
+			}
+			s.Tokens[MemberID(k)] = append([]uint32(nil), c.vnodes[k]...)
+		}
+		if r, ok := c.replicas[k]; ok {
+			if s.Replicas == nil {
+				s.Replicas = make(map[string]int)
+			}
+			s.Replicas[MemberID(k)] = r
+		}
+		if w, ok := c.weights[k]; ok {
+			if s.Weights == nil {
+				s.Weights = make(map[string]float64)
+			}
+			s.Weights[MemberID(k)] = w
+		}
+		if c.isExcluded(k) {
+			s.Excluded = append(s.Excluded, MemberID(k))
+		}
+		if p := c.probes(k); p != nil && !c.explicit[k] {
+			if s.Probes == nil {
+				s.Probes = make(map[string]map[int]uint32)
+			}
+			s.Probes[MemberID(k)] = p
+		}
+	}
+	sort.Strings(s.Members)
+	sort.Strings(s.Excluded)
+	for a, e := range c.aliases {
+		if s.Aliases == nil {
+			s.Aliases = make(map[string]string)
+		}
+		s.Aliases[a] = MemberID(e)
+	}
+	for _, o := range c.overrides {
+		s.Overrides = append(s.Overrides, OverrideSnapshot{Start: o.Start, End: o.End, Member: MemberID(o.Member)})
+	}
+	return s
+}
+
+// Restore replaces the state of the hash with s, using lookup to turn member
+// IDs into writers.  An ID lookup fails on is retried under each of its
+// aliases in s.  If that fails too, or s would leave fewer members than
+// WithMinMembers allows, the hash is left unchanged and the error is
+// returned.  The new circle is installed in a single step, as by SetCtx, and
+// members in both keep their health and load state.
+func (c *Consistent) Restore(s Snapshot, lookup func(name string) (lineProtocol.WriteCloser, error)) error {
+	return c.restore(s, lookup, false)
+}
+
+func (c *Consistent) restore(s Snapshot, lookup func(name string) (lineProtocol.WriteCloser, error), replicated bool) error {
+	start := time.Now()
+	byName := make(map[string]lineProtocol.WriteCloser, len(s.Members))
+	elements := make([]lineProtocol.WriteCloser, 0, len(s.Members))
+	for _, name := range s.Members {
+		e, err := lookupAliased(s, name, lookup)
+		if err != nil {
+			return err
+		}
+		byName[name] = e
+		elements = append(elements, e)
+	}
+
+	c.lock()
+	defer c.unlock()
+	if c.closed {
+		return c.opError("restore", "", nil, ErrClosed)
+	}
+	if !replicated && !c.allowMutation() {
+		return c.opError("restore", "", nil, ErrNotLeader)
+	}
+	if !c.allowShrink(countDistinct(elements)) {
+		return c.opError("restore", "", nil, ErrMinMembers)
+	}
+	overrides := make([]Override, 0, len(s.Overrides))
+	for _, o := range s.Overrides {
+		e, ok := byName[o.Member]
+		if !ok {
+			return c.opError("restore", "", nil, ErrUnknownMember)
+		}
+		if o.Start > o.End {
+			return c.opError("restore", "", e, ErrInvalidRange)
+		}
+		overrides = append(overrides, Override{Start: o.Start, End: o.End, Member: e})
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Start < overrides[j].Start })
+	for i := 1; i < len(overrides); i++ {
+		if overrides[i].Start <= overrides[i-1].End {
+			return c.opError("restore", "", overrides[i].Member, ErrOverlappingRange)
+		}
+	}
+
+	for k := range c.ramps {
+		c.stopRamp(k)
+	}
+	if s.NumberOfReplicas > 0 {
+		c.NumberOfReplicas = s.NumberOfReplicas
+	}
+	c.replicas, c.weights = nil, nil
+	for name, n := range s.Replicas {
+		if e, ok := byName[name]; ok {
+			if c.replicas == nil {
+				c.replicas = make(map[lineProtocol.WriteCloser]int)
+			}
+			c.replicas[e] = n
+		}
+	}
+	for name, w := range s.Weights {
+		if e, ok := byName[name]; ok {
+			if c.weights == nil {
+				c.weights = make(map[lineProtocol.WriteCloser]float64)
+			}
+			c.weights[e] = w
+		}
+	}
+	c.swap(c.restored(s, byName), time.Since(start))
+	c.overrides = overrides
+	c.aliases = nil
+	for a, name := range s.Aliases {
+		if e, ok := byName[name]; ok {
+			if c.aliases == nil {
+				c.aliases = make(map[string]lineProtocol.WriteCloser)
+			}
+			c.aliases[a] = e
+		}
+	}
+	excluded := make(map[lineProtocol.WriteCloser]bool, len(s.Excluded))
+	for _, name := range s.Excluded {
+		excluded[byName[name]] = true
+	}
+	for k, st := range c.state {
+		st.excluded = excluded[k]
+	}
+	return nil
+}
+
+// restored builds the circle s describes, placing members in its order.
+// need c.rlock() before calling
+func (c *Consistent) restored(s Snapshot, byName map[string]lineProtocol.WriteCloser) *ringState {
+	r := &ringState{
+		circle:   make(map[uint32]lineProtocol.WriteCloser, len(s.Members)*c.NumberOfReplicas),
+		members:  make(map[lineProtocol.WriteCloser]bool, len(s.Members)),
+		vnodes:   make(map[lineProtocol.WriteCloser][]uint32, len(s.Members)),
+		explicit: make(map[lineProtocol.WriteCloser]bool),
+	}
+	for _, name := range s.Members {
+		e := byName[name]
+		if r.members[e] {
+			continue
+		}
+		var hashes []uint32
+		if t, ok := s.Tokens[name]; ok {
+			hashes = append([]uint32(nil), t...)
+			r.explicit[e] = true
+		} else {
+			hashes = c.derivedHashes(e)
+			if p, ok := s.Probes[name]; ok {
+				for i, h := range p {
+					if i >= 0 && i < len(hashes) {
+						hashes[i] = h
+					}
+				}
+			} else {
+				r.collisions += c.resolve(e, hashes, 0, r.circle)
+			}
+		}
+		for _, h := range hashes {
+			r.circle[h] = e
+		}
+		r.members[e] = true
+		r.vnodes[e] = hashes
+	}
+	for k := range c.members {
+		if !r.members[k] {
+			r.removed = append(r.removed, k)
+		}
+	}
+	r.sorted = make(uints, 0, len(r.circle))
+	for h := range r.circle {
+		r.sorted = append(r.sorted, h)
+	}
+	slices.Sort(r.sorted)
+	return r
+}
+
+// lookupAliased resolves name, falling back to its aliases in s in order.
+func lookupAliased(s Snapshot, name string, lookup func(name string) (lineProtocol.WriteCloser, error)) (lineProtocol.WriteCloser, error) {
+	e, err := lookup(name)
+	if err == nil {
+		return e, nil
+	}
+	var aliases []string
+	for a, n := range s.Aliases {
+		if n == name {
+			aliases = append(aliases, a)
+		}
+	}
+	sort.Strings(aliases)
+	for _, a := range aliases {
+		if e, aerr := lookup(a); aerr == nil {
+			return e, nil
+		}
+	}
+	return nil, err
+}
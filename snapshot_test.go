@@ -0,0 +1,120 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func lookupIn(members ...*member) func(string) (lineProtocol.WriteCloser, error) {
+	return func(name string) (lineProtocol.WriteCloser, error) {
+		for _, m := range members {
+			if m.name == name {
+				return m, nil
+			}
+		}
+		return nil, ErrUnknownMember
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.NumberOfReplicas = 30
+	x.Add(a)
+	x.Add(b)
+	if err := x.AssignRange(10, 20, b); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := json.Marshal(x.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(buf, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	y := New()
+	y.Add(newMember("opqrstu"))
+	if err := y.Restore(s, lookupIn(a, b)); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(y.NumberOfReplicas, 30, t)
+	checkNum(len(y.circle), 60, t)
+	for _, k := range []string{"ggg", "hhh", "iiiii"} {
+		want, _ := x.Get(k)
+		if got, _ := y.Get(k); got != want {
+			t.Errorf("%s: got %v, expected %v", k, got, want)
+		}
+	}
+	if o := y.Overrides(); len(o) != 1 || o[0].Member != b {
+		t.Errorf("got overrides %v, expected one for hijklmn", o)
+	}
+}
+
+func TestRestoreLookupFailure(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	failed := errors.New("dial failed")
+	err := x.Restore(Snapshot{Members: []string{"hijklmn"}}, func(string) (lineProtocol.WriteCloser, error) {
+		return nil, failed
+	})
+	if err != failed {
+		t.Errorf("got %v, expected %v", err, failed)
+	}
+	checkNum(len(x.circle), 20, t)
+}
+
+func TestRestoreInOneStep(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	x.MarkDown(b)
+	y := New()
+	y.Add(b)
+	y.Add(c)
+	epoch := x.Epoch()
+	sub := x.Events(10)
+	if err := x.Restore(y.Snapshot(), lookupIn(a, b, c)); err != nil {
+		t.Fatal(err)
+	}
+	sub.Close()
+	for ev := range sub.C {
+		switch ev := ev.(type) {
+		case MemberAdded:
+			if ev.Member != c {
+				t.Errorf("got %v added, expected only %v", ev.Member, c)
+			}
+		case MemberRemoved:
+			if ev.Member != a {
+				t.Errorf("got %v removed, expected only %v", ev.Member, a)
+			}
+		}
+	}
+	if got := x.Epoch(); got != epoch+1 {
+		t.Errorf("got epoch %d, expected %d", got, epoch+1)
+	}
+	if x.Healthy(b) {
+		t.Error("expected b to stay down across the restore")
+	}
+	if !reflect.DeepEqual(x.Snapshot(), y.Snapshot()) {
+		t.Errorf("got %+v, expected %+v", x.Snapshot(), y.Snapshot())
+	}
+
+	z := New(WithMinMembers(2))
+	z.Add(a)
+	z.Add(b)
+	if err := z.Restore(Snapshot{Members: []string{"abcdefg"}}, lookupIn(a, b)); !errors.Is(err, ErrMinMembers) {
+		t.Errorf("got %v, expected ErrMinMembers", err)
+	}
+	checkNum(len(z.Members()), 2, t)
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// DefaultRateWindow is the period over which the growth of a member's load
+// is measured when WithCapacityWarning is given a window <= 0.
+const DefaultRateWindow = time.Minute
+
+// CapacityStatus is the load of a member with a capacity, and how soon it
+// reaches it at the rate its load grew over the last window.
+type CapacityStatus struct {
+	Member lineProtocol.WriteCloser
+	Load   int64
+	Limit  int64
+	Rate   float64 // load added per second
+	// TimeToLimit is how long Load takes to reach Limit at Rate, or 0 if
+	// Load is not growing or already at Limit.
+	TimeToLimit time.Duration
+}
+
+type capacityWarning struct {
+	threshold float64
+	window    time.Duration
+	warn      func(CapacityStatus)
+}
+
+// loadRate measures the growth of a member's load window by window.
+type loadRate struct {
+	mu     sync.Mutex
+	start  time.Time // of the current window
+	base   int64     // load at start
+	rate   float64   // of the last complete window, per second
+	warned bool
+}
+
+// WithCapacityWarning calls warn, on its own goroutine, when the load of a
+// member reported through AddLoad reaches threshold times its capacity, for
+// example 0.8, so capacity can be added before Get starts moving its keys.
+// warn is called again only after the load has dropped back below the
+// threshold.  The rate in the status is measured over window.
+func WithCapacityWarning(threshold float64, window time.Duration, warn func(CapacityStatus)) Option {
+	if window <= 0 {
+		window = DefaultRateWindow
+	}
+	return func(c *Consistent) {
+		c.capacityWarn = &capacityWarning{threshold: threshold, window: window, warn: warn}
+	}
+}
+
+// observe updates the rate of cp with its new load, and reports whether it
+// just crossed the warning threshold.
+func (w *capacityWarning) observe(cp *capacity, load int64, now time.Time) bool {
+	r := &cp.rate
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roll(w.window, load, now)
+	soft := w.threshold * float64(cp.limit)
+	if float64(load) < soft {
+		r.warned = false
+		return false
+	}
+	if r.warned {
+		return false
+	}
+	r.warned = true
+	return true
+}
+
+// need r.mu.Lock() before calling
+func (r *loadRate) roll(window time.Duration, load int64, now time.Time) {
+	if r.start.IsZero() {
+		r.start, r.base = now, load
+		return
+	}
+	if d := now.Sub(r.start); d >= window {
+		r.rate = float64(load-r.base) / d.Seconds()
+		r.start, r.base = now, load
+	}
+}
+
+// need c.rlock() before calling
+func (c *Consistent) capacityStatus(element lineProtocol.WriteCloser, cp *capacity) CapacityStatus {
+	s := CapacityStatus{Member: element, Load: cp.load.Load(), Limit: cp.limit}
+	if c.capacityWarn != nil {
+		cp.rate.mu.Lock()
+		cp.rate.roll(c.capacityWarn.window, s.Load, c.clock.Now())
+		s.Rate = cp.rate.rate
+		cp.rate.mu.Unlock()
+	}
+	if s.Rate > 0 && s.Load < s.Limit {
+		s.TimeToLimit = time.Duration(float64(s.Limit-s.Load) / s.Rate * float64(time.Second))
+	}
+	return s
+}
+
+// Capacities returns the status of every member with a capacity, sorted by
+// name.  Rate and TimeToLimit are only measured WithCapacityWarning.
+func (c *Consistent) Capacities() []CapacityStatus {
+	c.rlock()
+	defer c.runlock()
+	res := make([]CapacityStatus, 0, len(c.capacities))
+	for k, cp := range c.capacities {
+		res = append(res, c.capacityStatus(k, cp))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Member.Name() < res[j].Member.Name() })
+	return res
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCapacityWarning(t *testing.T) {
+	clk := newFakeClock()
+	warnings := make(chan CapacityStatus, 4)
+	x := New(WithClock(clk), WithCapacityWarning(0.8, time.Second, func(s CapacityStatus) { warnings <- s }))
+	a := newMember("a")
+	x.Add(a)
+	x.SetCapacity(a, 100)
+
+	x.AddLoad(a, 10)
+	for i := 0; i < 6; i++ {
+		clk.Advance(time.Second)
+		x.AddLoad(a, 10)
+	}
+	select {
+	case s := <-warnings:
+		t.Fatalf("warned at load %d", s.Load)
+	default:
+	}
+	clk.Advance(time.Second)
+	x.AddLoad(a, 10)
+	s := <-warnings
+	if s.Member != a || s.Load != 80 || s.Limit != 100 || s.Rate != 10 || s.TimeToLimit != 2*time.Second {
+		t.Errorf("status = %+v", s)
+	}
+	x.AddLoad(a, 5)
+	x.AddLoad(a, -20)
+	x.AddLoad(a, 15)
+	<-warnings
+	checkNum(int(x.Stats().NearCapacity), 2, t)
+
+	clk.Advance(10 * time.Second)
+	cs := x.Capacities()
+	if len(cs) != 1 || cs[0].Load != 80 || cs[0].Rate != 0 || cs[0].TimeToLimit != 0 {
+		t.Errorf("Capacities() = %+v", cs)
+	}
+}
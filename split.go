@@ -0,0 +1,70 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrMemberExists is the error returned when an operation would add an element
+// that is already in the hash.
+var ErrMemberExists = errors.New("member already exists")
+
+// ErrTooFewVnodes is the error returned by SplitMember when the member has
+// fewer than two points on the circle.
+var ErrTooFewVnodes = errors.New("too few vnodes to split")
+
+// SplitMember replaces element with a and b.  Walking element's points in ring
+// order, a takes every even point and b every odd one, so each inherits about
+// half of element's keys and no other member's keys move.  Overrides that
+// pointed at element are moved to a.
+func (c *Consistent) SplitMember(element, a, b lineProtocol.WriteCloser) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("split", "", element, c.refusal())
+	}
+	if _, ok := c.members[element]; !ok {
+		return c.opError("split", "", element, ErrUnknownMember)
+	}
+	if _, ok := c.members[a]; ok || a == b {
+		return c.opError("split", "", a, ErrMemberExists)
+	}
+	if _, ok := c.members[b]; ok {
+		return c.opError("split", "", b, ErrMemberExists)
+	}
+	var owned []uint32
+	for _, h := range c.hashesOf(element) {
+		if c.circle[h] == element {
+			owned = append(owned, h)
+		}
+	}
+	if len(owned) < 2 {
+		return c.opError("split", "", element, ErrTooFewVnodes)
+	}
+	slices.Sort(owned)
+	var ta, tb []uint32
+	for i, h := range owned {
+		if i%2 == 0 {
+			ta = append(ta, h)
+		} else {
+			tb = append(tb, h)
+		}
+	}
+	overrides := append([]Override(nil), c.overrides...)
+	c.remove(element)
+	c.addTokens(a, ta)
+	c.addTokens(b, tb)
+	for i := range overrides {
+		if overrides[i].Member == element {
+			overrides[i].Member = a
+		}
+	}
+	c.overrides = overrides
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestSplitMember(t *testing.T) {
+	x := New()
+	old, other := newMember("abcdefg"), newMember("hijklmn")
+	x.Add(old)
+	x.Add(other)
+	before := make(map[string]lineProtocol.WriteCloser)
+	for i := 0; i < 1000; i++ {
+		k := strconv.Itoa(i)
+		before[k], _ = x.Get(k)
+	}
+
+	a, b := newMember("abcdefg-a"), newMember("abcdefg-b")
+	if err := x.SplitMember(old, a, b); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.circle), 40, t)
+	checkNum(len(x.Members()), 3, t)
+	counts := make(map[lineProtocol.WriteCloser]int)
+	for k, was := range before {
+		got, _ := x.Get(k)
+		if was == other && got != other || was == old && got != a && got != b {
+			t.Errorf("%s: moved from %v to %v", k, was, got)
+		}
+		counts[got]++
+	}
+	if counts[a] == 0 || counts[b] == 0 {
+		t.Errorf("expected both halves to own keys, got %d and %d", counts[a], counts[b])
+	}
+
+	x.Remove(a)
+	checkNum(len(x.circle), 30, t)
+	if err := x.SplitMember(old, a, b); !errors.Is(err, ErrUnknownMember) {
+		t.Errorf("expected unknown member error, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrAlreadyMember is the error returned by AddStandby for a member of the
+// hash.
+var ErrAlreadyMember = errors.New("already a member")
+
+// AddStandby reserves the points element would take if added, without
+// adding it: element gets no traffic, but no member added meanwhile can take
+// those points, so Activate later places element exactly where AddStandby
+// found it free, moving only the keys of its own arcs, without hashing or
+// re-sorting the circle.  Adding element by any other means also ends its
+// standby.
+func (c *Consistent) AddStandby(element lineProtocol.WriteCloser) error {
+	if element == nil {
+		return ErrNilMember
+	}
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("standby", "", element, c.refusal())
+	}
+	if c.members[element] {
+		return c.opError("standby", "", element, ErrAlreadyMember)
+	}
+	if _, ok := c.standby[element]; ok {
+		return nil
+	}
+	hashes, _ := c.freshHashes(element)
+	if c.standby == nil {
+		c.standby = make(map[lineProtocol.WriteCloser][]uint32)
+		c.reserved = make(map[uint32]lineProtocol.WriteCloser)
+	}
+	c.standby[element] = hashes
+	for _, h := range hashes {
+		c.reserved[h] = element
+	}
+	return nil
+}
+
+// Activate adds the standby element to the hash on its reserved points.
+// Any of them taken meanwhile, as by Restore, are probed as collisions are by
+// Add.
+func (c *Consistent) Activate(element lineProtocol.WriteCloser) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("activate", "", element, c.refusal())
+	}
+	reserved, ok := c.standby[element]
+	if !ok {
+		return c.opError("activate", "", element, ErrUnknownMember)
+	}
+	start := time.Now()
+	hashes := append([]uint32(nil), reserved...)
+	c.stats.Collisions += int64(c.resolve(element, hashes, 0, c.circle))
+	merged := make(uints, 0, len(c.sortedHashes)+len(hashes))
+	sorted := slices.Sorted(slices.Values(hashes))
+	i, j := 0, 0
+	for i < len(c.sortedHashes) && j < len(sorted) {
+		if c.sortedHashes[i] < sorted[j] {
+			merged = append(merged, c.sortedHashes[i])
+			i++
+		} else {
+			merged = append(merged, sorted[j])
+			j++
+		}
+	}
+	merged = append(append(merged, c.sortedHashes[i:]...), sorted[j:]...)
+	c.placeSorted(element, hashes, merged, time.Since(start))
+	return nil
+}
+
+// RemoveStandby releases the points reserved for element.
+func (c *Consistent) RemoveStandby(element lineProtocol.WriteCloser) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("removestandby", "", element, c.refusal())
+	}
+	c.unreserve(element)
+	return nil
+}
+
+// Standby returns the standby members sorted by name.
+func (c *Consistent) Standby() []lineProtocol.WriteCloser {
+	c.rlock()
+	defer c.runlock()
+	s := make([]lineProtocol.WriteCloser, 0, len(c.standby))
+	for k := range c.standby {
+		s = append(s, k)
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].Name() < s[j].Name() })
+	return s
+}
+
+// reservedFor reports whether h is reserved for a standby member other than
+// element.
+// need c.rlock() before calling
+func (c *Consistent) reservedFor(h uint32, element lineProtocol.WriteCloser) bool {
+	o, ok := c.reserved[h]
+	return ok && o != element
+}
+
+// need c.lock() before calling
+func (c *Consistent) unreserve(element lineProtocol.WriteCloser) {
+	for _, h := range c.standby[element] {
+		if c.reserved[h] == element {
+			delete(c.reserved, h)
+		}
+	}
+	delete(c.standby, element)
+}
@@ -0,0 +1,137 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestStandby(t *testing.T) {
+	a, b, spare := newMember("a"), newMember("b"), newMember("spare")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("key%d", i)
+		e, _ := x.Get(k)
+		before[k] = e.Name()
+	}
+	if err := x.AddStandby(spare); err != nil {
+		t.Fatal(err)
+	}
+	for k, name := range before {
+		if e, _ := x.Get(k); e.Name() != name {
+			t.Fatalf("standby member took %s", k)
+		}
+	}
+	if s := x.Standby(); len(s) != 1 || s[0] != spare {
+		t.Errorf("Standby() = %v", s)
+	}
+	if err := x.AddStandby(a); !errors.Is(err, ErrAlreadyMember) {
+		t.Errorf("standby of a member: %v", err)
+	}
+
+	if err := x.Activate(spare); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for k, name := range before {
+		e, _ := x.Get(k)
+		if e.Name() != name {
+			if e != spare {
+				t.Fatalf("%s moved from %s to %s", k, name, e.Name())
+			}
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Error("no key moved to the activated member")
+	}
+	if len(x.Standby()) != 0 {
+		t.Error("still standby after Activate")
+	}
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+	if err := x.Activate(spare); !errors.Is(err, ErrUnknownMember) {
+		t.Errorf("second Activate: %v", err)
+	}
+}
+
+func TestStandbyReserves(t *testing.T) {
+	coarse := WithHasher(func(key []byte) uint32 { return uint32(hash64(string(key)) % 32) })
+	spare := newMember("spare")
+	x := New(coarse)
+	x.NumberOfReplicas = 8
+	x.Add(newMember("a"))
+	x.AddStandby(spare)
+	x.rlock()
+	reserved := append([]uint32(nil), x.standby[spare]...)
+	x.runlock()
+	x.Add(newMember("b"))
+	x.Add(newMember("c"))
+	x.Activate(spare)
+	x.rlock()
+	got := x.vnodes[spare]
+	x.runlock()
+	if !reflect.DeepEqual(got, reserved) {
+		t.Errorf("activated on %v, reserved %v", got, reserved)
+	}
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStandbyTokens(t *testing.T) {
+	a, b, spare := newMember("a"), newMember("b"), newMember("spare")
+	x := New()
+	x.Add(a)
+	x.AddStandby(spare)
+	x.rlock()
+	point := x.standby[spare][0]
+	x.runlock()
+	if err := x.AddTokens(b, []uint32{point}); !errors.Is(err, ErrDuplicateToken) {
+		t.Errorf("AddTokens on a reserved point: %v", err)
+	}
+	table := x.ExportTokens()
+	table.Members = append(table.Members, TokenAssignment{Member: "b", Tokens: []uint32{point}})
+	if err := x.ImportTokens(table, lookupIn(a, b)); !errors.Is(err, ErrDuplicateToken) {
+		t.Errorf("ImportTokens on a reserved point: %v", err)
+	}
+
+	// a point taken despite the reservation is probed on Activate
+	y := New()
+	y.Add(a)
+	y.AddStandby(spare)
+	y.Restore(Snapshot{Members: []string{"a", "b"}, Tokens: map[string][]uint32{"b": {point}}}, lookupIn(a, b))
+	if err := y.Activate(spare); err != nil {
+		t.Fatal(err)
+	}
+	y.rlock()
+	e := y.circle[point]
+	y.runlock()
+	if e != b {
+		t.Errorf("point %d went to %v, expected b to keep it", point, e)
+	}
+	if err := y.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRemoveStandbyClosed(t *testing.T) {
+	spare := newMember("spare")
+	x := New()
+	x.Add(newMember("a"))
+	x.AddStandby(spare)
+	x.Close()
+	if err := x.RemoveStandby(spare); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, expected ErrClosed", err)
+	}
+	checkNum(len(x.Standby()), 1, t)
+}
@@ -0,0 +1,139 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "time"
+
+// Stats is a point-in-time copy of the counters kept by a Consistent.
+type Stats struct {
+	Ring          string         // name set WithName, to label metrics with
+	Members       int            // number of members in the circle
+	Vnodes        int            // number of points on the circle
+	Rebuilds      int64          // number of times the sorted hashes were rebuilt
+	LastRebuild   time.Duration  // duration of the most recent rebuild
+	TotalRebuild  time.Duration  // cumulative time spent rebuilding
+	Overflows     int64          // Gets sent past a member at capacity or down
+	Collisions    int64          // points moved because another member held them
+	Fallbacks     int64          // Gets served by the WithFallback ring
+	Refused       int64          // changes refused by WithMinMembers or WithLeader
+	HintsStored   int64          // writes kept for a member that was down
+	HintsReplayed int64          // hints written back to their member
+	Repair        RepairProgress // progress of the Repairer, if any
+	Churn         Churn          // membership changes since New
+	RecentChurn   Churn          // membership changes within the churn window
+	Latency       LatencyStats   // routing latency, see WithLatencyHistograms
+	Shadow        ShadowStats    // agreement with the WithShadow ring
+	Reclaiming    int            // removed writers waiting for writes in flight to close
+	Audited       int64          // writes copied to the WithAudit sink
+	AuditFailures int64          // writes the WithAudit sink failed to take
+	NearCapacity  int64          // times a member reached its WithCapacityWarning threshold
+}
+
+// need c.lock() before calling
+func (s *Stats) recordRebuild(d time.Duration) {
+	s.Rebuilds++
+	s.LastRebuild = d
+	s.TotalRebuild += d
+}
+
+// allowShrink reports whether the hash may go from its current size to n
+// members, counting a refusal if not.
+// need c.lock() before calling
+func (c *Consistent) allowShrink(n int) bool {
+	if n >= c.minMembers || n >= len(c.members) {
+		return true
+	}
+	c.stats.Refused++
+	return false
+}
+
+// Stats returns a copy of the current counters.
+func (c *Consistent) Stats() Stats {
+	c.rlock()
+	defer c.runlock()
+	return c.currentStats()
+}
+
+// need c.rlock() before calling
+func (c *Consistent) currentStats() Stats {
+	s := c.stats
+	s.Ring = c.name
+	s.Members = len(c.members)
+	s.Vnodes = len(c.sortedHashes)
+	s.Overflows = c.overflows.Load()
+	s.Fallbacks = c.fallbacks.Load()
+	if c.shadow != nil {
+		s.Shadow = ShadowStats{Agree: c.shadow.agree.Load(), Disagree: c.shadow.disagree.Load()}
+	}
+	s.HintsStored = c.hintsStored.Load()
+	s.HintsReplayed = c.hintsReplayed.Load()
+	s.Latency = c.latencyStats()
+	s.Churn = c.churn.total
+	s.RecentChurn = c.churn.recent(c.clock.Now())
+	if c.repairer != nil {
+		s.Repair = c.repairer.Progress()
+	}
+	s.Reclaiming = c.reclaim.pending()
+	s.NearCapacity = c.capacityWarnings.Load()
+	if c.audit != nil {
+		s.Audited = c.audit.audited.Load()
+		s.AuditFailures = c.audit.failures.Load()
+	}
+	return s
+}
+
+// Sub returns the counters of s accumulated since prev, an earlier Stats of
+// the same hash.  Members, Vnodes, LastRebuild, Repair, RecentChurn and
+// Reclaiming are gauges and are taken from s.
+func (s Stats) Sub(prev Stats) Stats {
+	d := s
+	d.Rebuilds -= prev.Rebuilds
+	d.TotalRebuild -= prev.TotalRebuild
+	d.Overflows -= prev.Overflows
+	d.Collisions -= prev.Collisions
+	d.Fallbacks -= prev.Fallbacks
+	d.Refused -= prev.Refused
+	d.HintsStored -= prev.HintsStored
+	d.HintsReplayed -= prev.HintsReplayed
+	d.Audited -= prev.Audited
+	d.AuditFailures -= prev.AuditFailures
+	d.NearCapacity -= prev.NearCapacity
+	d.Churn = s.Churn.Sub(prev.Churn)
+	d.Latency = s.Latency.Sub(prev.Latency)
+	d.Shadow = ShadowStats{
+		Agree:    s.Shadow.Agree - prev.Shadow.Agree,
+		Disagree: s.Shadow.Disagree - prev.Shadow.Disagree,
+	}
+	return d
+}
+
+// ResetStats zeroes every counter and histogram reported by Stats, so a test
+// or dashboard can measure from a known point.  Gauges, such as the number of
+// members, and the progress of the Repairer are not affected.
+func (c *Consistent) ResetStats() {
+	c.lock()
+	defer c.unlock()
+	c.stats = Stats{}
+	c.overflows.Store(0)
+	c.fallbacks.Store(0)
+	c.hintsStored.Store(0)
+	c.hintsReplayed.Store(0)
+	if c.shadow != nil {
+		c.shadow.agree.Store(0)
+		c.shadow.disagree.Store(0)
+	}
+	if c.latency != nil {
+		c.latency.reset()
+	}
+	c.capacityWarnings.Store(0)
+	if c.audit != nil {
+		c.audit.audited.Store(0)
+		c.audit.failures.Store(0)
+	}
+	c.churn.total = Churn{}
+	c.churn.events = nil
+	c.churn.alerted = false
+	c.statsResets++
+}
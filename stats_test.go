@@ -0,0 +1,48 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestStatsRebuild(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	x.Add(newMember("hijklmn"))
+	s := x.Stats()
+	if s.Members != 2 || s.Vnodes != 40 {
+		t.Errorf("got %d members %d vnodes, expected 2 and 40", s.Members, s.Vnodes)
+	}
+	if s.Rebuilds != 2 {
+		t.Errorf("got %d rebuilds, expected 2", s.Rebuilds)
+	}
+	if s.TotalRebuild < s.LastRebuild {
+		t.Errorf("total rebuild %v less than last %v", s.TotalRebuild, s.LastRebuild)
+	}
+	if !sort.IsSorted(x.sortedHashes) {
+		t.Errorf("expected sorted hashes to be sorted")
+	}
+}
+
+func TestResetStats(t *testing.T) {
+	x := New(WithLatencyHistograms())
+	x.Add(newMember("a"))
+	x.Add(newMember("b"))
+	x.Get("k")
+	x.ResetStats()
+	s := x.Stats()
+	if s.Rebuilds != 0 || s.Churn.Total() != 0 || s.Latency.Get.Count != 0 {
+		t.Errorf("counters not reset: %+v", s)
+	}
+	if s.Members != 2 || s.Vnodes != 40 {
+		t.Errorf("reset changed gauges: %d members %d vnodes", s.Members, s.Vnodes)
+	}
+	x.Get("k")
+	if s := x.Stats(); s.Latency.Get.Count != 1 {
+		t.Errorf("got %d Gets after reset, expected 1", s.Latency.Get.Count)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sort"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// StringRing is a hash over plain member names, for callers that only need
+// placement decisions and have no writers.  It is backed by a Consistent
+// whose members are NopMembers, so names place exactly as members of the
+// same name would in any other hash.
+type StringRing struct {
+	c *Consistent
+}
+
+// NewStringRing creates an empty StringRing configured with opts.
+func NewStringRing(opts ...Option) *StringRing {
+	return &StringRing{c: New(opts...)}
+}
+
+// Ring returns the Consistent backing r, for the features StringRing does
+// not wrap.
+func (r *StringRing) Ring() *Consistent { return r.c }
+
+// Add inserts name in the hash.
+func (r *StringRing) Add(name string) { r.c.Add(NopMember(name)) }
+
+// Remove removes name from the hash.
+func (r *StringRing) Remove(name string) { r.c.Remove(NopMember(name)) }
+
+// Set sets all the names in the hash, removing the others.
+func (r *StringRing) Set(names []string) {
+	m := make([]lineProtocol.WriteCloser, len(names))
+	for i, n := range names {
+		m[i] = NopMember(n)
+	}
+	r.c.Set(m)
+}
+
+// Members returns the sorted names in the hash.
+func (r *StringRing) Members() []string {
+	m := r.c.Members()
+	s := make([]string, len(m))
+	for i, e := range m {
+		s[i] = e.Name()
+	}
+	sort.Strings(s)
+	return s
+}
+
+// Get returns the name closest to where key hashes to in the circle.
+func (r *StringRing) Get(key string) (string, error) {
+	e, err := r.c.Get(key)
+	if err != nil {
+		return "", err
+	}
+	return e.Name(), nil
+}
+
+// GetTwo returns the two closest distinct names to key.
+func (r *StringRing) GetTwo(key string) (string, string, error) {
+	a, b, err := r.c.GetTwo(key)
+	if err != nil {
+		return "", "", err
+	}
+	var bn string
+	if b != nil {
+		bn = b.Name()
+	}
+	return a.Name(), bn, nil
+}
+
+// GetN returns the n closest distinct names to key, in preference order.
+func (r *StringRing) GetN(key string, n int) ([]string, error) {
+	m, err := r.c.GetN(key, n)
+	if err != nil {
+		return nil, err
+	}
+	s := make([]string, len(m))
+	for i, e := range m {
+		s[i] = e.Name()
+	}
+	return s, nil
+}
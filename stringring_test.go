@@ -0,0 +1,45 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStringRing(t *testing.T) {
+	r := NewStringRing()
+	if _, err := r.Get("foo"); err == nil {
+		t.Error("expected an error on an empty ring")
+	}
+	x := New()
+	for _, n := range []string{"abcdefg", "hijklmn", "opqrstu"} {
+		r.Add(n)
+		r.Add(n)
+		x.Add(newMember(n))
+	}
+	if got := r.Members(); !reflect.DeepEqual(got, []string{"abcdefg", "hijklmn", "opqrstu"}) {
+		t.Errorf("got %v", got)
+	}
+	for _, k := range []string{"foo", "bar", "baz", "qux"} {
+		got, err := r.Get(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := x.Get(k)
+		if got != want.Name() {
+			t.Errorf("%s: got %s, expected %s as with writers", k, got, want.Name())
+		}
+	}
+	n, err := r.GetN("foo", 3)
+	if err != nil || len(n) != 3 {
+		t.Fatalf("got %v, %v", n, err)
+	}
+	r.Remove("hijklmn")
+	r.Set([]string{"abcdefg", "zzzzzzz"})
+	if got := r.Members(); !reflect.DeepEqual(got, []string{"abcdefg", "zzzzzzz"}) {
+		t.Errorf("got %v", got)
+	}
+}
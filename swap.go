@@ -0,0 +1,94 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Diff lists the members that changed between two states of a hash, each
+// sorted by name.
+type Diff struct {
+	Added   []lineProtocol.WriteCloser
+	Removed []lineProtocol.WriteCloser
+}
+
+// Swap replaces the members, points, weights, overrides, rules and hasher of
+// the hash with those of next in a single step, so readers see either the old
+// or the new topology and nothing in between.  next is typically built off to
+// the side with New and Add; it is copied, so it can be reused or dropped
+// afterwards.  Members in both rings keep their health and load state.  The
+// epoch advances once and the returned Diff lists every member added or
+// removed; subscribers get it in a single RingSwapped event.  Ramps running
+// on the hash are cancelled.
+//
+// Like SetCtx, Swap returns ErrNotLeader without the WithLeader lease and
+// ErrMinMembers if next has fewer members than WithMinMembers allows.
+func (c *Consistent) Swap(next *Consistent) (Diff, error) {
+	start := time.Now()
+	next.rlock()
+	r := &ringState{
+		circle:   make(map[uint32]lineProtocol.WriteCloser, len(next.circle)),
+		members:  make(map[lineProtocol.WriteCloser]bool, len(next.members)),
+		vnodes:   make(map[lineProtocol.WriteCloser][]uint32, len(next.vnodes)),
+		explicit: make(map[lineProtocol.WriteCloser]bool, len(next.explicit)),
+		sorted:   append(uints(nil), next.sortedHashes...),
+	}
+	for h, e := range next.circle {
+		r.circle[h] = e
+	}
+	for e := range next.members {
+		r.members[e] = true
+		r.vnodes[e] = append([]uint32(nil), next.vnodes[e]...)
+		if next.explicit[e] {
+			r.explicit[e] = true
+		}
+	}
+	weights := make(map[lineProtocol.WriteCloser]float64, len(next.weights))
+	for k, w := range next.weights {
+		weights[k] = w
+	}
+	replicaCounts := make(map[lineProtocol.WriteCloser]int, len(next.replicas))
+	for k, n := range next.replicas {
+		replicaCounts[k] = n
+	}
+	overrides := append([]Override(nil), next.overrides...)
+	rules := append([]Rule(nil), next.rules...)
+	hasher, replicas := next.hasher, next.NumberOfReplicas
+	next.runlock()
+
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return Diff{}, c.opError("swap", "", nil, c.refusal())
+	}
+	if !c.allowShrink(len(r.members)) {
+		return Diff{}, c.opError("swap", "", nil, ErrMinMembers)
+	}
+	for k := range c.members {
+		if !r.members[k] {
+			r.removed = append(r.removed, k)
+		}
+	}
+	for k := range c.ramps {
+		c.stopRamp(k)
+	}
+	d := Diff{Removed: append([]lineProtocol.WriteCloser(nil), r.removed...)}
+	d.Added = c.install(r)
+	c.overrides, c.rules, c.weights, c.replicas = overrides, rules, weights, replicaCounts
+	c.hasher, c.NumberOfReplicas = hasher, replicas
+	byName := func(s []lineProtocol.WriteCloser) {
+		sort.Slice(s, func(i, j int) bool { return s[i].Name() < s[j].Name() })
+	}
+	byName(d.Added)
+	byName(d.Removed)
+	c.stats.recordRebuild(time.Since(start))
+	c.advance()
+	c.bus.emit(RingSwapped{Epoch: c.epoch, Diff: d})
+	return d, nil
+}
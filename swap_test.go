@@ -0,0 +1,68 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSwap(t *testing.T) {
+	a, b, c := newMember("abcdefg"), newMember("hijklmn"), newMember("opqrstu")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	x.MarkDown(b)
+	epoch := x.Epoch()
+
+	next := New()
+	next.Add(b)
+	next.Add(c)
+	next.AssignRange(0, 100, c)
+	sub := x.Events(10)
+	d, err := x.Swap(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Close()
+	var events []Event
+	for ev := range sub.C {
+		events = append(events, ev)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got events %v, expected one RingSwapped", events)
+	}
+	if ev, ok := events[0].(RingSwapped); !ok || ev.Epoch != epoch+1 || !reflect.DeepEqual(ev.Diff, d) {
+		t.Errorf("got %+v, expected RingSwapped at epoch %d with %+v", events[0], epoch+1, d)
+	}
+	if len(d.Added) != 1 || d.Added[0] != c || len(d.Removed) != 1 || d.Removed[0] != a {
+		t.Errorf("got %+v, expected %v added and %v removed", d, c, a)
+	}
+	if got := x.Epoch(); got != epoch+1 {
+		t.Errorf("got epoch %d, expected %d", got, epoch+1)
+	}
+	checkNum(len(x.Members()), 2, t)
+	checkNum(len(x.Overrides()), 1, t)
+	if x.Healthy(b) {
+		t.Error("expected b to stay down across the swap")
+	}
+
+	// next is copied, changing it does not touch x
+	next.Remove(c)
+	checkNum(len(x.Members()), 2, t)
+	if _, err := x.Get("foo"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSwapMinMembers(t *testing.T) {
+	x := New(WithMinMembers(1))
+	x.Add(newMember("abcdefg"))
+	if _, err := x.Swap(New()); !errors.Is(err, ErrMinMembers) {
+		t.Errorf("got %v, expected ErrMinMembers", err)
+	}
+	checkNum(len(x.Members()), 1, t)
+}
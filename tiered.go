@@ -0,0 +1,43 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// Tiered routes each key through one of several rings chosen by classifying
+// the key, for example hot against cold measurements or realtime against
+// backfill writes.  The rings are the tenants of a Manager, named after the
+// tiers, so they are managed and monitored like any other tenant.
+type Tiered struct {
+	rings    *Manager
+	classify func(key string) string
+}
+
+// NewTiered creates a Tiered routing over the rings of m, where classify
+// returns the tier, and so the tenant of m, each key belongs to.
+func NewTiered(m *Manager, classify func(key string) string) *Tiered {
+	return &Tiered{rings: m, classify: classify}
+}
+
+// Rings returns the Manager holding the ring of every tier.
+func (t *Tiered) Rings() *Manager {
+	return t.rings
+}
+
+// Tier returns the tier of key.
+func (t *Tiered) Tier(key string) string {
+	return t.classify(key)
+}
+
+// Get returns the element for key in the ring of its tier.  A tier without a
+// ring behaves like an empty circle.
+func (t *Tiered) Get(key string) (lineProtocol.WriteCloser, error) {
+	return t.rings.Get(t.classify(key), key)
+}
+
+// GetN returns the N closest distinct elements to key in the ring of its tier.
+func (t *Tiered) GetN(key string, n int) ([]lineProtocol.WriteCloser, error) {
+	return t.rings.GetN(t.classify(key), key, n)
+}
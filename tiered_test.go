@@ -0,0 +1,39 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTiered(t *testing.T) {
+	hot, cold := newMember("abcdefg"), newMember("hijklmn")
+	m := NewManager(0)
+	m.Add("hot", hot)
+	m.Add("cold", cold)
+	tr := NewTiered(m, func(key string) string {
+		if strings.HasPrefix(key, "backfill.") {
+			return "cold"
+		}
+		if strings.HasPrefix(key, "debug.") {
+			return "debug"
+		}
+		return "hot"
+	})
+	if got, err := tr.Get("cpu"); err != nil || got != hot {
+		t.Errorf("got %v, %v, expected %v", got, err, hot)
+	}
+	if got, err := tr.GetN("backfill.cpu", 1); err != nil || got[0] != cold {
+		t.Errorf("got %v, %v, expected [%v]", got, err, cold)
+	}
+	if _, err := tr.Get("debug.cpu"); !errors.Is(err, ErrEmptyCircle) {
+		t.Errorf("got %v, expected ErrEmptyCircle", err)
+	}
+	s := tr.Rings().Stats()
+	checkNum(len(s), 2, t)
+	checkNum(s["cold"].Members, 1, t)
+}
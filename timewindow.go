@@ -0,0 +1,79 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// WindowRoute is the member holding one time window of a series.
+type WindowRoute struct {
+	Start  time.Time // start of the window
+	Member lineProtocol.WriteCloser
+}
+
+// TimeRouter routes time series data by series and time window, so the data
+// of one series for different windows, days for example, spreads over
+// different members.  Windows are aligned to multiples of the window length
+// since the zero time, which for whole days means UTC midnight.
+//
+// The routing key is the series followed by the window, so the ring should
+// not use WithPrefixRouting with a delimiter found in series names.
+type TimeRouter struct {
+	ring   *Consistent
+	window time.Duration
+}
+
+// NewTimeRouter creates a TimeRouter placing windows of the given length on
+// ring.
+func NewTimeRouter(ring *Consistent, window time.Duration) *TimeRouter {
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return &TimeRouter{ring: ring, window: window}
+}
+
+// Key returns the routing key of series at t.
+func (r *TimeRouter) Key(series string, t time.Time) string {
+	return series + "@" + strconv.FormatInt(t.Truncate(r.window).Unix(), 10)
+}
+
+// Get returns the member holding series at t, to write to.
+func (r *TimeRouter) Get(series string, t time.Time) (lineProtocol.WriteCloser, error) {
+	return r.ring.Get(r.Key(series, t))
+}
+
+// Windows returns the member of every window of series overlapping [from,
+// to], oldest first, to read from.
+func (r *TimeRouter) Windows(series string, from, to time.Time) ([]WindowRoute, error) {
+	var res []WindowRoute
+	for w := from.Truncate(r.window); !w.After(to); w = w.Add(r.window) {
+		e, err := r.Get(series, w)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, WindowRoute{Start: w, Member: e})
+	}
+	return res, nil
+}
+
+// Members returns the distinct members holding series between from and to,
+// in the order of the windows they first hold.
+func (r *TimeRouter) Members(series string, from, to time.Time) ([]lineProtocol.WriteCloser, error) {
+	windows, err := r.Windows(series, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var res []lineProtocol.WriteCloser
+	for _, w := range windows {
+		if !sliceContainsMember(res, w.Member) {
+			res = append(res, w.Member)
+		}
+	}
+	return res, nil
+}
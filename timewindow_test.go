@@ -0,0 +1,42 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTimeRouter(t *testing.T) {
+	x := New()
+	for i := 0; i < 4; i++ {
+		x.Add(newMember("member" + strconv.Itoa(i)))
+	}
+	r := NewTimeRouter(x, 24*time.Hour)
+	day := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	a, _ := r.Get("cpu,host=a", day.Add(time.Hour))
+	b, _ := r.Get("cpu,host=a", day.Add(23*time.Hour))
+	if a != b {
+		t.Errorf("got %v and %v, expected one member for the whole day", a, b)
+	}
+
+	windows, err := r.Windows("cpu,host=a", day.Add(12*time.Hour), day.Add(30*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(windows), 31, t)
+	if !windows[0].Start.Equal(day) || windows[0].Member != a {
+		t.Errorf("got first window %v on %v, expected %v on %v", windows[0].Start, windows[0].Member, day, a)
+	}
+	members, err := r.Members("cpu,host=a", day, day.Add(30*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) < 2 {
+		t.Errorf("got %d members over a month, expected the windows to spread", len(members))
+	}
+}
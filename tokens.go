@@ -0,0 +1,135 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"slices"
+	"sort"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrDuplicateToken is the error returned when two members claim the same
+// point on the circle.
+var ErrDuplicateToken = errors.New("duplicate token")
+
+// TokenTable is the full assignment of circle points to members, suitable for
+// computing placement outside the process and pushing it to every proxy.
+type TokenTable struct {
+	Members []TokenAssignment `json:"members"`
+}
+
+// TokenAssignment lists the circle points owned by one member.
+type TokenAssignment struct {
+	Member string   `json:"member"`
+	Tokens []uint32 `json:"tokens"`
+}
+
+// AddTokens inserts element at exactly the given circle points instead of
+// deriving them from its name, for mirroring an existing cluster's token map
+// (Cassandra style).  It fails if element is already a member or any point is
+// taken or reserved for a standby member.
+func (c *Consistent) AddTokens(element lineProtocol.WriteCloser, tokens []uint32) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("addtokens", "", element, c.refusal())
+	}
+	if _, ok := c.members[element]; ok {
+		return c.opError("addtokens", "", element, ErrMemberExists)
+	}
+	seen := make(map[uint32]bool, len(tokens))
+	for _, h := range tokens {
+		if _, taken := c.circle[h]; taken || seen[h] || c.reservedFor(h, element) {
+			return c.opError("addtokens", "", element, ErrDuplicateToken)
+		}
+		seen[h] = true
+	}
+	c.addTokens(element, append([]uint32(nil), tokens...))
+	return nil
+}
+
+// ExportTokens returns the points every member occupies, sorted by MemberID
+// and then by point.
+func (c *Consistent) ExportTokens() TokenTable {
+	c.rlock()
+	defer c.runlock()
+	var t TokenTable
+	for k := range c.members {
+		var tokens []uint32
+		for _, h := range c.hashesOf(k) {
+			if c.circle[h] == k {
+				tokens = append(tokens, h)
+			}
+		}
+		slices.Sort(tokens)
+		t.Members = append(t.Members, TokenAssignment{Member: MemberID(k), Tokens: tokens})
+	}
+	sort.Slice(t.Members, func(i, j int) bool { return t.Members[i].Member < t.Members[j].Member })
+	return t
+}
+
+// ImportTokens replaces the members of the hash with those in t, each placed
+// exactly on its listed points, using lookup to turn IDs into writers.  The
+// hash is left unchanged if t is invalid, lookup fails, a point is reserved
+// for a standby member or the change would leave fewer members than
+// WithMinMembers allows.  A member listed twice is invalid and fails with
+// ErrMemberExists.  Overrides are kept for members that remain.
+func (c *Consistent) ImportTokens(t TokenTable, lookup func(name string) (lineProtocol.WriteCloser, error)) error {
+	seen := make(map[uint32]bool)
+	listed := make(map[string]bool, len(t.Members))
+	elements := make([]lineProtocol.WriteCloser, len(t.Members))
+	for i, m := range t.Members {
+		if listed[m.Member] {
+			return &Error{Op: "importtokens", Member: m.Member, Err: ErrMemberExists}
+		}
+		listed[m.Member] = true
+		for _, h := range m.Tokens {
+			if seen[h] {
+				return &Error{Op: "importtokens", Member: m.Member, Err: ErrDuplicateToken}
+			}
+			seen[h] = true
+		}
+		e, err := lookup(m.Member)
+		if err != nil {
+			return &Error{Op: "importtokens", Member: m.Member, Err: err}
+		}
+		elements[i] = e
+	}
+	if countDistinct(elements) != len(elements) {
+		return &Error{Op: "importtokens", Err: ErrMemberExists}
+	}
+
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("importtokens", "", nil, c.refusal())
+	}
+	if !c.allowShrink(countDistinct(elements)) {
+		return c.opError("importtokens", "", nil, ErrMinMembers)
+	}
+	for i, m := range t.Members {
+		for _, h := range m.Tokens {
+			if c.reservedFor(h, elements[i]) {
+				return c.opError("importtokens", "", elements[i], ErrDuplicateToken)
+			}
+		}
+	}
+	overrides := append([]Override(nil), c.overrides...)
+	for k := range c.members {
+		c.remove(k)
+	}
+	c.overrides = nil
+	for i, m := range t.Members {
+		c.addTokens(elements[i], append([]uint32(nil), m.Tokens...))
+	}
+	for _, o := range overrides {
+		if _, ok := c.members[o.Member]; ok {
+			c.overrides = append(c.overrides, o)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestExportImportTokens(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	buf, err := json.Marshal(x.ExportTokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var table TokenTable
+	if err := json.Unmarshal(buf, &table); err != nil {
+		t.Fatal(err)
+	}
+	y := New()
+	y.NumberOfReplicas = 3
+	if err := y.ImportTokens(table, lookupIn(a, b)); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(y.circle), 40, t)
+	for _, k := range []string{"ggg", "hhh", "iiiii"} {
+		want, _ := x.Get(k)
+		if got, _ := y.Get(k); got != want {
+			t.Errorf("%s: got %v, expected %v", k, got, want)
+		}
+	}
+
+	table.Members[1].Tokens = append(table.Members[1].Tokens, table.Members[0].Tokens[0])
+	if err := y.ImportTokens(table, lookupIn(a, b)); !errors.Is(err, ErrDuplicateToken) {
+		t.Errorf("expected duplicate token error, got %v", err)
+	}
+}
+
+func TestAddTokens(t *testing.T) {
+	x := New()
+	a, b := newMember("a"), newMember("b")
+	if err := x.AddTokens(a, []uint32{100, 200}); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.AddTokens(b, []uint32{300, 200}); !errors.Is(err, ErrDuplicateToken) {
+		t.Errorf("expected duplicate token error, got %v", err)
+	}
+	if err := x.AddTokens(a, []uint32{400}); !errors.Is(err, ErrMemberExists) {
+		t.Errorf("expected member exists error, got %v", err)
+	}
+	if err := x.AddTokens(b, []uint32{300}); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.circle), 3, t)
+	for h, want := range map[uint32]*member{150: a, 250: b, 301: a} {
+		x.RLock()
+		got := x.circle[x.sortedHashes[x.search(h)]]
+		x.RUnlock()
+		if got != want {
+			t.Errorf("hash %d: got %v, expected %v", h, got, want)
+		}
+	}
+	if s := x.Snapshot(); len(s.Tokens["b"]) != 1 {
+		t.Errorf("expected snapshot to record b's tokens, got %v", s.Tokens)
+	}
+	x.Remove(a)
+	checkNum(len(x.circle), 1, t)
+}
+
+func TestImportTokensMinMembers(t *testing.T) {
+	a, b := newMember("a"), newMember("b")
+	x := New(WithMinMembers(2))
+	x.Add(a)
+	x.Add(b)
+	table := TokenTable{Members: []TokenAssignment{{Member: "a", Tokens: []uint32{1}}}}
+	err := x.ImportTokens(table, lookupIn(a, b))
+	var e *Error
+	if !errors.Is(err, ErrMinMembers) || !errors.As(err, &e) || e.Op != "importtokens" {
+		t.Fatalf("import below WithMinMembers: %v", err)
+	}
+	checkNum(len(x.Members()), 2, t)
+}
+
+func TestImportTokensDuplicateMember(t *testing.T) {
+	a, b := newMember("a"), newMember("b")
+	x := New()
+	x.Add(a)
+	table := TokenTable{Members: []TokenAssignment{
+		{Member: "a", Tokens: []uint32{1}},
+		{Member: "b", Tokens: []uint32{2}},
+		{Member: "a", Tokens: []uint32{3}},
+	}}
+	if err := x.ImportTokens(table, lookupIn(a, b)); !errors.Is(err, ErrMemberExists) {
+		t.Fatalf("got %v, expected ErrMemberExists", err)
+	}
+	checkNum(len(x.Members()), 1, t)
+	if err := x.CheckInvariants(); err != nil {
+		t.Error(err)
+	}
+}
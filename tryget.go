@@ -0,0 +1,43 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrBusy is the error returned by TryGet when the hash is being changed.
+var ErrBusy = errors.New("busy")
+
+// TryGet is like Get but fails at once with ErrBusy instead of waiting while a
+// membership change or rebuild holds the lock, for callers that would rather
+// retry or fall back than block.  Rules delegating to another ring and
+// WithFallback still wait on that ring.  TryGet does not report to the
+// Observer, shadow ring or latency histograms.
+func (c *Consistent) TryGet(name string) (lineProtocol.WriteCloser, error) {
+	if !c.tryRLock() {
+		return nil, &Error{Op: "get", Key: name, Err: ErrBusy}
+	}
+	defer c.runlock()
+	return c.routeLocked(name)
+}
+
+func (c *Consistent) tryRLock() bool {
+	if c.unlocked {
+		c.guard.rlock()
+		return true
+	}
+	if !c.TryRLock() {
+		return false
+	}
+	if c.dormant.Load() {
+		// materializing the points takes the write lock
+		c.RUnlock()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTryGet(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	e, err := x.TryGet("foo")
+	if err != nil || e.Name() != "abcdefg" {
+		t.Errorf("got %v, %v", e, err)
+	}
+	x.Lock()
+	_, err = x.TryGet("foo")
+	x.Unlock()
+	if !errors.Is(err, ErrBusy) {
+		t.Errorf("got %v, expected ErrBusy while the lock is held", err)
+	}
+	if _, err := New().TryGet("foo"); !errors.Is(err, ErrEmptyCircle) {
+		t.Errorf("got %v, expected ErrEmptyCircle", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "github.com/lvqian/mikuCluster/proxy/lineProtocol"
+
+// View is the read-only side of a Consistent, for request handling code that
+// routes keys but must not change membership.
+type View interface {
+	Get(name string) (lineProtocol.WriteCloser, error)
+	GetN(name string, n int) ([]lineProtocol.WriteCloser, error)
+	Members() []lineProtocol.WriteCloser
+	Stats() Stats
+}
+
+// view hides the *Consistent so a View cannot be asserted back to it.
+type view struct {
+	c *Consistent
+}
+
+// View returns a read-only View of c.
+func (c *Consistent) View() View {
+	return view{c}
+}
+
+func (v view) Get(name string) (lineProtocol.WriteCloser, error) { return v.c.Get(name) }
+
+func (v view) GetN(name string, n int) ([]lineProtocol.WriteCloser, error) {
+	return v.c.GetN(name, n)
+}
+
+func (v view) Members() []lineProtocol.WriteCloser { return v.c.Members() }
+
+func (v view) Stats() Stats { return v.c.Stats() }
@@ -0,0 +1,30 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestView(t *testing.T) {
+	x := New()
+	a := newMember("abcdefg")
+	x.Add(a)
+	v := x.View()
+	if got, err := v.Get("ggg"); err != nil || got != a {
+		t.Errorf("got %v, %v, expected abcdefg", got, err)
+	}
+	if _, ok := v.(*Consistent); ok {
+		t.Errorf("expected View not to expose the Consistent")
+	}
+	if _, ok := v.(interface {
+		Remove(lineProtocol.WriteCloser)
+	}); ok {
+		t.Errorf("expected View not to expose Remove")
+	}
+	checkNum(v.Stats().Members, 1, t)
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import "context"
+
+// WaitForMembers blocks until the hash has at least n members that are not
+// down, ctx is done, in which case it returns ctx.Err(), or the hash is
+// closed, in which case it returns ErrClosed.  Use it at startup so the first
+// requests are not answered with ErrEmptyCircle while discovery is still
+// running.
+func (c *Consistent) WaitForMembers(ctx context.Context, n int) error {
+	for {
+		c.lock()
+		if c.closed {
+			err := c.opError("waitformembers", "", nil, ErrClosed)
+			c.unlock()
+			return err
+		}
+		if c.active() >= n {
+			c.unlock()
+			return nil
+		}
+		ch := c.changes()
+		c.unlock()
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// changes returns a channel closed on the next change of the hash.
+// need c.lock() before calling
+func (c *Consistent) changes() <-chan struct{} {
+	if c.changed == nil {
+		c.changed = make(chan struct{})
+	}
+	return c.changed
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitForMembers(t *testing.T) {
+	x := New()
+	done := make(chan error)
+	go func() {
+		done <- x.WaitForMembers(context.Background(), 2)
+	}()
+	x.Add(newMember("abcdefg"))
+	select {
+	case err := <-done:
+		t.Fatalf("returned %v with one member", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	x.Add(newMember("hijklmn"))
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForMembersTimeout(t *testing.T) {
+	x := New()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := x.WaitForMembers(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("got %v, expected deadline exceeded", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestWalk(t *testing.T) {
+	x := New()
+	x.Add(newMember("abcdefg"))
+	x.Add(newMember("hijklmn"))
+	from := x.sortedHashes[10]
+	var hashes []uint32
+	x.Walk(from, func(h uint32, e lineProtocol.WriteCloser) bool {
+		if x.circle[h] != e {
+			t.Errorf("hash %d: got %v, expected %v", h, e, x.circle[h])
+		}
+		hashes = append(hashes, h)
+		return true
+	})
+	checkNum(len(hashes), 40, t)
+	if hashes[0] != x.sortedHashes[11] || hashes[len(hashes)-1] != from {
+		t.Errorf("walk did not start after %d and wrap around", from)
+	}
+
+	n := 0
+	x.Walk(0, func(uint32, lineProtocol.WriteCloser) bool {
+		n++
+		return n < 3
+	})
+	checkNum(n, 3, t)
+}
+
+func TestHashRanges(t *testing.T) {
+	x := New()
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x.Add(a)
+	x.Add(b)
+	var total uint64
+	for _, e := range []*member{a, b} {
+		ranges := x.HashRanges(e)
+		checkNum(len(ranges), 20, t)
+		for _, r := range ranges {
+			total += uint64(r.End-r.Start) + 1
+			for _, h := range []uint32{r.Start, r.End} {
+				x.RLock()
+				owner := x.circle[x.sortedHashes[x.search(h)]]
+				x.RUnlock()
+				if owner != e {
+					t.Errorf("range %+v: hash %d owned by %v, expected %v", r, h, owner, e)
+				}
+			}
+		}
+	}
+	if total != 1<<32 {
+		t.Errorf("ranges cover %d hashes, expected the whole circle", total)
+	}
+	x.Remove(a)
+	checkNum(len(x.HashRanges(a)), 0, t)
+}
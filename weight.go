@@ -0,0 +1,193 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// ErrExplicitTokens is the error returned when weighting a member placed with
+// AddTokens, whose points are not derived from its name.
+var ErrExplicitTokens = errors.New("member has explicit tokens")
+
+// pointCount returns the number of points element's name hashes to at its
+// weight.
+// need c.rlock() before calling
+func (c *Consistent) pointCount(element lineProtocol.WriteCloser) int {
+	replicas := c.NumberOfReplicas
+	if r, ok := c.replicas[element]; ok {
+		replicas = r
+	}
+	w, ok := c.weights[element]
+	if !ok {
+		return replicas
+	}
+	n := int(math.Round(w * float64(replicas)))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// SetWeight scales the number of points element has on the circle to w
+// times its replica count, at least one.  Points are derived from the name in
+// a fixed order, so changing the weight only adds or removes points at the
+// end of that sequence and moves no other keys.  Removing element forgets its
+// weight.
+func (c *Consistent) SetWeight(element lineProtocol.WriteCloser, w float64) error {
+	c.lock()
+	defer c.unlock()
+	if !c.allowMutation() {
+		return c.opError("setweight", "", element, c.refusal())
+	}
+	if !c.members[element] {
+		return c.opError("setweight", "", element, ErrUnknownMember)
+	}
+	if c.explicit[element] {
+		return c.opError("setweight", "", element, ErrExplicitTokens)
+	}
+	if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+		w = 1
+	}
+	if c.weights == nil {
+		c.weights = make(map[lineProtocol.WriteCloser]float64)
+	}
+	c.weights[element] = w
+	c.stopRamp(element)
+	if c.reweigh(element) {
+		c.updateSortedHashes()
+	}
+	return nil
+}
+
+// Weight returns the weight of element, 1 unless set with SetWeight.
+func (c *Consistent) Weight(element lineProtocol.WriteCloser) float64 {
+	c.rlock()
+	defer c.runlock()
+	if w, ok := c.weights[element]; ok {
+		return w
+	}
+	return 1
+}
+
+// reweigh moves element to the points of its current weight and reports
+// whether that changed the circle.  The caller rebuilds the sorted points.
+// need c.lock() before calling
+func (c *Consistent) reweigh(element lineProtocol.WriteCloser) bool {
+	old, n := c.vnodes[element], c.pointCount(element)
+	if len(old) == n {
+		return false
+	}
+	if n < len(old) {
+		for _, h := range old[n:] {
+			if c.circle[h] == element {
+				delete(c.circle, h)
+			}
+		}
+		c.vnodes[element] = old[:n:n]
+		return true
+	}
+	hashes := append([]uint32(nil), old...)
+	for i := len(old); i < n; i++ {
+		hashes = append(hashes, c.hashKey(c.elementKey(element, i)))
+	}
+	c.stats.Collisions += int64(c.resolve(element, hashes[len(old):], len(old), c.circle))
+	for _, h := range hashes[len(old):] {
+		c.circle[h] = element
+	}
+	c.vnodes[element] = hashes
+	return true
+}
+
+// WeightPolicy configures a WeightController.
+type WeightPolicy struct {
+	MaxErrorRatio float64       // share of failed writes above which a member is degraded, 0 to ignore
+	MaxLatency    time.Duration // mean write latency above which a member is degraded, 0 to ignore
+	MinWeight     float64       // lowest weight the controller sets
+	MaxWeight     float64       // highest weight the controller sets, 1 if 0
+	Decay         float64       // factor applied to a degraded member's weight, 0.5 if 0
+	Recover       float64       // weight given back per healthy round, 0.1 if 0
+}
+
+// WeightController adjusts the weights of the members of a hash from the
+// outcome of the writes made through Write and WriteReplicas: a member whose
+// error rate or latency exceeds the policy loses weight multiplicatively and
+// wins it back step by step once it is healthy again, within the policy's
+// bounds.  Members without writes in a round keep their weight.
+type WeightController struct {
+	c *Consistent
+	p WeightPolicy
+}
+
+// NewWeightController creates a WeightController for c.
+func NewWeightController(c *Consistent, p WeightPolicy) *WeightController {
+	if p.MaxWeight == 0 {
+		p.MaxWeight = 1
+	}
+	if p.Decay == 0 {
+		p.Decay = 0.5
+	}
+	if p.Recover == 0 {
+		p.Recover = 0.1
+	}
+	return &WeightController{c: c, p: p}
+}
+
+// Adjust runs one round, judging every member by the writes made since the
+// previous one.
+func (w *WeightController) Adjust() {
+	c, p := w.c, w.p
+	type change struct {
+		element lineProtocol.WriteCloser
+		weight  float64
+	}
+	var changes []change
+	c.rlock()
+	for k, st := range c.state {
+		writes, failures := st.fbWrites.Swap(0), st.fbFailures.Swap(0)
+		latency := time.Duration(st.fbLatency.Swap(0))
+		if writes == 0 || c.explicit[k] {
+			continue
+		}
+		degraded := (p.MaxErrorRatio > 0 && float64(failures)/float64(writes) > p.MaxErrorRatio) ||
+			(p.MaxLatency > 0 && latency/time.Duration(writes) > p.MaxLatency)
+		cur, ok := c.weights[k]
+		if !ok {
+			cur = 1
+		}
+		next := math.Min(cur+p.Recover, p.MaxWeight)
+		if degraded {
+			next = math.Max(cur*p.Decay, p.MinWeight)
+		}
+		if next != cur {
+			changes = append(changes, change{k, next})
+		}
+	}
+	c.runlock()
+	for _, ch := range changes {
+		c.SetWeight(ch.element, ch.weight)
+	}
+}
+
+// Start runs Adjust every interval until ctx is done.
+func (w *WeightController) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := w.c.clock.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.Chan():
+				w.Adjust()
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestSetWeight(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	before := make(map[string]*member)
+	for i := 0; i < 1000; i++ {
+		e, _ := x.Get(strconv.Itoa(i))
+		before[strconv.Itoa(i)] = e.(*member)
+	}
+	if err := x.SetWeight(a, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.HashRanges(a)), 10, t)
+	// lowering a's weight only moves keys from a to b
+	for k, old := range before {
+		if e, _ := x.Get(k); e != old && old != a {
+			t.Errorf("key %q moved from %v to %v", k, old, e)
+		}
+	}
+	if err := x.SetWeight(a, 2); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(x.vnodes[a]), 40, t)
+	if err := x.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+
+	y := New()
+	if err := y.Restore(x.Snapshot(), lookupIn(a, b)); err != nil {
+		t.Fatal(err)
+	}
+	if y.Weight(a) != 2 || y.Weight(b) != 1 {
+		t.Errorf("got weights %v and %v after restore, expected 2 and 1", y.Weight(a), y.Weight(b))
+	}
+	if err := x.SetWeight(newMember("opqrstu"), 1); !errors.Is(err, ErrUnknownMember) {
+		t.Errorf("got %v, expected ErrUnknownMember", err)
+	}
+}
+
+func TestWeightController(t *testing.T) {
+	a, b := newMember("abcdefg"), newMember("hijklmn")
+	x := New()
+	x.Add(a)
+	x.Add(b)
+	w := NewWeightController(x, WeightPolicy{MaxErrorRatio: 0.2, MinWeight: 0.2})
+	write := func() {
+		for i := 0; i < 200; i++ {
+			x.Write(strconv.Itoa(i), []byte("x"))
+		}
+	}
+
+	a.mu.Lock()
+	a.err = errors.New("disk full")
+	a.mu.Unlock()
+	for i := 0; i < 5; i++ {
+		write()
+		w.Adjust()
+	}
+	if got := x.Weight(a); got != 0.2 {
+		t.Errorf("got weight %v for a failing member, expected the 0.2 floor", got)
+	}
+	if got := x.Weight(b); got != 1 {
+		t.Errorf("got weight %v for a healthy member, expected 1", got)
+	}
+
+	a.mu.Lock()
+	a.err = nil
+	a.mu.Unlock()
+	for i := 0; i < 20; i++ {
+		write()
+		w.Adjust()
+	}
+	if got := x.Weight(a); got < 0.999 {
+		t.Errorf("got weight %v, expected a to recover to 1", got)
+	}
+}
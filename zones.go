@@ -0,0 +1,175 @@
+// Copyright (C) 2012 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"context"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+// Zoned is implemented by members labelled with the zone they run in.
+type Zoned interface {
+	Zone() string
+}
+
+// InZone returns element labelled with zone.  The result forwards Ping,
+// Encoding and its MemberID to element.
+func InZone(zone string, element lineProtocol.WriteCloser) lineProtocol.WriteCloser {
+	return &zoned{WriteCloser: element, zone: zone}
+}
+
+type zoned struct {
+	lineProtocol.WriteCloser
+	zone string
+}
+
+func (z *zoned) Zone() string                     { return z.zone }
+func (z *zoned) ID() string                       { return MemberID(z.WriteCloser) }
+func (z *zoned) Unwrap() lineProtocol.WriteCloser { return z.WriteCloser }
+
+func (z *zoned) Encoding() string {
+	if e, ok := z.WriteCloser.(Encoder); ok {
+		return e.Encoding()
+	}
+	return ""
+}
+
+func (z *zoned) Ping(ctx context.Context) error {
+	if p, ok := z.WriteCloser.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ZoneOf returns the zone of element, looking through the wrappers of this
+// package, or "" if it has none.
+func ZoneOf(element lineProtocol.WriteCloser) string {
+	for element != nil {
+		if z, ok := element.(Zoned); ok {
+			return z.Zone()
+		}
+		u, ok := element.(interface {
+			Unwrap() lineProtocol.WriteCloser
+		})
+		if !ok {
+			return ""
+		}
+		element = u.Unwrap()
+	}
+	return ""
+}
+
+// AnyZone matches, in a ZonePolicy, the zones no other entry names.
+const AnyZone = "*"
+
+// ZoneReplicas asks for Replicas copies of a key in Zone, which may be
+// AnyZone.
+type ZoneReplicas struct {
+	Zone     string
+	Replicas int
+}
+
+// ZonePolicy says how many replicas of every key GetN places in each zone,
+// for example {{"local", 2}, {AnyZone, 1}} for two copies in the local zone
+// and one elsewhere.
+type ZonePolicy []ZoneReplicas
+
+// WithZonePolicy makes GetN, and so WriteReplicas and PreferenceList, pick
+// the replicas of a key to satisfy p: the owner of the key always comes
+// first, and walking the circle on from it, a member is taken if its zone
+// still lacks replicas.  Members without a zone count for no entry.  When the
+// zones cannot satisfy p, or n exceeds what p asks for, the remaining
+// replicas are the next members in ring order regardless of zone, so GetN
+// still returns n members when it can.  The replicas are returned in ring
+// order.
+func WithZonePolicy(p ZonePolicy) Option {
+	return func(c *Consistent) {
+		c.zonePolicy = append(ZonePolicy(nil), p...)
+	}
+}
+
+// zoneEntry returns the index of the entry of p zone counts for, or -1.
+func (p ZonePolicy) zoneEntry(zone string) int {
+	if zone == "" {
+		return -1
+	}
+	other := -1
+	for i, r := range p {
+		if r.Zone == zone {
+			return i
+		}
+		if r.Zone == AnyZone && other < 0 {
+			other = i
+		}
+	}
+	return other
+}
+
+// walkReplicas calls fn with the n replicas of key, chosen by the zone
+// policy if there is one.
+// need c.rlock() before calling
+func (c *Consistent) walkReplicas(key uint32, n int, fn func(elem lineProtocol.WriteCloser, point uint32)) {
+	if c.zonePolicy != nil {
+		c.zoneN(key, n, fn)
+		return
+	}
+	c.walkN(key, n, fn)
+}
+
+// zoneN is walkN picking members by the zone policy.
+// need c.rlock() before calling
+func (c *Consistent) zoneN(key uint32, n int, fn func(elem lineProtocol.WriteCloser, point uint32)) {
+	type cand struct {
+		elem  lineProtocol.WriteCloser
+		point uint32
+	}
+	all := make([]cand, 0, c.count)
+	c.walkN(key, int(c.count), func(elem lineProtocol.WriteCloser, point uint32) {
+		all = append(all, cand{elem, point})
+	})
+	if n > len(all) {
+		n = len(all)
+	}
+	quota := make([]int, len(c.zonePolicy))
+	for i, r := range c.zonePolicy {
+		quota[i] = r.Replicas
+	}
+	picked := make([]bool, len(all))
+	got := 0
+	for i, a := range all {
+		if got == n {
+			break
+		}
+		if i == 0 {
+			// the owner always leads, as with Get
+			if j := c.zonePolicy.zoneEntry(ZoneOf(a.elem)); j >= 0 && quota[j] > 0 {
+				quota[j]--
+			}
+			picked[i] = true
+			got++
+			continue
+		}
+		if j := c.zonePolicy.zoneEntry(ZoneOf(a.elem)); j >= 0 && quota[j] > 0 {
+			quota[j]--
+			picked[i] = true
+			got++
+		}
+	}
+	for i := range all {
+		if got == n {
+			break
+		}
+		if !picked[i] {
+			picked[i] = true
+			got++
+		}
+	}
+	for i, a := range all {
+		if picked[i] {
+			fn(a.elem, a.point)
+		}
+	}
+}
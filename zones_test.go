@@ -0,0 +1,76 @@
+// Copyright (C) 2012-2014 Numerotron Inc.
+// Use of this source code is governed by an MIT-style license
+// that can be found in the LICENSE file.
+
+package consistent
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lvqian/mikuCluster/proxy/lineProtocol"
+)
+
+func TestZonePolicy(t *testing.T) {
+	var members []lineProtocol.WriteCloser
+	for i := 0; i < 4; i++ {
+		members = append(members, InZone("local", newMember(fmt.Sprintf("l%d", i))))
+		members = append(members, InZone("remote", newMember(fmt.Sprintf("r%d", i))))
+	}
+	x := New(WithZonePolicy(ZonePolicy{{"local", 2}, {AnyZone, 1}}))
+	x.Set(members)
+	for i := 0; i < 200; i++ {
+		k := fmt.Sprintf("key%d", i)
+		got, err := x.GetN(k, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owner, _ := x.Get(k); got[0] != owner {
+			t.Fatalf("%s: got %v, expected it led by %v", k, got, owner)
+		}
+		zones := make(map[string]int)
+		for _, e := range got {
+			zones[ZoneOf(e)]++
+		}
+		if len(got) != 3 || zones["local"] != 2 || zones["remote"] != 1 {
+			t.Fatalf("%s: got %v in zones %v", k, got, zones)
+		}
+	}
+
+	// the owner leads even when its zone has no quota left for it
+	w := New(WithZonePolicy(ZonePolicy{{"remote", 3}}))
+	w.Set(members)
+	for i := 0; i < 200; i++ {
+		k := fmt.Sprintf("key%d", i)
+		got, _ := w.GetN(k, 3)
+		owner, _ := w.Get(k)
+		if len(got) != 3 || got[0] != owner {
+			t.Fatalf("%s: got %v, expected it led by %v", k, got, owner)
+		}
+	}
+
+	// too few local members: the shortfall is filled in ring order
+	y := New(WithZonePolicy(ZonePolicy{{"local", 2}, {AnyZone, 1}}))
+	y.Set([]lineProtocol.WriteCloser{members[0], members[1], members[3], members[5]})
+	got, _ := y.GetN("key", 3)
+	checkNum(len(got), 3, t)
+
+	// no labels at all: plain ring order
+	plain := []lineProtocol.WriteCloser{newMember("a"), newMember("b"), newMember("c"), newMember("d")}
+	z := New(WithZonePolicy(ZonePolicy{{"local", 2}, {AnyZone, 1}}))
+	z.Set(plain)
+	ref := New()
+	ref.Set(plain)
+	a, _ := z.GetN("key", 3)
+	b, _ := ref.GetN("key", 3)
+	if fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("unlabelled: got %v, want %v", a, b)
+	}
+}
+
+func TestZoneOf(t *testing.T) {
+	m := Compress("gzip", Identify("n1", InZone("z1", newMember("a"))))
+	if ZoneOf(m) != "z1" || MemberID(m) != "n1" || ZoneOf(newMember("b")) != "" {
+		t.Errorf("zone %q id %q", ZoneOf(m), MemberID(m))
+	}
+}